@@ -30,7 +30,7 @@ type flagpole struct {
 	InitArtifacts           string
 	ImageTars               []string
 	ImageNamePrefix         string
-	UpgradeArtifacts        string
+	UpgradeArtifacts        []string
 	Kubeadm                 string
 	Kubelet                 string
 	PrePullAdditionalImages bool
@@ -75,10 +75,10 @@ func NewCommand() *cobra.Command {
 		"",
 		"add a name prefix to images tars included in the image",
 	)
-	cmd.Flags().StringVar(
+	cmd.Flags().StringSliceVar(
 		&flags.UpgradeArtifacts, "with-upgrade-artifacts",
-		"",
-		"version/build-label/path to a folder with Kubernetes binaries & image tarballs to be used for testing the kubeadm-upgrade workflow",
+		nil,
+		"version/build-label/path to a folder with Kubernetes binaries & image tarballs to be used for testing the kubeadm-upgrade workflow; multiple values can be used for testing upgrade chains",
 	)
 	cmd.Flags().StringVar(
 		&flags.Kubeadm, "with-kubeadm",
@@ -17,11 +17,17 @@ limitations under the License.
 package cluster
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
+
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 
 	"k8s.io/kubeadm/kinder/pkg/cluster/manager"
+	"k8s.io/kubeadm/kinder/pkg/cni"
 	"k8s.io/kubeadm/kinder/pkg/constants"
+	"k8s.io/kubeadm/kinder/pkg/loadbalancer"
 )
 
 const (
@@ -36,8 +42,13 @@ type flagpole struct {
 	ControlPlanes        int
 	Retain               bool
 	ExternalEtcd         bool
+	ExternalEtcdMembers  int
 	ExternalLoadBalancer bool
 	Volumes              []string
+	CNI                  string
+	LoadBalancer         string
+	LoadBalancerVerifyCA bool
+	ControlPlaneVIP      bool
 }
 
 // NewCommand returns a new cobra.Command for cluster creation
@@ -83,17 +94,43 @@ func NewCommand() *cobra.Command {
 		"external-etcd", false,
 		"create an external etcd container and setup kubeadm for using it",
 	)
+	cmd.Flags().IntVar(
+		&flags.ExternalEtcdMembers,
+		"external-etcd-members", 0,
+		"create a TLS secured external etcd cluster with the given number of members and setup kubeadm for using it",
+	)
 	cmd.Flags().BoolVar(
 		&flags.ExternalLoadBalancer,
 		"external-load-balancer", false,
 		"add an external load balancer to the cluster (implicit if number of control-plane nodes>1)",
 	)
+	cmd.Flags().StringVar(
+		&flags.LoadBalancer,
+		"load-balancer-type", loadbalancer.Default,
+		fmt.Sprintf("the implementation of the external load balancer, one of %s", loadbalancer.KnownImplementations()),
+	)
+	cmd.Flags().BoolVar(
+		&flags.LoadBalancerVerifyCA,
+		"load-balancer-verify-ca", false,
+		"verify the API server certificate of control plane nodes against the cluster CA when executing load balancer health checks",
+	)
+	cmd.Flags().BoolVar(
+		&flags.ControlPlaneVIP,
+		"control-plane-vip", false,
+		"use a VIP managed by keepalived static pods on control-plane nodes as a control plane endpoint instead of an external load balancer",
+	)
 	cmd.Flags().StringSliceVar(
 		&flags.Volumes,
 		"volume", nil,
 		"mount a volume on node containers",
 	)
 
+	cmd.Flags().StringVar(
+		&flags.CNI,
+		"cni", "",
+		fmt.Sprintf("the CNI plugin to be installed after kubeadm init, one of %s; if empty, Calico or, for Kubernetes versions not supported by the embedded Calico manifest, kindnet", cni.KnownCNIs()),
+	)
+
 	cmd.MarkFlagRequired("image")
 
 	return cmd
@@ -106,6 +143,25 @@ func runE(flags *flagpole, cmd *cobra.Command, args []string) error {
 		return errors.Errorf("flags --%s and --%s should not be a negative number", controlPlaneNodesFlagName, workerNodesFlagName)
 	}
 
+	if flags.ExternalEtcdMembers < 0 {
+		return errors.New("flag --external-etcd-members should not be a negative number")
+	}
+
+	if err := cni.Validate(flags.CNI); err != nil {
+		return err
+	}
+	// CNI manifest files are read at kubeadm init time, so the path should not depend on the working directory
+	if path, ok := cni.ManifestFile(flags.CNI); ok {
+		absPath, err := filepath.Abs(path)
+		if err != nil {
+			return errors.Wrapf(err, "failed to get the absolute path of the CNI manifest %s", path)
+		}
+		if _, err := os.Stat(absPath); err != nil {
+			return errors.Wrapf(err, "failed to read the CNI manifest %s", path)
+		}
+		flags.CNI = cni.FromFile(absPath)
+	}
+
 	// get a kinder cluster manager
 	if err = manager.CreateCluster(
 		flags.Name,
@@ -114,8 +170,13 @@ func runE(flags *flagpole, cmd *cobra.Command, args []string) error {
 		manager.Image(flags.ImageName),
 		manager.ExternalLoadBalancer(flags.ExternalLoadBalancer),
 		manager.ExternalEtcd(flags.ExternalEtcd),
+		manager.ExternalEtcdMembers(flags.ExternalEtcdMembers),
 		manager.Retain(flags.Retain),
 		manager.Volumes(flags.Volumes),
+		manager.CNI(flags.CNI),
+		manager.LoadBalancer(flags.LoadBalancer),
+		manager.LoadBalancerVerifyCA(flags.LoadBalancerVerifyCA),
+		manager.ControlPlaneVIP(flags.ControlPlaneVIP),
 	); err != nil {
 		return errors.Wrap(err, "failed to create cluster")
 	}
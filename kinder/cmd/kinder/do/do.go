@@ -25,24 +25,43 @@ import (
 	"github.com/spf13/cobra"
 
 	K8sVersion "k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/kubeadm/kinder/pkg/certs"
 	"k8s.io/kubeadm/kinder/pkg/cluster/manager"
 	"k8s.io/kubeadm/kinder/pkg/cluster/manager/actions"
 	"k8s.io/kubeadm/kinder/pkg/constants"
 )
 
 type flagpole struct {
-	Name                  string
-	UsePhases             bool
-	UpgradeVersion        string
-	CopyCerts             string
-	KubeDNS               bool
-	Discovery             string
-	OnlyNode              string
-	DryRun                bool
-	VLevel                int
-	PatchesDir            string
-	Wait                  time.Duration
-	IgnorePreflightErrors string
+	Name                   string
+	UsePhases              bool
+	UpgradeVersion         string
+	UpgradePath            []string
+	UpgradeFailComponent   string
+	CopyCerts              string
+	KubeDNS                bool
+	Discovery              string
+	OnlyNode               string
+	DryRun                 bool
+	VLevel                 int
+	PatchesDir             string
+	Wait                   time.Duration
+	IgnorePreflightErrors  string
+	VerifyReset            bool
+	Certs                  []string
+	Offset                 time.Duration
+	ExternalCAs            []string
+	ExternalCAIntermediate bool
+	EtcdDefrag             bool
+	Manifests              []string
+	SmokeTests             []string
+	SmokeTestImage         string
+	ChaosLatency           time.Duration
+	Kubeadm                string
+	Kubelet                string
+	Images                 string
+	UpdateManifests        bool
+	TokenTTL               time.Duration
+	Diff                   bool
 }
 
 // NewCommand returns a new cobra.Command for exec
@@ -51,10 +70,19 @@ func NewCommand() *cobra.Command {
 		Discovery: string(actions.TokenDiscovery),
 	}
 	cmd := &cobra.Command{
-		Args: cobra.ExactArgs(1),
-		Use: "do [flags] ACTION\n\n" +
+		Args: func(cmd *cobra.Command, args []string) error {
+			if err := cobra.MinimumNArgs(1)(cmd, args); err != nil {
+				return err
+			}
+			return actions.ValidateArgs(args[0], args[1:])
+		},
+		Use: "do [flags] ACTION [ACTION_ARGS...]\n\n" +
 			"Args:\n" +
-			fmt.Sprintf("  ACTION is one of %s", actions.KnownActions()),
+			fmt.Sprintf("  ACTION is one of %s\n", actions.KnownActions()) +
+			"  ACTION_ARGS are additional arguments for actions that support them, e.g. etcd-snapshot save|restore [PATH]\n" +
+			"  or bootstrap-token create [TOKEN]|list|delete TOKEN\n" +
+			fmt.Sprintf("  or chaos SCENARIO, where SCENARIO is one of %s\n", actions.KnownChaosScenarios()) +
+			fmt.Sprintf("  or negative-test SCENARIO, where SCENARIO is one of %s", actions.KnownNegativeScenarios()),
 		Short: "Executes actions (tasks/sequence of commands) on a cluster",
 		Long: "Action define a set of tasks/sequence of commands to be executed on a cluster. Usage of actions allows \n" +
 			"to automate repetitive operations.",
@@ -76,6 +104,11 @@ func NewCommand() *cobra.Command {
 		"dry-run", false,
 		"only prints workflow commands, without executing them",
 	)
+	cmd.Flags().BoolVar(
+		&flags.Diff,
+		"diff", false,
+		"write a diff of static pod manifests, kubelet config files and kube-system ConfigMaps before and after the action to the ARTIFACTS folder",
+	)
 	cmd.Flags().BoolVar(
 		&flags.UsePhases, "use-phases",
 		false, "use the kubeadm phases subcommands instead of the kubeadm top-level commands",
@@ -85,6 +118,16 @@ func NewCommand() *cobra.Command {
 		"upgrade-version", "",
 		"defines the target upgrade version (it should match the version of upgrades binaries)",
 	)
+	cmd.Flags().StringSliceVar(
+		&flags.UpgradePath,
+		"upgrade-path", nil,
+		"defines a list of target upgrade versions to be applied in sequence (it should match the versions of upgrades binaries)",
+	)
+	cmd.Flags().StringVar(
+		&flags.UpgradeFailComponent,
+		"upgrade-fail-component", "kube-scheduler",
+		fmt.Sprintf("the control-plane component that should fail during kubeadm-upgrade-rollback; use one of %s", actions.KnownUpgradeFailComponents()),
+	)
 	cmd.Flags().StringVar(
 		&flags.CopyCerts,
 		"copy-certs", string(actions.CopyCertsModeManual),
@@ -120,6 +163,81 @@ func NewCommand() *cobra.Command {
 		"ignore-preflight-errors", constants.KubeadmIgnorePreflightErrors,
 		"list of kubeadm preflight errors to skip",
 	)
+	cmd.Flags().BoolVar(
+		&flags.VerifyReset,
+		"verify-reset", false,
+		"verify that kubeadm reset cleaned up nodes and removed etcd members; leftovers are reported as failures",
+	)
+	cmd.Flags().StringSliceVar(
+		&flags.Certs,
+		"certs", []string{"all"},
+		"the certificates to be renewed by kubeadm-certs-renew; use all or a list of certificate names as accepted by kubeadm certs renew",
+	)
+	cmd.Flags().DurationVar(
+		&flags.Offset,
+		"offset", 0,
+		"the time offset for time-travel, e.g. 8760h for simulating one year passed",
+	)
+	cmd.Flags().StringSliceVar(
+		&flags.ExternalCAs,
+		"external-cas", []string{string(actions.ClusterCA)},
+		fmt.Sprintf("the CAs to be external for setup-external-ca and verify-external-ca; use a list of %s", actions.KnownExternalCAs()),
+	)
+	cmd.Flags().BoolVar(
+		&flags.ExternalCAIntermediate,
+		"external-ca-intermediate", false,
+		"use an intermediate CA signed by an offline root CA as a cluster CA for setup-external-ca and verify-external-ca",
+	)
+	cmd.Flags().BoolVar(
+		&flags.EtcdDefrag,
+		"etcd-defrag", false,
+		"defragment all the etcd members before reporting etcd-health",
+	)
+	cmd.Flags().StringSliceVar(
+		&flags.Manifests,
+		"manifests", nil,
+		"the manifests to be applied by addons; use a list of directories, files or URLs",
+	)
+	cmd.Flags().StringSliceVar(
+		&flags.SmokeTests,
+		"smoke-tests", []string{"all"},
+		fmt.Sprintf("the checks to be executed by smoke-test; use all or a list of %s", actions.KnownSmokeTests()),
+	)
+	cmd.Flags().StringVar(
+		&flags.SmokeTestImage,
+		"smoke-test-image", actions.DefaultSmokeTestImage,
+		"the image used by smoke-test; the image should serve HTTP on port 80 and include sh, wget and nslookup",
+	)
+	cmd.Flags().DurationVar(
+		&flags.ChaosLatency,
+		"chaos-latency", actions.DefaultChaosLatency,
+		"the latency injected on the etcd peer port by the chaos etcd-latency scenario",
+	)
+	cmd.Flags().StringVar(
+		&flags.Kubeadm,
+		"kubeadm", "",
+		"the kubeadm binary to be pushed into the nodes by replace-binaries; use a version, a build label, a URL or a local path",
+	)
+	cmd.Flags().StringVar(
+		&flags.Kubelet,
+		"kubelet", "",
+		"the kubelet binary to be pushed into the nodes by replace-binaries; use a version, a build label, a URL or a local path",
+	)
+	cmd.Flags().StringVar(
+		&flags.Images,
+		"images", "",
+		"the image tarballs to be imported into the nodes by replace-binaries; use a version, a build label, a URL or a local path",
+	)
+	cmd.Flags().BoolVar(
+		&flags.UpdateManifests,
+		"update-manifests", false,
+		"update static pod manifests to use the images imported by replace-binaries",
+	)
+	cmd.Flags().DurationVar(
+		&flags.TokenTTL,
+		"token-ttl", actions.DefaultTokenTTL,
+		"the TTL of bootstrap tokens created by bootstrap-token; 0 means that the token never expires",
+	)
 	return cmd
 }
 
@@ -133,6 +251,46 @@ func runE(flags *flagpole, cmd *cobra.Command, args []string) (err error) {
 		}
 	}
 
+	// validate UpgradePath flag
+	var upgradePath []*K8sVersion.Version
+	if len(flags.UpgradePath) > 0 {
+		if upgradeVersion != nil {
+			return errors.New("the --upgrade-version and --upgrade-path flags are mutually exclusive")
+		}
+		for _, v := range flags.UpgradePath {
+			hop, err := K8sVersion.ParseSemantic(v)
+			if err != nil {
+				return err
+			}
+			if len(upgradePath) > 0 && !upgradePath[len(upgradePath)-1].LessThan(hop) {
+				return errors.Errorf("invalid --upgrade-path; version %s should be greater than v%s", v, upgradePath[len(upgradePath)-1])
+			}
+			upgradePath = append(upgradePath, hop)
+		}
+	}
+
+	if err := actions.ValidateUpgradeFailComponent(flags.UpgradeFailComponent); err != nil {
+		return err
+	}
+
+	if _, err := certs.SelectKubeadmCertificates(flags.Certs); err != nil {
+		return errors.Wrap(err, "invalid --certs")
+	}
+
+	smokeTests, err := actions.SelectSmokeTests(flags.SmokeTests)
+	if err != nil {
+		return errors.Wrap(err, "invalid --smoke-tests")
+	}
+
+	externalCAs := []actions.ExternalCA{}
+	for _, ca := range flags.ExternalCAs {
+		externalCA := actions.ExternalCA(strings.ToLower(ca))
+		if err := actions.ValidateExternalCA(externalCA); err != nil {
+			return err
+		}
+		externalCAs = append(externalCAs, externalCA)
+	}
+
 	discovery := actions.DiscoveryMode(strings.ToLower(flags.Discovery))
 	if err := actions.ValidateDiscoveryMode(discovery); err != nil {
 		return err
@@ -164,15 +322,34 @@ func runE(flags *flagpole, cmd *cobra.Command, args []string) (err error) {
 	// executed the requested action
 	action := args[0]
 	err = o.DoAction(action,
+		actions.Args(args[1:]),
 		actions.UsePhases(flags.UsePhases),
 		actions.CopyCerts(copyCerts),
 		actions.KubeDNS(flags.KubeDNS),
 		actions.Discovery(discovery),
 		actions.Wait(flags.Wait),
 		actions.UpgradeVersion(upgradeVersion),
+		actions.UpgradePath(upgradePath),
+		actions.UpgradeFailComponent(flags.UpgradeFailComponent),
 		actions.VLevel(flags.VLevel),
 		actions.PatchesDir(flags.PatchesDir),
 		actions.IgnorePreflightErrors(flags.IgnorePreflightErrors),
+		actions.VerifyReset(flags.VerifyReset),
+		actions.Certs(flags.Certs),
+		actions.Offset(flags.Offset),
+		actions.ExternalCAs(externalCAs),
+		actions.ExternalCAIntermediate(flags.ExternalCAIntermediate),
+		actions.EtcdDefrag(flags.EtcdDefrag),
+		actions.Manifests(flags.Manifests),
+		actions.SmokeTests(smokeTests),
+		actions.SmokeTestImage(flags.SmokeTestImage),
+		actions.ChaosLatency(flags.ChaosLatency),
+		actions.Kubeadm(flags.Kubeadm),
+		actions.Kubelet(flags.Kubelet),
+		actions.Images(flags.Images),
+		actions.UpdateManifests(flags.UpdateManifests),
+		actions.TokenTTL(flags.TokenTTL),
+		actions.Diff(flags.Diff),
 	)
 	if err != nil {
 		return errors.Wrapf(err, "failed to exec action %s", action)
@@ -0,0 +1,80 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package diagnostics
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/manager/actions"
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/constants"
+)
+
+type flagpole struct {
+	Name string
+}
+
+// NewCommand returns a new cobra.Command for exporting kubeadm diagnostics
+func NewCommand() *cobra.Command {
+	flags := &flagpole{}
+
+	cmd := &cobra.Command{
+		Args:  cobra.MaximumNArgs(1),
+		Use:   "diagnostics [output-dir]",
+		Short: "Exports kubeadm related diagnostics from all the nodes and from the cluster to a folder",
+		Long: "Exports kubeadm related diagnostics to a folder; for each node kubeadm and kubelet configuration files, " +
+			"static pod manifests, kubelet/containerd/docker journals, the list of containers and their logs, " +
+			"certificate expiration summaries and iptables rules are collected, while at cluster level nodes, pods, " +
+			"events and kubeadm ConfigMaps are dumped. If output-dir is not provided, diagnostics are exported " +
+			"to a <cluster name>-diagnostics folder in the ARTIFACTS folder, if defined, or in the current folder",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runE(flags, cmd, args)
+		},
+	}
+
+	cmd.Flags().StringVar(
+		&flags.Name,
+		"name", constants.DefaultClusterName, "cluster name",
+	)
+	return cmd
+}
+
+func runE(flags *flagpole, cmd *cobra.Command, args []string) error {
+	c, err := status.FromDocker(flags.Name)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read cluster status for %s", flags.Name)
+	}
+	// diagnostics are usually exported from broken clusters, so settings that can't be read
+	// should not prevent collecting what is available
+	if err := c.ReadSettings(); err != nil {
+		log.Warnf("failed to read cluster settings, using defaults: %v", err)
+		c.Settings, _ = status.ClusterSettingsFromLabel("")
+	}
+
+	dir := filepath.Join(os.Getenv("ARTIFACTS"), fmt.Sprintf("%s-diagnostics", c.Name()))
+	if len(args) > 0 {
+		dir = args[0]
+	}
+
+	return actions.ExportDiagnostics(c, dir)
+}
@@ -0,0 +1,250 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package certs
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"k8s.io/client-go/tools/clientcmd"
+	kindercerts "k8s.io/kubeadm/kinder/pkg/certs"
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/constants"
+)
+
+const (
+	tableOutput = "table"
+	jsonOutput  = "json"
+)
+
+// caPaths defines the CA certificates that should be the same on all the nodes
+var caPaths = []string{
+	"/etc/kubernetes/pki/ca.crt",
+	"/etc/kubernetes/pki/front-proxy-ca.crt",
+	"/etc/kubernetes/pki/etcd/ca.crt",
+}
+
+type flagpole struct {
+	Name   string
+	Output string
+}
+
+// inventory defines the certificates found in a cluster and the result of consistency checks
+type inventory struct {
+	Certificates []kindercerts.CertificateInfo `json:"certificates"`
+	Problems     []string                      `json:"problems"`
+}
+
+// NewCommand returns a new cobra.Command for getting the certificates in a cluster
+func NewCommand() *cobra.Command {
+	flags := &flagpole{}
+
+	cmd := &cobra.Command{
+		Args:  cobra.NoArgs,
+		Use:   "certs",
+		Short: "Lists certificates in /etc/kubernetes on all the nodes and checks their consistency",
+		Long: "Lists certificates and client certificates embedded in kubeconfig files in /etc/kubernetes on all the nodes, " +
+			"and checks that CA are the same on all the nodes, that the API server certificates are valid for localhost, " +
+			"the node IP and the control-plane endpoint, and that certificates are not expired",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runE(flags, cmd, args)
+		},
+	}
+
+	cmd.Flags().StringVar(
+		&flags.Name,
+		"name", constants.DefaultClusterName, "cluster name",
+	)
+	cmd.Flags().StringVarP(
+		&flags.Output,
+		"output", "o", tableOutput, "output format; use one of table or json",
+	)
+	return cmd
+}
+
+func runE(flags *flagpole, cmd *cobra.Command, args []string) error {
+	if flags.Output != tableOutput && flags.Output != jsonOutput {
+		return errors.Errorf("invalid output format %q; use one of table or json", flags.Output)
+	}
+
+	c, err := status.FromDocker(flags.Name)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read cluster status for %s", flags.Name)
+	}
+	if err := c.ReadSettings(); err != nil {
+		return err
+	}
+
+	inv := &inventory{
+		Certificates: []kindercerts.CertificateInfo{},
+		Problems:     []string{},
+	}
+	for _, n := range c.K8sNodes() {
+		infos, err := readNodeCertificates(n)
+		if err != nil {
+			return err
+		}
+		inv.Certificates = append(inv.Certificates, infos...)
+	}
+
+	if err := checkConsistency(c, inv); err != nil {
+		return err
+	}
+
+	switch flags.Output {
+	case jsonOutput:
+		out, err := json.MarshalIndent(inv, "", "  ")
+		if err != nil {
+			return errors.Wrap(err, "failed to encode certificates")
+		}
+		fmt.Println(string(out))
+	default:
+		printTable(inv)
+	}
+
+	if len(inv.Problems) > 0 {
+		return errors.Errorf("%d certificate consistency checks failed", len(inv.Problems))
+	}
+	return nil
+}
+
+// readNodeCertificates reads all the certificates and the client certificates embedded in kubeconfig files
+// stored in /etc/kubernetes on a node
+func readNodeCertificates(n *status.Node) ([]kindercerts.CertificateInfo, error) {
+	infos := []kindercerts.CertificateInfo{}
+
+	certFiles, err := n.Command(
+		"find", "/etc/kubernetes", "-name", "*.crt",
+	).Silent().RunAndCapture()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list certificates on %s", n.Name())
+	}
+	for _, f := range certFiles {
+		data, err := n.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		certs, err := kindercerts.ParseCertificates(data)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse %s on %s", f, n.Name())
+		}
+		for _, cert := range certs {
+			infos = append(infos, kindercerts.NewCertificateInfo(n.Name(), f, "", cert))
+		}
+	}
+
+	kubeConfigFiles, err := n.Command(
+		"find", "/etc/kubernetes", "-maxdepth", "1", "-name", "*.conf",
+	).Silent().RunAndCapture()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list kubeconfig files on %s", n.Name())
+	}
+	for _, f := range kubeConfigFiles {
+		data, err := n.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		config, err := clientcmd.Load(data)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse %s on %s", f, n.Name())
+		}
+		for user, authInfo := range config.AuthInfos {
+			if len(authInfo.ClientCertificateData) == 0 {
+				continue
+			}
+			certs, err := kindercerts.ParseCertificates(authInfo.ClientCertificateData)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to parse the client certificate for user %s in %s on %s", user, f, n.Name())
+			}
+			infos = append(infos, kindercerts.NewCertificateInfo(n.Name(), f, user, certs[0]))
+		}
+	}
+
+	return infos, nil
+}
+
+// checkConsistency checks that CA certificates are the same on all the nodes, that API server certificates
+// are valid for localhost, the node IP and the control-plane endpoint, and that certificates are not expired
+func checkConsistency(c *status.Cluster, inv *inventory) error {
+	for _, p := range caPaths {
+		inv.Problems = append(inv.Problems, kindercerts.CheckSameCertificate(inv.Certificates, p)...)
+	}
+
+	endpoint := []string{}
+	if lb := c.ExternalLoadBalancer(); lb != nil {
+		lbIPv4, lbIPv6, err := lb.IP()
+		if err != nil {
+			return errors.Wrapf(err, "failed to get IP for node: %s", lb.Name())
+		}
+		endpoint = append(endpoint, lbIPv4, lbIPv6)
+	}
+	if vip := c.Settings.ControlPlaneVIP; vip != "" {
+		endpoint = append(endpoint, vip)
+	}
+
+	now := time.Now()
+	for _, i := range inv.Certificates {
+		inv.Problems = append(inv.Problems, kindercerts.CheckExpiration(i, now)...)
+	}
+
+	for _, n := range c.ControlPlanes() {
+		ipv4, ipv6, err := n.IP()
+		if err != nil {
+			return errors.Wrapf(err, "failed to get IP for node: %s", n.Name())
+		}
+		hosts := append([]string{"localhost", ipv4, ipv6}, endpoint...)
+
+		for _, i := range inv.Certificates {
+			if i.Node == n.Name() && i.Path == "/etc/kubernetes/pki/apiserver.crt" {
+				inv.Problems = append(inv.Problems, kindercerts.CheckSANs(i, hosts)...)
+			}
+		}
+	}
+
+	return nil
+}
+
+func printTable(inv *inventory) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NODE\tPATH\tSUBJECT\tISSUER\tKEY\tEXPIRES\tSANS")
+	for _, i := range inv.Certificates {
+		path := i.Path
+		if i.User != "" {
+			path = fmt.Sprintf("%s (%s)", i.Path, i.User)
+		}
+		sans := strings.Join(append(append([]string{}, i.DNSNames...), i.IPAddresses...), ",")
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", i.Node, path, i.Subject, i.Issuer, i.KeyType, i.NotAfter.Format(time.RFC3339), sans)
+	}
+	w.Flush()
+
+	fmt.Println()
+	if len(inv.Problems) == 0 {
+		fmt.Println("All the certificate consistency checks passed")
+		return
+	}
+	fmt.Println("Certificate consistency checks failed:")
+	for _, p := range inv.Problems {
+		fmt.Printf("- %s\n", p)
+	}
+}
@@ -20,8 +20,10 @@ import (
 	"github.com/spf13/cobra"
 
 	"k8s.io/kubeadm/kinder/cmd/kinder/get/artifacts"
+	"k8s.io/kubeadm/kinder/cmd/kinder/get/certs"
 	"k8s.io/kubeadm/kinder/cmd/kinder/get/clusters"
 	"k8s.io/kubeadm/kinder/cmd/kinder/get/kubeconfigpath"
+	"k8s.io/kubeadm/kinder/cmd/kinder/get/lbstatus"
 	"k8s.io/kubeadm/kinder/cmd/kinder/get/nodes"
 )
 
@@ -30,8 +32,8 @@ func NewCommand() *cobra.Command {
 	cmd := &cobra.Command{
 		Args:  cobra.NoArgs,
 		Use:   "get",
-		Short: "Gets one of [clusters, nodes, kubeconfig-path, artifacts]",
-		Long:  "Gets one of [clusters, nodes, kubeconfig-path, artifacts]",
+		Short: "Gets one of [clusters, nodes, kubeconfig-path, artifacts, certs, lb-status]",
+		Long:  "Gets one of [clusters, nodes, kubeconfig-path, artifacts, certs, lb-status]",
 	}
 
 	cmd.AddCommand(clusters.NewCommand())
@@ -42,5 +44,7 @@ func NewCommand() *cobra.Command {
 
 	// add kinder only commands
 	cmd.AddCommand(artifacts.NewCommand())
+	cmd.AddCommand(certs.NewCommand())
+	cmd.AddCommand(lbstatus.NewCommand())
 	return cmd
 }
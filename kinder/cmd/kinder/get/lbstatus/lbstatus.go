@@ -0,0 +1,113 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lbstatus
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/manager/actions"
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/constants"
+)
+
+const (
+	tableOutput = "table"
+	jsonOutput  = "json"
+)
+
+type flagpole struct {
+	Name   string
+	Output string
+}
+
+// NewCommand returns a new cobra.Command for getting the status of the load balancer backends
+func NewCommand() *cobra.Command {
+	flags := &flagpole{}
+
+	cmd := &cobra.Command{
+		Args:  cobra.NoArgs,
+		Use:   "lb-status",
+		Short: "Reports the health of each control plane node as seen by the external load balancer",
+		Long: "Reports the health of each control plane node as seen by the external load balancer, " +
+			"as reported by the HAProxy stats page; the command fails if any backend is not up",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runE(flags, cmd, args)
+		},
+	}
+
+	cmd.Flags().StringVar(
+		&flags.Name,
+		"name", constants.DefaultClusterName, "cluster name",
+	)
+	cmd.Flags().StringVarP(
+		&flags.Output,
+		"output", "o", tableOutput, "output format; use one of table or json",
+	)
+	return cmd
+}
+
+func runE(flags *flagpole, cmd *cobra.Command, args []string) error {
+	if flags.Output != tableOutput && flags.Output != jsonOutput {
+		return errors.Errorf("invalid output format %q; use one of table or json", flags.Output)
+	}
+
+	c, err := status.FromDocker(flags.Name)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read cluster status for %s", flags.Name)
+	}
+	if err := c.ReadSettings(); err != nil {
+		return err
+	}
+
+	backends, err := actions.LoadBalancerBackends(c)
+	if err != nil {
+		return err
+	}
+
+	switch flags.Output {
+	case jsonOutput:
+		out, err := json.MarshalIndent(backends, "", "  ")
+		if err != nil {
+			return errors.Wrap(err, "failed to encode load balancer status")
+		}
+		fmt.Println(string(out))
+	default:
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "BACKEND\tADDRESS\tSTATUS\tCHECK\tLAST CHANGE\tDOWNTIME")
+		for _, b := range backends {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%ss\t%ss\n", b.Name, b.Address, b.Status, b.CheckStatus, b.LastChange, b.Downtime)
+		}
+		w.Flush()
+	}
+
+	down := 0
+	for _, b := range backends {
+		if !b.IsUp() {
+			down++
+		}
+	}
+	if down > 0 {
+		return errors.Errorf("%d load balancer backends are not up", down)
+	}
+	return nil
+}
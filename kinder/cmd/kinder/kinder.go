@@ -28,12 +28,12 @@ import (
 	"k8s.io/kubeadm/kinder/cmd/kinder/create"
 	"k8s.io/kubeadm/kinder/cmd/kinder/do"
 	"k8s.io/kubeadm/kinder/cmd/kinder/exec"
+	"k8s.io/kubeadm/kinder/cmd/kinder/export"
 	"k8s.io/kubeadm/kinder/cmd/kinder/get"
 	"k8s.io/kubeadm/kinder/cmd/kinder/test"
 	"k8s.io/kubeadm/kinder/cmd/kinder/version"
 	"k8s.io/kubeadm/kinder/pkg/constants"
 	kinddelete "sigs.k8s.io/kind/cmd/kind/delete"
-	kindexport "sigs.k8s.io/kind/cmd/kind/export"
 )
 
 const defaultLevel = log.WarnLevel
@@ -73,13 +73,13 @@ func NewCommand() *cobra.Command {
 
 	// add kind top level subcommands re-used without changes
 	cmd.AddCommand(kinddelete.NewCommand())
-	cmd.AddCommand(kindexport.NewCommand())
 
 	// add kind commands customized in kind
 	cmd.AddCommand(build.NewCommand())
 	cmd.AddCommand(create.NewCommand())
 	cmd.AddCommand(version.NewCommand())
 	cmd.AddCommand(get.NewCommand())
+	cmd.AddCommand(export.NewCommand())
 
 	// add kinder only commands
 	cmd.AddCommand(cp.NewCommand())
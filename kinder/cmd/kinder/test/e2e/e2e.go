@@ -29,6 +29,8 @@ import (
 
 type flagpole struct {
 	KubeRoot            string
+	TestBinaries        string
+	RerunFailed         int
 	Parallel            bool
 	TestGridConformance bool
 	GinkgoFlags         string
@@ -55,6 +57,16 @@ func NewCommand() *cobra.Command {
 		"kube-root", "",
 		"Path to the Kubernetes source directory (if empty, the path is autodetected)",
 	)
+	cmd.Flags().StringVar(
+		&flags.TestBinaries,
+		"test-binaries", "",
+		"Kubernetes version, release/ci label, URL or local path of the prebuilt kubernetes-test binaries to use instead of building them from the Kubernetes source directory",
+	)
+	cmd.Flags().IntVar(
+		&flags.RerunFailed,
+		"rerun-failed", 0,
+		"Number of times failed specs should be re-run for detecting flakes",
+	)
 	cmd.Flags().BoolVar(
 		&flags.TestGridConformance,
 		"conformance", true,
@@ -144,6 +156,8 @@ func runE(flags *flagpole, cmd *cobra.Command, args []string) error {
 	// creates a NewKubernetesTestRunner with the desired options and run it
 	testRunner, err := e2e.NewKubernetesTestRunner(
 		e2e.KubeRoot(flags.KubeRoot),
+		e2e.TestBinaries(flags.TestBinaries),
+		e2e.RerunFailed(flags.RerunFailed),
 		e2e.WithGinkgoFlags(ginkgoFlags),
 		e2e.WithSuiteFlags(testFlags),
 	)
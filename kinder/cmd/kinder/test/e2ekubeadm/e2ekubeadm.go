@@ -28,13 +28,15 @@ import (
 )
 
 type flagpole struct {
-	KubeRoot    string
-	SingleNode  bool
-	CopyCerts   bool
-	GinkgoFlags string
-	TestFlags   string
-	Name        string
-	kubeconfig  string
+	KubeRoot     string
+	TestBinaries string
+	RerunFailed  int
+	SingleNode   bool
+	CopyCerts    bool
+	GinkgoFlags  string
+	TestFlags    string
+	Name         string
+	kubeconfig   string
 }
 
 // NewCommand returns a new cobra.Command for e2e-kubeadm
@@ -54,6 +56,16 @@ func NewCommand() *cobra.Command {
 		&flags.KubeRoot,
 		"kube-root", "", "Path to the Kubernetes source directory (if empty, the path is autodetected)",
 	)
+	cmd.Flags().StringVar(
+		&flags.TestBinaries,
+		"test-binaries", "",
+		"Kubernetes version, release/ci label, URL or local path of the prebuilt kubernetes-test binaries to use instead of building them from the Kubernetes source directory",
+	)
+	cmd.Flags().IntVar(
+		&flags.RerunFailed,
+		"rerun-failed", 0,
+		"Number of times failed specs should be re-run for detecting flakes",
+	)
 	cmd.Flags().BoolVar(
 		&flags.SingleNode,
 		"single-node", false,
@@ -132,6 +144,8 @@ func runE(flags *flagpole, cmd *cobra.Command, args []string) error {
 	// creates a KubeadmTestRunner with the desired options and run it
 	testRunner, err := e2e.NewKubeadmTestRunner(
 		e2e.KubeRoot(flags.KubeRoot),
+		e2e.TestBinaries(flags.TestBinaries),
+		e2e.RerunFailed(flags.RerunFailed),
 		e2e.WithGinkgoFlags(ginkgoFlags),
 		e2e.WithSuiteFlags(testFlags),
 	)
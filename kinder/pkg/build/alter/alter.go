@@ -51,7 +51,7 @@ type Context struct {
 	initArtifactsSrc        string
 	imageSrcs               []string
 	imageNamePrefix         string
-	upgradeArtifactsSrc     string
+	upgradeArtifactsSrcs    []string
 	kubeadmSrc              string
 	kubeletSrc              string
 	prePullAdditionalImages bool
@@ -96,10 +96,11 @@ func WithImageNamePrefix(namePrefix string) Option {
 	}
 }
 
-// WithUpgradeArtifacts configures a NewContext to include binaries & images for upgrade
-func WithUpgradeArtifacts(src string) Option {
+// WithUpgradeArtifacts configures a NewContext to include binaries & images for upgrade;
+// many sources can be included for testing upgrade chains
+func WithUpgradeArtifacts(srcs []string) Option {
 	return func(b *Context) {
-		b.upgradeArtifactsSrc = src
+		b.upgradeArtifactsSrcs = append(b.upgradeArtifactsSrcs, srcs...)
 	}
 }
 
@@ -165,8 +166,8 @@ func (c *Context) Alter() (err error) {
 		bitsInstallers = append(bitsInstallers, bits.NewImageBits(c.imageSrcs, c.imageNamePrefix))
 	}
 
-	if c.upgradeArtifactsSrc != "" {
-		bitsInstallers = append(bitsInstallers, bits.NewUpgradeBits(c.upgradeArtifactsSrc))
+	if len(c.upgradeArtifactsSrcs) > 0 {
+		bitsInstallers = append(bitsInstallers, bits.NewUpgradeBits(c.upgradeArtifactsSrcs))
 	}
 
 	if len(c.paths) > 0 {
@@ -223,7 +224,7 @@ func (c *Context) prepareBits(bitsInstallers []bits.Installer, bc *bits.BuildCon
 		for k, v := range bits {
 			// if the bit is one of the kubernetes images, we should ensure the repository/name matches kubeadm expectations
 			if isAKubernetesImages(k) {
-				if err := fixImageTar(v); err != nil {
+				if err := FixImageTar(v); err != nil {
 					return errors.Wrap(err, "failed to fix bits")
 				}
 			}
@@ -233,8 +234,8 @@ func (c *Context) prepareBits(bitsInstallers []bits.Installer, bc *bits.BuildCon
 	return nil
 }
 
-// fixImageTar ensure the repository/name matches kubeadm expectations
-func fixImageTar(v string) error {
+// FixImageTar ensures the repository/name of the images in an image tarball matches kubeadm expectations
+func FixImageTar(v string) error {
 	log.Infof("fixing %s", v)
 
 	// prepare to read the image tar
@@ -358,28 +359,26 @@ func (c *Context) alterImage(bitsInstallers []bits.Installer, bc *bits.BuildCont
 
 		// pull images required for upgrade
 		upgradePath := "/kinder/upgrade"
-		// check if the version file for the upgrade artifacts is in place
-		versionFile := filepath.Join(upgradePath, "version")
-		version, err := bc.CombinedOutputLinesInContainer(
+		// list the version folders for the upgrade artifacts, if any
+		// nb. the node image could contain many upgrade versions, e.g. for testing upgrade chains
+		versions, err := bc.CombinedOutputLinesInContainer(
 			"bash",
 			"-c",
-			"cat "+versionFile+" 2> /dev/null",
+			"find "+upgradePath+" -mindepth 1 -maxdepth 1 -type d -name 'v*' -printf '%f\\n' 2> /dev/null",
 		)
 
-		// don't return the error if the version file is missing
+		// don't return the error if the upgrade folder is missing
 		if err == nil {
-			if len(version) != 1 {
-				return errors.Errorf("expected the version file %q to have 1 line, got: %v", versionFile, version)
-			}
-
-			// use the resulting upgrade path e.g. /kinder/upgrade/v1.19.0-alpha.3.36+8c4e3faed35411
-			upgradeImages, err := alterHelper.GetImagesForKubeadmBinary(bc, filepath.Join(upgradePath, version[0], "kubeadm"))
-			if err != nil {
-				return err
-			}
+			for _, version := range versions {
+				// use the resulting upgrade path e.g. /kinder/upgrade/v1.19.0-alpha.3.36+8c4e3faed35411
+				upgradeImages, err := alterHelper.GetImagesForKubeadmBinary(bc, filepath.Join(upgradePath, version, "kubeadm"))
+				if err != nil {
+					return err
+				}
 
-			if err := pullImages(alterHelper, bc, upgradeImages, filepath.Join(upgradePath, version[0]), containerID); err != nil {
-				return err
+				if err := pullImages(alterHelper, bc, upgradeImages, filepath.Join(upgradePath, version), containerID); err != nil {
+					return err
+				}
 			}
 		}
 	}
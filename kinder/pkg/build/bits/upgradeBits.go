@@ -27,17 +27,19 @@ import (
 )
 
 // upgradeBits defines a bit installer that allows to add Kubernetes binaries & images to the /kinder/upgrade folder into the node image;
-// those artifact will be used by the kinder do kubeadm-upgrade script
+// those artifact will be used by the kinder do kubeadm-upgrade script.
+// Each source is saved into a separated /kinder/upgrade/{version} folder, thus allowing to
+// test upgrade chains across many Kubernetes versions
 type upgradeBits struct {
-	src string
+	srcs []string
 }
 
 var _ Installer = &upgradeBits{}
 
 // NewUpgradeBits returns a new upgradeBits
-func NewUpgradeBits(arg string) Installer {
+func NewUpgradeBits(args []string) Installer {
 	return &upgradeBits{
-		src: arg,
+		srcs: args,
 	}
 }
 
@@ -49,16 +51,27 @@ func (b *upgradeBits) Prepare(c *BuildContext) (map[string]string, error) {
 		return nil, errors.Wrap(err, "failed to make bits dir")
 	}
 
-	// Creates an extractor instance, that will read binaries & images required from upgrades from the src,
-	// where source can be one of version/build-label/folder containing the  binaries & images,
-	// and save it to the dst folder
-	e := extract.NewExtractor(
-		b.src, dst,
-		extract.WithVersionFolder(true),
-	)
+	bits := map[string]string{}
+	for _, src := range b.srcs {
+		// Creates an extractor instance, that will read binaries & images required from upgrades from the src,
+		// where source can be one of version/build-label/folder containing the  binaries & images,
+		// and save it to the dst folder
+		e := extract.NewExtractor(
+			src, dst,
+			extract.WithVersionFolder(true),
+		)
+
+		// Extracts the binary bit
+		srcBits, err := e.Extract()
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range srcBits {
+			bits[k] = v
+		}
+	}
 
-	// Extracts the binary bit
-	return e.Extract()
+	return bits, nil
 }
 
 // Install implements bits.Install
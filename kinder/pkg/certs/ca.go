@@ -0,0 +1,173 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package certs
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math"
+	"math/big"
+	"net"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// caValidity defines the validity of CAs generated by kinder; this is the same
+	// validity used by kubeadm for CAs
+	caValidity = 10 * 365 * 24 * time.Hour
+
+	// certificateValidity defines the validity of certificates generated by kinder; this is the same
+	// validity used by kubeadm for certificates
+	certificateValidity = 365 * 24 * time.Hour
+
+	// rsaKeySize defines the size of RSA keys generated by kinder
+	rsaKeySize = 2048
+)
+
+// CertificateConfig defines the attributes of a certificate to be generated by kinder
+type CertificateConfig struct {
+	CommonName   string
+	Organization []string
+	DNSNames     []string
+	IPAddresses  []net.IP
+	Usages       []x509.ExtKeyUsage
+}
+
+// NewCA creates a new CA certificate and key; the CA is signed by the given parent CA,
+// or self-signed if the parent is nil
+func NewCA(commonName string, parent *x509.Certificate, parentKey crypto.Signer) (*x509.Certificate, crypto.Signer, error) {
+	key, err := rsa.GenerateKey(rand.Reader, rsaKeySize)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to generate CA key")
+	}
+
+	serial, err := newSerialNumber()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to generate serial number")
+	}
+
+	now := time.Now().UTC()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(caValidity),
+		KeyUsage:              x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	// if the CA is an intermediate CA, it should not be allowed to sign other CAs
+	if parent != nil {
+		template.MaxPathLenZero = true
+	} else {
+		parent, parentKey = template, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, key.Public(), parentKey)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to sign CA %s", commonName)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to parse CA %s", commonName)
+	}
+	return cert, key, nil
+}
+
+// NewSignedCertificate creates a new certificate and key signed by the given CA
+func NewSignedCertificate(config CertificateConfig, ca *x509.Certificate, caKey crypto.Signer) (*x509.Certificate, crypto.Signer, error) {
+	key, err := rsa.GenerateKey(rand.Reader, rsaKeySize)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to generate key")
+	}
+
+	serial, err := newSerialNumber()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to generate serial number")
+	}
+
+	now := time.Now().UTC()
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			CommonName:   config.CommonName,
+			Organization: config.Organization,
+		},
+		DNSNames:    config.DNSNames,
+		IPAddresses: config.IPAddresses,
+		NotBefore:   ca.NotBefore,
+		NotAfter:    now.Add(certificateValidity),
+		KeyUsage:    x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: config.Usages,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, key.Public(), caKey)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to sign certificate %s", config.CommonName)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to parse certificate %s", config.CommonName)
+	}
+	return cert, key, nil
+}
+
+// newSerialNumber returns a random serial number for a new certificate
+func newSerialNumber() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).SetInt64(math.MaxInt64))
+}
+
+// EncodeCertificates returns the PEM encoding of a list of certificates
+func EncodeCertificates(certs ...*x509.Certificate) []byte {
+	var out []byte
+	for _, c := range certs {
+		out = append(out, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.Raw})...)
+	}
+	return out
+}
+
+// EncodePrivateKey returns the PEM encoding of a RSA private key
+func EncodePrivateKey(key crypto.Signer) ([]byte, error) {
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("only RSA private keys are supported")
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}), nil
+}
+
+// VerifyCertificate verifies that a certificate chains up to one of the certificates in the CA bundle
+func VerifyCertificate(cert *x509.Certificate, bundle []*x509.Certificate) error {
+	roots := x509.NewCertPool()
+	for _, c := range bundle {
+		roots.AddCert(c)
+	}
+
+	if _, err := cert.Verify(x509.VerifyOptions{
+		Roots:     roots,
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}); err != nil {
+		return errors.Wrapf(err, "certificate %s cannot be verified", cert.Subject.CommonName)
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package certs
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// KubeadmCertificate defines a certificate managed by kubeadm
+type KubeadmCertificate struct {
+	// Name of the certificate, as used by kubeadm certs renew
+	Name string
+
+	// Path of the certificate file or of the kubeconfig file embedding the certificate
+	Path string
+
+	// KubeConfig is true if the certificate is embedded in a kubeconfig file
+	KubeConfig bool
+
+	// Component is the name of the static pod using the certificate
+	Component string
+}
+
+// KubeadmCertificates returns the list of certificates that can be renewed by kubeadm
+func KubeadmCertificates() []KubeadmCertificate {
+	return []KubeadmCertificate{
+		{Name: "admin.conf", Path: "/etc/kubernetes/admin.conf", KubeConfig: true},
+		{Name: "apiserver", Path: "/etc/kubernetes/pki/apiserver.crt", Component: "kube-apiserver"},
+		{Name: "apiserver-etcd-client", Path: "/etc/kubernetes/pki/apiserver-etcd-client.crt", Component: "kube-apiserver"},
+		{Name: "apiserver-kubelet-client", Path: "/etc/kubernetes/pki/apiserver-kubelet-client.crt", Component: "kube-apiserver"},
+		{Name: "controller-manager.conf", Path: "/etc/kubernetes/controller-manager.conf", KubeConfig: true, Component: "kube-controller-manager"},
+		{Name: "etcd-healthcheck-client", Path: "/etc/kubernetes/pki/etcd/healthcheck-client.crt", Component: "etcd"},
+		{Name: "etcd-peer", Path: "/etc/kubernetes/pki/etcd/peer.crt", Component: "etcd"},
+		{Name: "etcd-server", Path: "/etc/kubernetes/pki/etcd/server.crt", Component: "etcd"},
+		{Name: "front-proxy-client", Path: "/etc/kubernetes/pki/front-proxy-client.crt", Component: "kube-apiserver"},
+		{Name: "scheduler.conf", Path: "/etc/kubernetes/scheduler.conf", KubeConfig: true, Component: "kube-scheduler"},
+	}
+}
+
+// SelectKubeadmCertificates returns the kubeadm certificates with the given names;
+// "all" selects all the certificates that can be renewed by kubeadm
+func SelectKubeadmCertificates(names []string) ([]KubeadmCertificate, error) {
+	all := KubeadmCertificates()
+	if len(names) == 0 {
+		return all, nil
+	}
+
+	var selected []KubeadmCertificate
+	for _, name := range names {
+		if name == "all" {
+			return all, nil
+		}
+
+		found := false
+		for _, c := range all {
+			if c.Name == name {
+				selected = append(selected, c)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, errors.Errorf("unknown certificate %q", name)
+		}
+	}
+	return selected, nil
+}
+
+// ParseCertificates parses all the PEM encoded certificates in data
+func ParseCertificates(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse certificate")
+		}
+		certs = append(certs, cert)
+	}
+
+	if len(certs) == 0 {
+		return nil, errors.New("no certificates found")
+	}
+	return certs, nil
+}
+
+// ParseKubeConfigCertificate parses the client certificate embedded in the
+// user of the current context of a kubeconfig file
+func ParseKubeConfigCertificate(data []byte) (*x509.Certificate, error) {
+	config, err := clientcmd.Load(data)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse kubeconfig")
+	}
+
+	context, ok := config.Contexts[config.CurrentContext]
+	if !ok {
+		return nil, errors.Errorf("kubeconfig does not contain the current context %q", config.CurrentContext)
+	}
+	authInfo, ok := config.AuthInfos[context.AuthInfo]
+	if !ok {
+		return nil, errors.Errorf("kubeconfig does not contain the user %q", context.AuthInfo)
+	}
+	if len(authInfo.ClientCertificateData) == 0 {
+		return nil, errors.Errorf("user %q does not have an embedded client certificate", context.AuthInfo)
+	}
+
+	certs, err := ParseCertificates(authInfo.ClientCertificateData)
+	if err != nil {
+		return nil, err
+	}
+	return certs[0], nil
+}
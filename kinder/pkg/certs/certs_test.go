@@ -0,0 +1,297 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package certs
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSelectKubeadmCertificates(t *testing.T) {
+	tests := []struct {
+		name          string
+		inputNames    []string
+		expectedNames []string
+		expectedError bool
+	}{
+		{
+			name:          "valid: no names selects all",
+			expectedNames: kubeadmCertificateNames(KubeadmCertificates()),
+		},
+		{
+			name:          "valid: all",
+			inputNames:    []string{"all"},
+			expectedNames: kubeadmCertificateNames(KubeadmCertificates()),
+		},
+		{
+			name:          "valid: list of names",
+			inputNames:    []string{"apiserver", "admin.conf"},
+			expectedNames: []string{"apiserver", "admin.conf"},
+		},
+		{
+			name:          "invalid: unknown name",
+			inputNames:    []string{"apiserver", "foo"},
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			selected, err := SelectKubeadmCertificates(test.inputNames)
+			if (err != nil) != test.expectedError {
+				t.Fatalf("expected error: %v, found %v, error: %v", test.expectedError, err != nil, err)
+			}
+			if test.expectedError {
+				return
+			}
+			if names := kubeadmCertificateNames(selected); !reflect.DeepEqual(names, test.expectedNames) {
+				t.Fatalf("expected names: %v, found %v", test.expectedNames, names)
+			}
+		})
+	}
+}
+
+func TestCheckSANs(t *testing.T) {
+	ca, caKey := newTestCertificate(t, nil, nil, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "kubernetes"},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	})
+	apiserver, _ := newTestCertificate(t, ca, caKey, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "kube-apiserver"},
+		DNSNames:    []string{"localhost", "kubernetes"},
+		IPAddresses: []net.IP{net.ParseIP("172.17.0.2")},
+	})
+	info := NewCertificateInfo("cp1", "/etc/kubernetes/pki/apiserver.crt", "", apiserver)
+
+	tests := []struct {
+		name             string
+		inputHosts       []string
+		expectedProblems int
+	}{
+		{
+			name:       "valid: all hosts covered",
+			inputHosts: []string{"localhost", "172.17.0.2", ""},
+		},
+		{
+			name:             "invalid: IP not covered",
+			inputHosts:       []string{"localhost", "172.17.0.3"},
+			expectedProblems: 1,
+		},
+		{
+			name:             "invalid: name not covered",
+			inputHosts:       []string{"foo", "172.17.0.2"},
+			expectedProblems: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if problems := CheckSANs(info, test.inputHosts); len(problems) != test.expectedProblems {
+				t.Fatalf("expected %d problems, found %v", test.expectedProblems, problems)
+			}
+		})
+	}
+}
+
+func TestCheckSameCertificate(t *testing.T) {
+	ca1, _ := newTestCertificate(t, nil, nil, &x509.Certificate{Subject: pkix.Name{CommonName: "ca1"}, IsCA: true, BasicConstraintsValid: true})
+	ca2, _ := newTestCertificate(t, nil, nil, &x509.Certificate{Subject: pkix.Name{CommonName: "ca2"}, IsCA: true, BasicConstraintsValid: true})
+	path := "/etc/kubernetes/pki/ca.crt"
+
+	tests := []struct {
+		name             string
+		inputInfos       []CertificateInfo
+		expectedProblems int
+	}{
+		{
+			name: "valid: same CA",
+			inputInfos: []CertificateInfo{
+				NewCertificateInfo("cp1", path, "", ca1),
+				NewCertificateInfo("cp2", path, "", ca1),
+				NewCertificateInfo("cp2", "/etc/kubernetes/pki/front-proxy-ca.crt", "", ca2),
+			},
+		},
+		{
+			name: "invalid: different CA",
+			inputInfos: []CertificateInfo{
+				NewCertificateInfo("cp1", path, "", ca1),
+				NewCertificateInfo("cp2", path, "", ca2),
+			},
+			expectedProblems: 2,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if problems := CheckSameCertificate(test.inputInfos, path); len(problems) != test.expectedProblems {
+				t.Fatalf("expected %d problems, found %v", test.expectedProblems, problems)
+			}
+		})
+	}
+}
+
+func TestShiftCertificate(t *testing.T) {
+	ca, caKey := newTestCertificate(t, nil, nil, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "kubernetes"},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	})
+	cert, key := newTestCertificate(t, ca, caKey, &x509.Certificate{
+		Subject:  pkix.Name{CommonName: "kube-apiserver"},
+		DNSNames: []string{"localhost"},
+	})
+
+	offset := 365 * 24 * time.Hour
+	shifted, err := ShiftCertificate(cert, ca, caKey, offset)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !IsSignedBy(shifted, ca) {
+		t.Fatalf("shifted certificate is not signed by the CA")
+	}
+	if !shifted.NotAfter.Equal(cert.NotAfter.Add(-offset)) || !shifted.NotBefore.Equal(cert.NotBefore.Add(-offset)) {
+		t.Fatalf("unexpected validity: %s - %s", shifted.NotBefore, shifted.NotAfter)
+	}
+	if !reflect.DeepEqual(shifted.DNSNames, cert.DNSNames) || !reflect.DeepEqual(shifted.PublicKey, cert.PublicKey) {
+		t.Fatalf("shifted certificate does not preserve SANs and public key")
+	}
+
+	// checks that a self-signed CA shifted with its own key still verifies certificates signed by the original CA
+	shiftedCA, err := ShiftCertificate(ca, ca, caKey, offset)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !shiftedCA.IsCA || !shiftedCA.NotAfter.Equal(ca.NotAfter.Add(-offset)) {
+		t.Fatalf("unexpected shifted CA: IsCA %v, NotAfter %s", shiftedCA.IsCA, shiftedCA.NotAfter)
+	}
+	if !IsSignedBy(shiftedCA, shiftedCA) || !IsSignedBy(cert, shiftedCA) {
+		t.Fatalf("shifted CA does not verify certificates signed by the original CA")
+	}
+
+	// checks that replacing the certificate in a PEM file preserves the private key
+	data := append(
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}),
+		pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})...,
+	)
+	data, err = ReplaceCertificate(data, shifted)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	certs, err := ParseCertificates(data)
+	if err != nil || !certs[0].Equal(shifted) {
+		t.Fatalf("certificate was not replaced, error: %v", err)
+	}
+	if _, err := ParsePrivateKey(data); err != nil {
+		t.Fatalf("private key was not preserved, error: %v", err)
+	}
+}
+
+func TestVerifyCertificate(t *testing.T) {
+	root, rootKey, err := NewCA("root", nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	intermediate, intermediateKey, err := NewCA("kubernetes", root, rootKey)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	other, _, err := NewCA("other", nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	leaf, _ := newTestCertificate(t, intermediate, intermediateKey.(*rsa.PrivateKey), &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "kube-apiserver"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	})
+
+	tests := []struct {
+		name          string
+		inputCert     *x509.Certificate
+		inputBundle   []*x509.Certificate
+		expectedError bool
+	}{
+		{
+			name:        "valid: leaf signed by the intermediate CA in the bundle",
+			inputCert:   leaf,
+			inputBundle: []*x509.Certificate{intermediate, root},
+		},
+		{
+			name:        "valid: intermediate CA signed by the root CA",
+			inputCert:   intermediate,
+			inputBundle: []*x509.Certificate{root},
+		},
+		{
+			name:          "invalid: leaf signed by another CA",
+			inputCert:     leaf,
+			inputBundle:   []*x509.Certificate{other},
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := VerifyCertificate(test.inputCert, test.inputBundle)
+			if (err != nil) != test.expectedError {
+				t.Fatalf("expected error: %v, found %v, error: %v", test.expectedError, err != nil, err)
+			}
+		})
+	}
+}
+
+func kubeadmCertificateNames(certs []KubeadmCertificate) []string {
+	names := []string{}
+	for _, c := range certs {
+		names = append(names, c.Name)
+	}
+	return names
+}
+
+// newTestCertificate creates a certificate from a template, signed by the given CA or self-signed if the CA is nil
+func newTestCertificate(t *testing.T, ca *x509.Certificate, caKey *rsa.PrivateKey, template *x509.Certificate) (*x509.Certificate, *rsa.PrivateKey) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	template.SerialNumber = big.NewInt(time.Now().UnixNano())
+	template.NotBefore = time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+	template.NotAfter = time.Now().Add(365 * 24 * time.Hour).UTC().Truncate(time.Second)
+	if ca == nil {
+		ca, caKey = template, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, key.Public(), caKey)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+	return cert, key
+}
@@ -0,0 +1,25 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package certs contains the list of certificates managed by kubeadm and utilities
+for parsing and inspecting them.
+
+Having a direct control on certificates is a specific necessity for kinder, because
+certificate related kubeadm features - like certificate renewal - can only be tested
+by checking what kubeadm actually wrote on the nodes.
+*/
+package certs
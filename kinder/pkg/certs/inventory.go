@@ -0,0 +1,142 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// CertificateInfo describes a certificate found on a node
+type CertificateInfo struct {
+	// Node hosting the certificate
+	Node string `json:"node"`
+	// Path of the certificate file or of the kubeconfig file embedding the certificate
+	Path string `json:"path"`
+	// User is the kubeconfig user the certificate belongs to, if the certificate is embedded in a kubeconfig file
+	User string `json:"user,omitempty"`
+
+	Subject     string    `json:"subject"`
+	Issuer      string    `json:"issuer"`
+	KeyType     string    `json:"keyType"`
+	IsCA        bool      `json:"isCA"`
+	NotBefore   time.Time `json:"notBefore"`
+	NotAfter    time.Time `json:"notAfter"`
+	DNSNames    []string  `json:"dnsNames,omitempty"`
+	IPAddresses []string  `json:"ipAddresses,omitempty"`
+	Fingerprint string    `json:"fingerprint"`
+
+	cert *x509.Certificate
+}
+
+// NewCertificateInfo returns the CertificateInfo for a certificate
+func NewCertificateInfo(node, path, user string, cert *x509.Certificate) CertificateInfo {
+	ips := []string{}
+	for _, ip := range cert.IPAddresses {
+		ips = append(ips, ip.String())
+	}
+
+	return CertificateInfo{
+		Node:        node,
+		Path:        path,
+		User:        user,
+		Subject:     cert.Subject.String(),
+		Issuer:      cert.Issuer.String(),
+		KeyType:     KeyType(cert),
+		IsCA:        cert.IsCA,
+		NotBefore:   cert.NotBefore.UTC(),
+		NotAfter:    cert.NotAfter.UTC(),
+		DNSNames:    cert.DNSNames,
+		IPAddresses: ips,
+		Fingerprint: fmt.Sprintf("%x", sha256.Sum256(cert.Raw)),
+		cert:        cert,
+	}
+}
+
+// KeyType returns a description of the type of the public key of a certificate, e.g. RSA-2048
+func KeyType(cert *x509.Certificate) string {
+	switch k := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return fmt.Sprintf("RSA-%d", k.N.BitLen())
+	case *ecdsa.PublicKey:
+		return fmt.Sprintf("ECDSA-%s", k.Curve.Params().Name)
+	case ed25519.PublicKey:
+		return "Ed25519"
+	}
+	return cert.PublicKeyAlgorithm.String()
+}
+
+// CheckSameCertificate checks that the certificate with the given path is the same on all the nodes
+// where it exists, e.g. for checking that all the nodes are using the same CA
+func CheckSameCertificate(infos []CertificateInfo, path string) []string {
+	fingerprints := map[string][]string{}
+	for _, i := range infos {
+		if i.Path != path || i.User != "" {
+			continue
+		}
+		fingerprints[i.Fingerprint] = append(fingerprints[i.Fingerprint], i.Node)
+	}
+
+	if len(fingerprints) <= 1 {
+		return nil
+	}
+
+	problems := []string{}
+	for f, nodes := range fingerprints {
+		problems = append(problems, fmt.Sprintf("%s differs across nodes: nodes %v have fingerprint %s", path, nodes, f))
+	}
+	sort.Strings(problems)
+	return problems
+}
+
+// CheckSANs checks that a certificate is valid for all the given host names or IP addresses
+func CheckSANs(info CertificateInfo, hosts []string) []string {
+	problems := []string{}
+	for _, h := range hosts {
+		if h == "" {
+			continue
+		}
+		if err := info.cert.VerifyHostname(h); err != nil {
+			problems = append(problems, fmt.Sprintf("%s on %s is not valid for %s", info.Path, info.Node, h))
+		}
+	}
+	return problems
+}
+
+// CheckExpiration checks that a certificate is not expired at the given time
+func CheckExpiration(info CertificateInfo, now time.Time) []string {
+	if now.Before(info.NotBefore) {
+		return []string{fmt.Sprintf("%s on %s is not yet valid (NotBefore %s)", displayPath(info), info.Node, info.NotBefore)}
+	}
+	if now.After(info.NotAfter) {
+		return []string{fmt.Sprintf("%s on %s is expired (NotAfter %s)", displayPath(info), info.Node, info.NotAfter)}
+	}
+	return nil
+}
+
+func displayPath(info CertificateInfo) string {
+	if info.User != "" {
+		return fmt.Sprintf("%s (user %s)", info.Path, info.User)
+	}
+	return info.Path
+}
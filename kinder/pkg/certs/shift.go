@@ -0,0 +1,109 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package certs
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ParsePrivateKey parses the first PEM encoded private key in data; PKCS1, PKCS8 and
+// EC private keys are supported
+func ParsePrivateKey(data []byte) (crypto.Signer, error) {
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+
+		switch block.Type {
+		case "RSA PRIVATE KEY":
+			return x509.ParsePKCS1PrivateKey(block.Bytes)
+		case "EC PRIVATE KEY":
+			return x509.ParseECPrivateKey(block.Bytes)
+		case "PRIVATE KEY":
+			key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+			if err != nil {
+				return nil, err
+			}
+			signer, ok := key.(crypto.Signer)
+			if !ok {
+				return nil, errors.New("unsupported private key type")
+			}
+			return signer, nil
+		}
+	}
+	return nil, errors.New("no private key found")
+}
+
+// ShiftCertificate signs again a certificate with the given CA, preserving the certificate
+// subject, SANs, usages and public key but moving the validity period backwards by offset
+func ShiftCertificate(cert, caCert *x509.Certificate, caKey crypto.Signer, offset time.Duration) (*x509.Certificate, error) {
+	serial, err := newSerialNumber()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate serial number")
+	}
+
+	template := *cert
+	template.SerialNumber = serial
+	template.NotBefore = cert.NotBefore.Add(-offset)
+	template.NotAfter = cert.NotAfter.Add(-offset)
+	// let x509 select the signature algorithm corresponding to the CA key
+	template.SignatureAlgorithm = x509.UnknownSignatureAlgorithm
+
+	der, err := x509.CreateCertificate(rand.Reader, &template, caCert, cert.PublicKey, caKey)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to sign certificate %s", cert.Subject.CommonName)
+	}
+	return x509.ParseCertificate(der)
+}
+
+// ReplaceCertificate returns a copy of PEM encoded data where the first certificate is replaced
+// by cert; other PEM blocks, like e.g. private keys, are preserved
+func ReplaceCertificate(data []byte, cert *x509.Certificate) ([]byte, error) {
+	var out []byte
+	replaced := false
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+
+		if block.Type == "CERTIFICATE" && !replaced {
+			block = &pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}
+			replaced = true
+		}
+		out = append(out, pem.EncodeToMemory(block)...)
+	}
+
+	if !replaced {
+		return nil, errors.New("no certificates found")
+	}
+	return out, nil
+}
+
+// IsSignedBy returns true if cert was issued by the given CA
+func IsSignedBy(cert, caCert *x509.Certificate) bool {
+	return cert.CheckSignatureFrom(caCert) == nil
+}
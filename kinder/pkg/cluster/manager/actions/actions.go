@@ -36,6 +36,11 @@ var actionRegistry = map[string]func(*status.Cluster, *RunOptions) error{
 		// to invoke it separately as well
 		return LoadBalancer(c, c.ControlPlanes()...)
 	},
+	"control-plane-vip": func(c *status.Cluster, flags *RunOptions) error {
+		// Nb. this action is invoked automatically at kubeadm init/join time, but it is possible
+		// to invoke it separately as well
+		return ControlPlaneVIP(c, c.ControlPlanes().EligibleForActions()...)
+	},
 	"kubeadm-config": func(c *status.Cluster, flags *RunOptions) error {
 		// Nb. this action is invoked automatically at kubeadm init/join time, but it is possible
 		// to invoke it separately as well
@@ -48,23 +53,79 @@ var actionRegistry = map[string]func(*status.Cluster, *RunOptions) error{
 		return KubeadmJoin(c, flags.usePhases, flags.copyCertsMode, flags.discoveryMode, flags.patchesDir, flags.ignorePreflightErrors, flags.wait, flags.vLevel)
 	},
 	"kubeadm-upgrade": func(c *status.Cluster, flags *RunOptions) error {
+		if len(flags.upgradePath) > 0 {
+			return KubeadmUpgradeChain(c, flags.upgradePath, flags.patchesDir, flags.wait, flags.vLevel)
+		}
 		return KubeadmUpgrade(c, flags.upgradeVersion, flags.patchesDir, flags.wait, flags.vLevel)
 	},
+	"kubeadm-upgrade-rollback": func(c *status.Cluster, flags *RunOptions) error {
+		return KubeadmUpgradeRollback(c, flags.upgradeVersion, flags.upgradeFailComponent, flags.patchesDir, flags.wait, flags.vLevel)
+	},
 	"kubeadm-reset": func(c *status.Cluster, flags *RunOptions) error {
-		return KubeadmReset(c, flags.vLevel)
+		return KubeadmReset(c, flags.verifyReset, flags.wait, flags.vLevel)
+	},
+	"kubeadm-certs-renew": func(c *status.Cluster, flags *RunOptions) error {
+		return KubeadmCertsRenew(c, flags.certs, flags.wait, flags.vLevel)
+	},
+	"time-travel": func(c *status.Cluster, flags *RunOptions) error {
+		return TimeTravel(c, flags.offset, flags.wait)
+	},
+	"etcd-snapshot": func(c *status.Cluster, flags *RunOptions) error {
+		return EtcdSnapshot(c, flags.args, flags.wait)
+	},
+	"etcd-health": func(c *status.Cluster, flags *RunOptions) error {
+		return EtcdHealth(c, flags.etcdDefrag)
+	},
+	"addons": func(c *status.Cluster, flags *RunOptions) error {
+		return Addons(c, flags.manifests, flags.wait)
 	},
 	"copy-certs": func(c *status.Cluster, flags *RunOptions) error {
 		return CopyCertificates(c)
 	},
 	"setup-external-ca": func(c *status.Cluster, flags *RunOptions) error {
-		return SetupExternalCA(c, flags.vLevel)
+		return SetupExternalCA(c, flags.externalCAs, flags.externalCAIntermediate, flags.vLevel)
+	},
+	"verify-external-ca": func(c *status.Cluster, flags *RunOptions) error {
+		return VerifyExternalCA(c, flags.externalCAs, flags.externalCAIntermediate)
 	},
 	"cluster-info": func(c *status.Cluster, flags *RunOptions) error {
 		return CluterInfo(c)
 	},
 	"smoke-test": func(c *status.Cluster, flags *RunOptions) error {
-		return SmokeTest(c, flags.wait)
+		return SmokeTest(c, flags.smokeTests, flags.smokeTestImage, flags.wait)
+	},
+	"chaos": func(c *status.Cluster, flags *RunOptions) error {
+		return Chaos(c, flags.args, flags.chaosLatency, flags.wait)
+	},
+	"replace-binaries": func(c *status.Cluster, flags *RunOptions) error {
+		return ReplaceBinaries(c, flags.kubeadm, flags.kubelet, flags.images, flags.updateManifests, flags.wait)
+	},
+	"bootstrap-token": func(c *status.Cluster, flags *RunOptions) error {
+		return BootstrapToken(c, flags.args, flags.tokenTTL, flags.vLevel)
 	},
+	"negative-test": func(c *status.Cluster, flags *RunOptions) error {
+		return NegativeTest(c, flags.args, flags.ignorePreflightErrors, flags.wait, flags.vLevel)
+	},
+}
+
+// actionsWithArgs defines the list of actions accepting additional arguments; those actions are
+// responsible for validating their own arguments
+var actionsWithArgs = map[string]bool{
+	"etcd-snapshot":   true,
+	"chaos":           true,
+	"bootstrap-token": true,
+	"negative-test":   true,
+}
+
+// ValidateArgs checks that additional arguments are passed only to actions supporting them
+func ValidateArgs(action string, args []string) error {
+	if _, ok := actionRegistry[action]; !ok {
+		return errors.Errorf("%s is not a valid action name. Use one of %s", action, KnownActions())
+	}
+	if len(args) > 0 && !actionsWithArgs[action] {
+		return errors.Errorf("%s does not accept arguments, got %v", action, args)
+	}
+	return nil
 }
 
 // KnownActions returns the list of known actions
@@ -117,6 +178,143 @@ func UpgradeVersion(upgradeVersion *K8sVersion.Version) Option {
 	}
 }
 
+// UpgradePath option instructs kubeadm actions to execute consecutive upgrades, one for each version in the path
+func UpgradePath(upgradePath []*K8sVersion.Version) Option {
+	return func(r *RunOptions) {
+		r.upgradePath = upgradePath
+	}
+}
+
+// UpgradeFailComponent option instructs the kubeadm-upgrade-rollback action to interrupt the upgrade
+// when upgrading the given control-plane component
+func UpgradeFailComponent(component string) Option {
+	return func(r *RunOptions) {
+		r.upgradeFailComponent = component
+	}
+}
+
+// VerifyReset option instructs kubeadm reset action to verify that nodes are cleaned up properly
+func VerifyReset(verifyReset bool) Option {
+	return func(r *RunOptions) {
+		r.verifyReset = verifyReset
+	}
+}
+
+// Certs option instructs kubeadm certs renew action to renew only the given certificates
+func Certs(certs []string) Option {
+	return func(r *RunOptions) {
+		r.certs = certs
+	}
+}
+
+// Offset option instructs the time-travel action to move the clock of the nodes forward by the given offset
+func Offset(offset time.Duration) Option {
+	return func(r *RunOptions) {
+		r.offset = offset
+	}
+}
+
+// ExternalCAs option instructs setup-external-ca and verify-external-ca actions about the CAs to be external
+func ExternalCAs(externalCAs []ExternalCA) Option {
+	return func(r *RunOptions) {
+		r.externalCAs = externalCAs
+	}
+}
+
+// ExternalCAIntermediate option instructs setup-external-ca and verify-external-ca actions to use
+// an intermediate CA signed by an offline root CA as a cluster CA
+func ExternalCAIntermediate(intermediate bool) Option {
+	return func(r *RunOptions) {
+		r.externalCAIntermediate = intermediate
+	}
+}
+
+// Args option provides additional arguments to actions that support them, e.g. etcd-snapshot
+func Args(args []string) Option {
+	return func(r *RunOptions) {
+		r.args = args
+	}
+}
+
+// EtcdDefrag option instructs the etcd-health action to defragment all the etcd members
+func EtcdDefrag(defrag bool) Option {
+	return func(r *RunOptions) {
+		r.etcdDefrag = defrag
+	}
+}
+
+// Manifests option instructs the addons action about the manifests to be applied; each manifest can be
+// a directory, a file or a URL
+func Manifests(manifests []string) Option {
+	return func(r *RunOptions) {
+		r.manifests = manifests
+	}
+}
+
+// SmokeTests option instructs the smoke-test action to execute only the given checks
+func SmokeTests(smokeTests []string) Option {
+	return func(r *RunOptions) {
+		r.smokeTests = smokeTests
+	}
+}
+
+// SmokeTestImage option instructs the smoke-test action to use the given image
+func SmokeTestImage(image string) Option {
+	return func(r *RunOptions) {
+		r.smokeTestImage = image
+	}
+}
+
+// ChaosLatency option instructs the chaos action about the latency to be injected by the etcd-latency scenario
+func ChaosLatency(latency time.Duration) Option {
+	return func(r *RunOptions) {
+		r.chaosLatency = latency
+	}
+}
+
+// Kubeadm option instructs the replace-binaries action to push the kubeadm binary from the given source into the nodes
+func Kubeadm(src string) Option {
+	return func(r *RunOptions) {
+		r.kubeadm = src
+	}
+}
+
+// Kubelet option instructs the replace-binaries action to push the kubelet binary from the given source into the nodes
+func Kubelet(src string) Option {
+	return func(r *RunOptions) {
+		r.kubelet = src
+	}
+}
+
+// Images option instructs the replace-binaries action to import the image tarballs from the given source into the nodes
+func Images(src string) Option {
+	return func(r *RunOptions) {
+		r.images = src
+	}
+}
+
+// UpdateManifests option instructs the replace-binaries action to update static pod manifests to use the new images
+func UpdateManifests(updateManifests bool) Option {
+	return func(r *RunOptions) {
+		r.updateManifests = updateManifests
+	}
+}
+
+// TokenTTL option instructs the bootstrap-token action about the TTL of the bootstrap tokens to be created
+func TokenTTL(ttl time.Duration) Option {
+	return func(r *RunOptions) {
+		r.tokenTTL = ttl
+	}
+}
+
+// Diff option instructs actions.Run to write a diff of static pod manifests, kubelet config files
+// and kube-system ConfigMaps before and after the action
+func Diff(diff bool) Option {
+	return func(r *RunOptions) {
+		r.diff = diff
+	}
+}
+
 // Discovery option instructs kubeadm join to use a specific discovery mode
 func Discovery(discoveryMode DiscoveryMode) Option {
 	return func(r *RunOptions) {
@@ -147,15 +345,34 @@ func IgnorePreflightErrors(ignorePreflightErrors string) Option {
 
 // RunOptions holds options supplied to actions.Run
 type RunOptions struct {
-	kubeDNS               bool
-	usePhases             bool
-	copyCertsMode         CopyCertsMode
-	discoveryMode         DiscoveryMode
-	wait                  time.Duration
-	upgradeVersion        *K8sVersion.Version
-	vLevel                int
-	patchesDir            string
-	ignorePreflightErrors string
+	kubeDNS                bool
+	usePhases              bool
+	copyCertsMode          CopyCertsMode
+	discoveryMode          DiscoveryMode
+	wait                   time.Duration
+	upgradeVersion         *K8sVersion.Version
+	upgradePath            []*K8sVersion.Version
+	upgradeFailComponent   string
+	vLevel                 int
+	patchesDir             string
+	ignorePreflightErrors  string
+	verifyReset            bool
+	certs                  []string
+	offset                 time.Duration
+	externalCAs            []ExternalCA
+	externalCAIntermediate bool
+	args                   []string
+	etcdDefrag             bool
+	manifests              []string
+	smokeTests             []string
+	smokeTestImage         string
+	chaosLatency           time.Duration
+	kubeadm                string
+	kubelet                string
+	images                 string
+	updateManifests        bool
+	tokenTTL               time.Duration
+	diff                   bool
 }
 
 // DiscoveryMode defines discovery mode supported by kubeadm join
@@ -245,9 +462,13 @@ func Run(c *status.Cluster, action string, options ...Option) error {
 		o(flags)
 	}
 
-	if a, ok := actionRegistry[action]; ok {
-		return a(c, flags)
+	if err := ValidateArgs(action, flags.args); err != nil {
+		return err
 	}
 
-	return errors.Errorf("%s is not a valid action name. Use one of %s", action, KnownActions())
+	a := actionRegistry[action]
+	if flags.diff {
+		return runWithDiff(c, action, flags, a)
+	}
+	return a(c, flags)
 }
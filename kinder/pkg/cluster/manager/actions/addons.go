@@ -0,0 +1,209 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+)
+
+// addonsDir defines the folder on the bootstrap control-plane node where addon manifests are copied
+const addonsDir = "/kinder/addons"
+
+// addonManifest defines a manifest to be applied by the addons action
+type addonManifest struct {
+	// Source of the manifest, that is the path of a file on the host or a URL
+	Source string
+
+	// Data of the manifest
+	Data []byte
+}
+
+// Addons applies a list of manifests to the cluster using the admin kubeconfig on the bootstrap
+// control-plane node and waits for the Deployments, StatefulSets and DaemonSets defined in the manifests
+// to become ready. Each entry can be a directory, a file or a URL; directories are not read recursively and
+// only .yaml, .yml and .json files are applied, in alphabetical order. Manifests are read on the host
+// and then copied to the node, so local files can be used in offline environments.
+func Addons(c *status.Cluster, entries []string, wait time.Duration) error {
+	if len(entries) == 0 {
+		return errors.New("addons requires at least one manifest. Use --manifests to provide a list of directories, files or URLs")
+	}
+
+	manifests, err := readAddonManifests(entries)
+	if err != nil {
+		return err
+	}
+
+	workloads := []workload{}
+	for _, m := range manifests {
+		w, err := parseWorkloads(m.Data)
+		if err != nil {
+			return errors.Wrapf(err, "failed to parse manifest %s", m.Source)
+		}
+		workloads = append(workloads, w...)
+	}
+
+	cp1 := c.BootstrapControlPlane()
+	if err := cp1.Command("mkdir", "-p", addonsDir).Silent().Run(); err != nil {
+		return errors.Wrapf(err, "failed to create %s", addonsDir)
+	}
+
+	for i, m := range manifests {
+		// NB. manifests are prefixed with an index for preserving the order in which they are applied
+		dest := filepath.Join(addonsDir, fmt.Sprintf("%02d-%s", i, path.Base(m.Source)))
+
+		cp1.Infof("applying %s", m.Source)
+		if !cp1.IsDryRun() {
+			if err := cp1.WriteFile(dest, m.Data); err != nil {
+				return errors.Wrapf(err, "failed to copy %s to node %s", m.Source, cp1.Name())
+			}
+		}
+		if err := cp1.Command(
+			"kubectl", "--kubeconfig=/etc/kubernetes/admin.conf", "apply", "-f", dest,
+		).RunWithEcho(); err != nil {
+			return errors.Wrapf(err, "failed to apply %s", m.Source)
+		}
+	}
+
+	return waitWorkloadsReady(c, cp1, workloads, wait)
+}
+
+// readAddonManifests reads the manifests corresponding to the given list of directories, files or URLs
+func readAddonManifests(entries []string) ([]addonManifest, error) {
+	manifests := []addonManifest{}
+	for _, e := range entries {
+		if strings.HasPrefix(e, "http://") || strings.HasPrefix(e, "https://") {
+			data, err := httpGetManifest(e)
+			if err != nil {
+				return nil, err
+			}
+			manifests = append(manifests, addonManifest{Source: e, Data: data})
+			continue
+		}
+
+		info, err := os.Stat(e)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read %s", e)
+		}
+
+		files := []string{e}
+		if info.IsDir() {
+			files, err = manifestFiles(e)
+			if err != nil {
+				return nil, err
+			}
+			if len(files) == 0 {
+				return nil, errors.Errorf("directory %s does not contain manifests", e)
+			}
+		}
+
+		for _, f := range files {
+			data, err := ioutil.ReadFile(f)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to read %s", f)
+			}
+			manifests = append(manifests, addonManifest{Source: f, Data: data})
+		}
+	}
+	return manifests, nil
+}
+
+// manifestFiles returns the .yaml, .yml and .json files in a directory, in alphabetical order
+func manifestFiles(dir string) ([]string, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read directory %s", dir)
+	}
+
+	files := []string{}
+	for _, info := range infos {
+		if info.IsDir() {
+			continue
+		}
+		switch filepath.Ext(info.Name()) {
+		case ".yaml", ".yml", ".json":
+			files = append(files, filepath.Join(dir, info.Name()))
+		}
+	}
+	return files, nil
+}
+
+// httpGetManifest reads a manifest from a URL
+func httpGetManifest(url string) ([]byte, error) {
+	client := http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, errors.Wrapf(err, "HTTP GET %s failed", url)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("HTTP GET %s failed: %s", url, resp.Status)
+	}
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %s", url)
+	}
+	return data, nil
+}
+
+// parseWorkloads returns the Deployments, StatefulSets and DaemonSets defined in a manifest;
+// workloads without namespace are assumed to be in the default namespace
+func parseWorkloads(data []byte) ([]workload, error) {
+	type object struct {
+		Kind     string `json:"kind"`
+		Metadata struct {
+			Name      string `json:"name"`
+			Namespace string `json:"namespace"`
+		} `json:"metadata"`
+	}
+
+	workloads := []workload{}
+	decoder := yaml.NewYAMLOrJSONDecoder(bytes.NewReader(data), 4096)
+	for {
+		var o object
+		if err := decoder.Decode(&o); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+
+		if !workloadKinds[o.Kind] {
+			continue
+		}
+		if o.Metadata.Namespace == "" {
+			o.Metadata.Namespace = "default"
+		}
+		workloads = append(workloads, workload{Kind: o.Kind, Namespace: o.Metadata.Namespace, Name: o.Metadata.Name})
+	}
+	return workloads, nil
+}
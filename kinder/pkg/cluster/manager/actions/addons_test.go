@@ -0,0 +1,101 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseWorkloads(t *testing.T) {
+	tests := []struct {
+		name              string
+		manifest          string
+		expectedWorkloads []workload
+		expectedError     bool
+	}{
+		{
+			name: "multi document YAML",
+			manifest: `
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: metrics-server
+  namespace: kube-system
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: metrics-server
+  namespace: kube-system
+---
+apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: web
+---
+apiVersion: apps/v1
+kind: DaemonSet
+metadata:
+  name: ingress
+  namespace: ingress
+`,
+			expectedWorkloads: []workload{
+				{Kind: "Deployment", Namespace: "kube-system", Name: "metrics-server"},
+				{Kind: "StatefulSet", Namespace: "default", Name: "web"},
+				{Kind: "DaemonSet", Namespace: "ingress", Name: "ingress"},
+			},
+		},
+		{
+			name:     "JSON",
+			manifest: `{"apiVersion": "apps/v1", "kind": "Deployment", "metadata": {"name": "provisioner", "namespace": "storage"}}`,
+			expectedWorkloads: []workload{
+				{Kind: "Deployment", Namespace: "storage", Name: "provisioner"},
+			},
+		},
+		{
+			name: "no workloads",
+			manifest: `
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: storage
+`,
+			expectedWorkloads: []workload{},
+		},
+		{
+			name:          "invalid YAML",
+			manifest:      "kind: [Deployment",
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			workloads, err := parseWorkloads([]byte(test.manifest))
+			if (err != nil) != test.expectedError {
+				t.Fatalf("expected error %t, got %v", test.expectedError, err)
+			}
+			if test.expectedError {
+				return
+			}
+			if !reflect.DeepEqual(workloads, test.expectedWorkloads) {
+				t.Errorf("expected workloads %v, got %v", test.expectedWorkloads, workloads)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/constants"
+)
+
+// DefaultTokenTTL defines the TTL of bootstrap tokens created by the bootstrap-token action; this is the same default of kubeadm
+const DefaultTokenTTL = 24 * time.Hour
+
+// BootstrapToken executes kubeadm token create, list or delete on the bootstrap control-plane node; args are
+// the token command and, optionally for create and required for delete, the token. Tokens are created with the given TTL,
+// where 0 means that the token never expires.
+func BootstrapToken(c *status.Cluster, args []string, ttl time.Duration, vLevel int) error {
+	if len(args) < 1 || len(args) > 2 {
+		return errors.New("bootstrap-token requires a command, create, list or delete, and optionally a token")
+	}
+
+	cp1 := c.BootstrapControlPlane()
+	tokenArgs := []string{"token", args[0]}
+	switch args[0] {
+	case "create":
+		tokenArgs = append(tokenArgs, args[1:]...)
+		tokenArgs = append(tokenArgs, fmt.Sprintf("--ttl=%s", ttl))
+	case "list":
+		if len(args) != 1 {
+			return errors.New("bootstrap-token list does not accept a token")
+		}
+	case "delete":
+		if len(args) != 2 {
+			return errors.New("bootstrap-token delete requires a token")
+		}
+		tokenArgs = append(tokenArgs, args[1])
+	default:
+		return errors.Errorf("invalid bootstrap-token command %q. Use one of [create list delete]", args[0])
+	}
+	tokenArgs = append(tokenArgs, fmt.Sprintf("--v=%d", vLevel))
+
+	if err := cp1.Command("kubeadm", tokenArgs...).RunWithEcho(); err != nil {
+		return errors.Wrapf(err, "failed to execute kubeadm token %s on node %s", args[0], cp1.Name())
+	}
+	return nil
+}
+
+// createBootstrapToken creates a bootstrap token with the given TTL on the bootstrap control-plane node and returns it
+func createBootstrapToken(c *status.Cluster, ttl time.Duration) (string, error) {
+	cp1 := c.BootstrapControlPlane()
+	cp1.Infof("creating a bootstrap token with TTL %s", ttl)
+
+	lines, err := cp1.Command(
+		"kubeadm", "token", "create", fmt.Sprintf("--ttl=%s", ttl),
+	).Silent().RunAndCapture()
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to create a bootstrap token on node %s", cp1.Name())
+	}
+	if cp1.IsDryRun() {
+		return constants.Token, nil
+	}
+	token := parseBootstrapToken(lines)
+	if token == "" {
+		return "", errors.Errorf("failed to create a bootstrap token on node %s: no token returned", cp1.Name())
+	}
+	return token, nil
+}
+
+// bootstrapTokenRegex matches a bootstrap token, e.g. abcdef.0123456789abcdef
+var bootstrapTokenRegex = regexp.MustCompile(`^[a-z0-9]{6}\.[a-z0-9]{16}$`)
+
+// parseBootstrapToken returns the bootstrap token printed by kubeadm token create, skipping warnings, if any
+func parseBootstrapToken(lines []string) string {
+	for _, l := range lines {
+		if t := strings.TrimSpace(l); bootstrapTokenRegex.MatchString(t) {
+			return t
+		}
+	}
+	return ""
+}
+
+// bootstrapTokenID returns the ID of a bootstrap token, that is the part of the token before the dot
+func bootstrapTokenID(token string) string {
+	return strings.SplitN(token, ".", 2)[0]
+}
+
+// deleteBootstrapToken deletes a bootstrap token on the bootstrap control-plane node
+func deleteBootstrapToken(c *status.Cluster, token string) error {
+	cp1 := c.BootstrapControlPlane()
+	if err := cp1.Command("kubeadm", "token", "delete", token).Silent().Run(); err != nil {
+		return errors.Wrapf(err, "failed to delete the bootstrap token on node %s", cp1.Name())
+	}
+	return nil
+}
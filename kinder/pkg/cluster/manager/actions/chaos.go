@@ -0,0 +1,532 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/cri"
+	"k8s.io/kubeadm/kinder/pkg/exec"
+	"k8s.io/kubeadm/kinder/pkg/exec/colors"
+)
+
+const (
+	// DefaultChaosLatency defines the latency injected on the etcd peer port by the etcd-latency scenario
+	DefaultChaosLatency = 200 * time.Millisecond
+
+	// chaosChain defines the iptables chain used for partitioning a node from the other nodes
+	chaosChain = "KINDER-CHAOS"
+
+	// chaosInterface defines the network interface of nodes where latency is injected
+	chaosInterface = "eth0"
+
+	// etcdPeerPort defines the port used by etcd members for peer communication
+	etcdPeerPort = 2380
+)
+
+// chaosContext holds the settings of a chaos scenario
+type chaosContext struct {
+	c       *status.Cluster
+	latency time.Duration
+	wait    time.Duration
+}
+
+// chaosScenario defines a named scenario executed by the chaos action
+type chaosScenario struct {
+	name        string
+	description string
+	run         func(x *chaosContext) error
+}
+
+// chaosScenarios defines the list of scenarios supported by the chaos action
+var chaosScenarios = []chaosScenario{
+	{name: "control-plane-down", description: "stop a control plane node container, then start it again", run: chaosControlPlaneDown},
+	{name: "kubelet-pause", description: "pause the kubelet on a node, then resume it", run: chaosKubeletPause},
+	{name: "etcd-leader-kill", description: "kill the etcd leader, then let the kubelet restart it", run: chaosEtcdLeaderKill},
+	{name: "network-partition", description: "partition a node from the other nodes, then heal the partition", run: chaosNetworkPartition},
+	{name: "etcd-latency", description: "inject latency on the etcd peer port of a control plane node, then remove it", run: chaosEtcdLatency},
+}
+
+// KnownChaosScenarios returns the list of chaos scenarios
+func KnownChaosScenarios() []string {
+	names := []string{}
+	for _, s := range chaosScenarios {
+		names = append(names, s.name)
+	}
+	return names
+}
+
+// Chaos injects a fault in the cluster according to the selected scenario, verifies that the cluster
+// behaves as expected while the fault is active, e.g. the API server is still reachable via the control
+// plane endpoint and etcd keeps quorum, then restores the original state and verifies that the cluster recovers.
+// The fault is injected on the last control plane/node eligible for actions, unless differently specified
+// by the scenario.
+func Chaos(c *status.Cluster, args []string, latency, wait time.Duration) error {
+	if len(args) != 1 {
+		return errors.Errorf("chaos requires a scenario. Use one of %s", KnownChaosScenarios())
+	}
+
+	x := &chaosContext{
+		c:       c,
+		latency: latency,
+		wait:    wait,
+	}
+	for _, s := range chaosScenarios {
+		if s.name == args[0] {
+			fmt.Printf("Executing chaos scenario %s: %s\n", s.name, s.description)
+			return s.run(x)
+		}
+	}
+	return errors.Errorf("invalid chaos scenario %q. Use one of %s", args[0], KnownChaosScenarios())
+}
+
+// chaosControlPlaneDown stops a control plane node container and verifies that the API server is still reachable
+// via the control plane endpoint, that etcd keeps quorum and that the load balancer stops routing traffic to the node;
+// then the container is started again and the node is expected to rejoin the cluster
+func chaosControlPlaneDown(x *chaosContext) error {
+	target, observer, err := chaosControlPlaneTarget(x.c)
+	if err != nil {
+		return err
+	}
+
+	ipv4, ipv6, err := target.IP()
+	if err != nil {
+		return errors.Wrapf(err, "failed to get IP for node %s", target.Name())
+	}
+
+	return runChaos(
+		func() error {
+			target.Infof("stopping the node container")
+			return hostDocker(target, "stop", target.Name())
+		},
+		func() error {
+			conditions := []try{apiServerIsReachable, etcdQuorumIsKept(observer)}
+			if loadBalancerHasStats(x.c) {
+				conditions = append(conditions, loadBalancerBackendIsDown)
+			}
+			return waitChaosFault(x.c, target, x.wait, conditions...)
+		},
+		func() error {
+			target.Infof("starting the node container")
+			if err := hostDocker(target, "start", target.Name()); err != nil {
+				return err
+			}
+			// NB. docker might assign a different IP to the restarted container, and this breaks the cluster
+			newIPv4, newIPv6, err := target.IP()
+			if err != nil {
+				return errors.Wrapf(err, "failed to get IP for node %s", target.Name())
+			}
+			if newIPv4 != ipv4 || newIPv6 != ipv6 {
+				return errors.Errorf("node %s restarted with a different IP", target.Name())
+			}
+			return nil
+		},
+		func() error {
+			return waitNewControlPlaneNodeReady(x.c, target, x.wait)
+		},
+	)
+}
+
+// chaosKubeletPause pauses the kubelet on a node and verifies that the node becomes NotReady;
+// then the kubelet is resumed and the node is expected to become Ready again
+func chaosKubeletPause(x *chaosContext) error {
+	nodes := x.c.K8sNodes().EligibleForActions()
+	if len(nodes) == 0 {
+		return errors.New("kubelet-pause requires a node eligible for actions")
+	}
+	target := nodes[len(nodes)-1]
+
+	return runChaos(
+		func() error {
+			return target.Command("systemctl", "kill", "--signal=SIGSTOP", "kubelet").RunWithEcho()
+		},
+		func() error {
+			return waitChaosFault(x.c, target, x.wait,
+				nodeIsUnreachable,
+				apiServerIsReachable,
+			)
+		},
+		func() error {
+			return target.Command("systemctl", "kill", "--signal=SIGCONT", "kubelet").RunWithEcho()
+		},
+		func() error {
+			return waitNewWorkerNodeReady(x.c, target, x.wait)
+		},
+	)
+}
+
+// chaosEtcdLeaderKill kills the etcd member acting as a leader and verifies that a new leader is elected and
+// that the API server is still reachable via the control plane endpoint; then the kubelet is expected to restart
+// the etcd static pod and the member to become healthy again
+func chaosEtcdLeaderKill(x *chaosContext) error {
+	if err := chaosCheckHA(x.c, "etcd-leader-kill"); err != nil {
+		return err
+	}
+	if x.c.ExternalEtcd() != nil {
+		return errors.New("etcd-leader-kill supports only stacked etcd")
+	}
+
+	target, leader, err := etcdLeader(x.c)
+	if err != nil {
+		return err
+	}
+	observer := chaosObserver(x.c, target)
+
+	return runChaos(
+		func() error {
+			target.Infof("killing the etcd leader")
+			nodeCRI, err := target.CRI()
+			if err != nil {
+				return err
+			}
+			actionHelper, err := cri.NewActionHelper(nodeCRI)
+			if err != nil {
+				return err
+			}
+			containers, err := actionHelper.GetRunningContainers(target, "etcd")
+			if err != nil {
+				return err
+			}
+			if len(containers) == 0 && !target.IsDryRun() {
+				return errors.Errorf("etcd is not running on node %s", target.Name())
+			}
+			return actionHelper.KillContainers(target, containers...)
+		},
+		func() error {
+			return waitChaosFault(x.c, target, x.wait,
+				etcdLeaderChanged(observer, leader),
+				etcdQuorumIsKept(observer),
+				apiServerIsReachable,
+			)
+		},
+		func() error {
+			// NB. the kubelet restarts the etcd static pod
+			return nil
+		},
+		func() error {
+			target.Infof("waiting for etcd to be restarted (timeout %s)", x.wait)
+			if pass := waitFor(x.c, target, x.wait,
+				staticPodIsReady("etcd"),
+				etcdMemberIsHealthy,
+			); !pass {
+				return errors.New("timeout: etcd member did not reach target state")
+			}
+			fmt.Println()
+			return nil
+		},
+	)
+}
+
+// chaosNetworkPartition drops all the traffic between a node and the other nodes in the cluster, and verifies that
+// the node becomes NotReady while the API server is still reachable via the control plane endpoint and, in case of
+// control plane nodes, that etcd keeps quorum; then the partition is healed and the node is expected to become Ready again
+func chaosNetworkPartition(x *chaosContext) error {
+	nodes := x.c.K8sNodes().EligibleForActions()
+	if len(nodes) == 0 {
+		return errors.New("network-partition requires a node eligible for actions")
+	}
+	target := nodes[len(nodes)-1]
+	if target.IsControlPlane() {
+		if err := chaosCheckHA(x.c, "network-partition"); err != nil {
+			return err
+		}
+	}
+	observer := chaosObserver(x.c, target)
+	if observer == nil {
+		return errors.Errorf("network-partition requires a control plane node other than %s", target.Name())
+	}
+
+	// NB. rules are added to a dedicated chain, so the partition can be healed by removing the chain
+	rules := map[string][][]string{}
+	for _, n := range x.c.AllNodes() {
+		if n.Name() == target.Name() {
+			continue
+		}
+		ipv4, ipv6, err := n.IP()
+		if err != nil {
+			return errors.Wrapf(err, "failed to get IP for node %s", n.Name())
+		}
+		if ipv4 != "" {
+			rules["iptables"] = append(rules["iptables"],
+				[]string{"iptables", "-A", chaosChain, "-s", ipv4, "-j", "DROP"},
+				[]string{"iptables", "-A", chaosChain, "-d", ipv4, "-j", "DROP"},
+			)
+		}
+		if ipv6 != "" {
+			rules["ip6tables"] = append(rules["ip6tables"],
+				[]string{"ip6tables", "-A", chaosChain, "-s", ipv6, "-j", "DROP"},
+				[]string{"ip6tables", "-A", chaosChain, "-d", ipv6, "-j", "DROP"},
+			)
+		}
+	}
+	iptables := []string{}
+	inject := [][]string{}
+	for _, t := range []string{"iptables", "ip6tables"} {
+		if len(rules[t]) == 0 {
+			continue
+		}
+		iptables = append(iptables, t)
+		inject = append(inject,
+			[]string{t, "-N", chaosChain},
+			[]string{t, "-I", "INPUT", "-j", chaosChain},
+			[]string{t, "-I", "OUTPUT", "-j", chaosChain},
+		)
+		inject = append(inject, rules[t]...)
+	}
+
+	conditions := []try{
+		nodeIsUnreachable,
+		apiServerIsReachable,
+	}
+	if target.IsControlPlane() {
+		conditions = append(conditions, etcdQuorumIsKept(observer))
+		if loadBalancerHasStats(x.c) {
+			conditions = append(conditions, loadBalancerBackendIsDown)
+		}
+	}
+
+	return runChaos(
+		func() error {
+			target.Infof("partitioning the node from the other nodes")
+			return runCommands(target, inject)
+		},
+		func() error {
+			return waitChaosFault(x.c, target, x.wait, conditions...)
+		},
+		func() error {
+			target.Infof("healing the network partition")
+			// NB. all the commands are executed, so a partially injected fault is restored as well
+			var errs []string
+			for _, t := range iptables {
+				for _, cmd := range [][]string{
+					{"-D", "INPUT", "-j", chaosChain},
+					{"-D", "OUTPUT", "-j", chaosChain},
+					{"-F", chaosChain},
+					{"-X", chaosChain},
+				} {
+					if err := target.Command(t, cmd...).RunWithEcho(); err != nil {
+						errs = append(errs, fmt.Sprintf("%s %s: %v", t, strings.Join(cmd, " "), err))
+					}
+				}
+			}
+			if len(errs) > 0 {
+				return errors.Errorf("failed to remove the network partition rules: %s", strings.Join(errs, "; "))
+			}
+			return nil
+		},
+		func() error {
+			if target.IsControlPlane() {
+				return waitNewControlPlaneNodeReady(x.c, target, x.wait)
+			}
+			return waitNewWorkerNodeReady(x.c, target, x.wait)
+		},
+	)
+}
+
+// chaosEtcdLatency injects latency on the etcd peer port of a control plane node, and verifies that etcd keeps quorum,
+// that the etcd member is still healthy and that the API server is reachable via the control plane endpoint;
+// then the latency is removed and the etcd member is expected to be healthy
+func chaosEtcdLatency(x *chaosContext) error {
+	if x.c.ExternalEtcd() != nil {
+		return errors.New("etcd-latency supports only stacked etcd")
+	}
+	if x.latency <= 0 {
+		return errors.New("etcd-latency requires a positive latency")
+	}
+	cps := x.c.ControlPlanes().EligibleForActions()
+	if len(cps) == 0 {
+		return errors.New("etcd-latency requires a control plane node eligible for actions")
+	}
+	target := cps[len(cps)-1]
+	observer := chaosObserver(x.c, target)
+	if observer == nil {
+		observer = target
+	}
+
+	// NB. the netem qdisc is attached to a fourth band of the prio qdisc, that is not used by the default priomap,
+	// so only the traffic matching the filters on the etcd peer port is delayed
+	protocol, match := "ip", "ip"
+	if x.c.Settings.IPFamily == status.IPv6Family {
+		protocol, match = "ipv6", "ip6"
+	}
+	inject := [][]string{
+		{"tc", "qdisc", "add", "dev", chaosInterface, "root", "handle", "1:", "prio", "bands", "4"},
+		{"tc", "qdisc", "add", "dev", chaosInterface, "parent", "1:4", "handle", "40:", "netem", "delay", fmt.Sprintf("%dms", x.latency.Milliseconds())},
+		{"tc", "filter", "add", "dev", chaosInterface, "protocol", protocol, "parent", "1:", "prio", "1", "u32", "match", match, "dport", fmt.Sprintf("%d", etcdPeerPort), "0xffff", "flowid", "1:4"},
+		{"tc", "filter", "add", "dev", chaosInterface, "protocol", protocol, "parent", "1:", "prio", "1", "u32", "match", match, "sport", fmt.Sprintf("%d", etcdPeerPort), "0xffff", "flowid", "1:4"},
+	}
+
+	return runChaos(
+		func() error {
+			target.Infof("injecting %s latency on the etcd peer port", x.latency)
+			return runCommands(target, inject)
+		},
+		func() error {
+			return waitChaosFault(x.c, target, x.wait,
+				etcdQuorumIsKept(observer),
+				etcdMemberIsHealthy,
+				apiServerIsReachable,
+			)
+		},
+		func() error {
+			target.Infof("removing latency on the etcd peer port")
+			return target.Command("tc", "qdisc", "del", "dev", chaosInterface, "root").RunWithEcho()
+		},
+		func() error {
+			target.Infof("waiting for etcd member to be healthy (timeout %s)", x.wait)
+			if pass := waitFor(x.c, target, x.wait,
+				etcdMemberIsHealthy,
+			); !pass {
+				return errors.New("timeout: etcd member did not reach target state")
+			}
+			fmt.Println()
+			return nil
+		},
+	)
+}
+
+// runChaos injects a fault and verifies the cluster behaviour while the fault is active, then restores the
+// original state and verifies that the cluster recovers. The original state is restored and the recovery
+// is verified even if the cluster did not behave as expected while the fault was active.
+func runChaos(inject, verifyFault, restore, verifyRecovery func() error) error {
+	if err := inject(); err != nil {
+		// NB. the fault might be partially injected, so the original state is restored anyway
+		_ = restore()
+		return errors.Wrap(err, "failed to inject the fault")
+	}
+
+	faultErr := verifyFault()
+
+	if err := restore(); err != nil {
+		return errors.Wrap(err, "failed to restore the original state")
+	}
+
+	if err := verifyRecovery(); err != nil {
+		if faultErr != nil {
+			return faultErr
+		}
+		return errors.Wrap(err, "cluster did not recover after restoring the original state")
+	}
+	return faultErr
+}
+
+// chaosCheckHA checks that the cluster can tolerate the loss of one control plane node, that is there are at least
+// two control plane nodes, and at least three with stacked etcd for keeping etcd quorum
+func chaosCheckHA(c *status.Cluster, scenario string) error {
+	cps := len(c.ControlPlanes())
+	if c.ExternalEtcd() == nil && cps < 3 {
+		return errors.Errorf("%s requires at least 3 control plane nodes for keeping etcd quorum", scenario)
+	}
+	if cps < 2 {
+		return errors.Errorf("%s requires at least 2 control plane nodes", scenario)
+	}
+	return nil
+}
+
+// chaosControlPlaneTarget returns the last control plane node eligible for actions and a control plane node
+// to be used for observing the cluster while the target is down
+func chaosControlPlaneTarget(c *status.Cluster) (target, observer *status.Node, err error) {
+	if err := chaosCheckHA(c, "control-plane-down"); err != nil {
+		return nil, nil, err
+	}
+	cps := c.ControlPlanes().EligibleForActions()
+	if len(cps) == 0 {
+		return nil, nil, errors.New("control-plane-down requires a control plane node eligible for actions")
+	}
+	target = cps[len(cps)-1]
+	return target, chaosObserver(c, target), nil
+}
+
+// chaosObserver returns the first control plane node other than the target, or nil if there are no such nodes
+func chaosObserver(c *status.Cluster, target *status.Node) *status.Node {
+	for _, cp := range c.ControlPlanes() {
+		if cp.Name() != target.Name() {
+			return cp
+		}
+	}
+	return nil
+}
+
+// etcdLeader returns the control plane node hosting the etcd leader and the leader member ID;
+// in dry run the last control plane node is returned
+func etcdLeader(c *status.Cluster) (*status.Node, uint64, error) {
+	cps := c.ControlPlanes()
+	cp1 := c.BootstrapControlPlane()
+	if cp1.IsDryRun() {
+		return cps[len(cps)-1], 0, nil
+	}
+
+	e, err := newEtcdctl(c, cp1)
+	if err != nil {
+		return nil, 0, err
+	}
+	members, err := e.members()
+	if err != nil {
+		return nil, 0, err
+	}
+	statuses, err := e.endpointsStatus(etcdClientURLs(members, false))
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(statuses) == 0 || statuses[0].Status.Leader == 0 {
+		return nil, 0, errors.New("failed to get the etcd leader")
+	}
+
+	leader := statuses[0].Status.Leader
+	for _, m := range members {
+		if m.ID != leader {
+			continue
+		}
+		for _, cp := range cps {
+			if cp.Name() == m.Name {
+				return cp, leader, nil
+			}
+		}
+	}
+	return nil, 0, errors.Errorf("failed to get the control plane node hosting the etcd leader %x", leader)
+}
+
+// runCommands runs a list of commands on a node, stopping at the first error
+func runCommands(n *status.Node, commands [][]string) error {
+	for _, cmd := range commands {
+		if err := n.Command(cmd[0], cmd[1:]...).RunWithEcho(); err != nil {
+			return errors.Wrapf(err, "failed to run %s on node %s", strings.Join(cmd, " "), n.Name())
+		}
+	}
+	return nil
+}
+
+// hostDocker runs a docker command on the host, e.g. for stopping/starting node containers;
+// in dry run the command is only printed
+func hostDocker(n *status.Node, args ...string) error {
+	prompt := colors.Prompt("host:$ ")
+	command := colors.Command(fmt.Sprintf("docker %s", strings.Join(args, " ")))
+	fmt.Printf("\n%s%s\n", prompt, command)
+
+	if n.IsDryRun() {
+		return nil
+	}
+	if err := exec.NewHostCmd("docker", args...).RunWithEcho(); err != nil {
+		return errors.Wrapf(err, "failed to run docker %s", strings.Join(args, " "))
+	}
+	return nil
+}
@@ -18,9 +18,10 @@ package actions
 
 import (
 	"fmt"
-	"github.com/pkg/errors"
 	"strings"
 
+	"github.com/pkg/errors"
+
 	versionutils "k8s.io/apimachinery/pkg/util/version"
 	"k8s.io/kubeadm/kinder/pkg/cluster/status"
 )
@@ -118,11 +119,17 @@ func appendEtcdctlCertArgs(etcdVersion string, etcdArgs *[]string) error {
 		return errors.Wrap(err, "cannot parse etcd version")
 	}
 
-	// Before 3.4.0, etcdctl was using --ca-file, --cert-file, --key-file flags; in newer etcdctl releases those flags are renamed
-	if version.AtLeast(versionutils.MustParseGeneric("v3.4.0")) {
-		*etcdArgs = append(*etcdArgs, etcdCertArgsNew...)
-	} else {
-		*etcdArgs = append(*etcdArgs, etcdCertArgsOld...)
-	}
+	*etcdArgs = append(*etcdArgs, etcdctlCertArgs(version, false)...)
 	return nil
 }
+
+// etcdctlCertArgs returns the etcdctl certificate arguments for the given etcd version; if apiV3 is true,
+// the arguments are returned for an etcdctl with the v3 API explicitly enabled.
+func etcdctlCertArgs(version *versionutils.Version, apiV3 bool) []string {
+	// Before 3.4.0, etcdctl was using the v2 API by default, with --ca-file, --cert-file, --key-file flags;
+	// in newer etcdctl releases the v3 API is the default, and those flags are renamed
+	if apiV3 || version.AtLeast(versionutils.MustParseGeneric("v3.4.0")) {
+		return etcdCertArgsNew
+	}
+	return etcdCertArgsOld
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"path/filepath"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/constants"
+	"k8s.io/kubeadm/kinder/pkg/loadbalancer"
+)
+
+// ControlPlaneVIP action writes the keepalived config file and static pod manifest for managing the
+// control plane VIP on the given control plane nodes.
+// Please note that this action is automatically executed during kubeadm init/join, before kubeadm is
+// executed on a node, but it is possible to invoke it separately as well.
+func ControlPlaneVIP(c *status.Cluster, nodes ...*status.Node) error {
+	// if the cluster is not using a control plane VIP we're done
+	if c.Settings.ControlPlaneVIP == "" {
+		return nil
+	}
+
+	// collect info about all the control plane nodes
+	addresses := map[string]string{}
+	for _, cp := range c.ControlPlanes() {
+		ipv4, _, err := cp.IP()
+		if err != nil {
+			return errors.Wrapf(err, "failed to get IP for node %s", cp.Name())
+		}
+		addresses[cp.Name()] = ipv4
+	}
+
+	for _, n := range nodes {
+		n.Infof("Preparing keepalived static pod for control plane VIP %s", c.Settings.ControlPlaneVIP)
+
+		// VRRP advertisements are sent to all the other control plane nodes
+		var peers []string
+		for _, cp := range c.ControlPlanes() {
+			if cp.Name() != n.Name() {
+				peers = append(peers, addresses[cp.Name()])
+			}
+		}
+
+		data := &loadbalancer.VIPConfigData{
+			NodeName:      n.Name(),
+			Bootstrap:     n.Name() == c.BootstrapControlPlane().Name(),
+			VIP:           c.Settings.ControlPlaneVIP,
+			Interface:     "eth0",
+			NodeAddress:   addresses[n.Name()],
+			Peers:         peers,
+			APIServerPort: constants.APIServerPort,
+			Image:         constants.KeepalivedImage,
+			ConfigPath:    constants.KeepalivedConfigPath,
+		}
+
+		config, err := loadbalancer.KeepalivedConfig(data)
+		if err != nil {
+			return errors.Wrap(err, "failed to generate keepalived config")
+		}
+
+		manifest, err := loadbalancer.KeepalivedManifest(data)
+		if err != nil {
+			return errors.Wrap(err, "failed to generate keepalived static pod manifest")
+		}
+
+		if err := n.Command(
+			"mkdir", "-p", filepath.Dir(constants.KeepalivedConfigPath), filepath.Dir(constants.KeepalivedManifestPath),
+		).Silent().Run(); err != nil {
+			return errors.Wrapf(err, "failed to create folders for keepalived on node %s", n.Name())
+		}
+
+		if err := n.WriteFile(constants.KeepalivedConfigPath, []byte(config)); err != nil {
+			return errors.Wrap(err, "failed to copy keepalived config to node")
+		}
+
+		if err := n.WriteFile(constants.KeepalivedManifestPath, []byte(manifest)); err != nil {
+			return errors.Wrap(err, "failed to copy keepalived static pod manifest to node")
+		}
+	}
+
+	return nil
+}
+
+// ignoreManifestsDirPreflightError adds the preflight error for a not empty manifests folder to the list of
+// preflight errors to be ignored when using a control plane VIP, because the keepalived static pod manifest
+// is written before running kubeadm
+func ignoreManifestsDirPreflightError(c *status.Cluster, ignorePreflightErrors string) string {
+	if c.Settings.ControlPlaneVIP == "" {
+		return ignorePreflightErrors
+	}
+	if ignorePreflightErrors == "" {
+		return "DirAvailable--etc-kubernetes-manifests"
+	}
+	return ignorePreflightErrors + ",DirAvailable--etc-kubernetes-manifests"
+}
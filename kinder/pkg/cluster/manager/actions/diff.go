@@ -0,0 +1,209 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/pmezard/go-difflib/difflib"
+	log "github.com/sirupsen/logrus"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+)
+
+// diffNodeFiles defines the files tracked on each node by the diff mode, in addition to static pod manifests
+var diffNodeFiles = []string{
+	"/var/lib/kubelet/config.yaml",
+	"/var/lib/kubelet/kubeadm-flags.env",
+}
+
+// snapshot defines the content of the files or ConfigMap keys tracked by the diff mode, indexed by path
+type snapshot map[string]string
+
+// clusterSnapshot defines the snapshot of all the nodes and of the kube-system ConfigMaps
+type clusterSnapshot struct {
+	nodes      map[string]snapshot
+	configMaps snapshot
+}
+
+// runWithDiff runs an action, taking a snapshot of static pod manifests, kubelet config files and kube-system ConfigMaps
+// before and after the action, and writes a unified diff for each node and for ConfigMaps to the ARTIFACTS folder, if defined,
+// or to the current folder; diffs are written also if the action fails.
+// In dry-run mode snapshots and diffs are skipped, because commands on nodes are only echoed
+func runWithDiff(c *status.Cluster, action string, flags *RunOptions, run func(*status.Cluster, *RunOptions) error) error {
+	if c.BootstrapControlPlane().IsDryRun() {
+		return run(c, flags)
+	}
+
+	before := takeClusterSnapshot(c)
+	err := run(c, flags)
+	after := takeClusterSnapshot(c)
+
+	dir := filepath.Join(os.Getenv("ARTIFACTS"), fmt.Sprintf("%s-%s-diff", c.Name(), action))
+	if werr := writeClusterDiff(c, dir, before, after); werr != nil {
+		if err != nil {
+			log.Warnf("failed to write diffs: %v", werr)
+			return err
+		}
+		return werr
+	}
+	return err
+}
+
+// takeClusterSnapshot takes a snapshot of all the Kubernetes nodes and of the kube-system ConfigMaps;
+// missing files, e.g. before kubeadm init, and unreachable API servers result in empty snapshots
+func takeClusterSnapshot(c *status.Cluster) *clusterSnapshot {
+	s := &clusterSnapshot{
+		nodes:      map[string]snapshot{},
+		configMaps: snapshot{},
+	}
+	for _, n := range c.K8sNodes() {
+		s.nodes[n.Name()] = takeNodeSnapshot(n)
+	}
+
+	client, err := kubeClient(c)
+	if err != nil {
+		log.Debugf("skipping ConfigMaps snapshot: %v", err)
+		return s
+	}
+	configMaps, err := client.CoreV1().ConfigMaps(metav1.NamespaceSystem).List(metav1.ListOptions{})
+	if err != nil {
+		log.Debugf("skipping ConfigMaps snapshot: %v", err)
+		return s
+	}
+	for _, cm := range configMaps.Items {
+		for k, v := range cm.Data {
+			s.configMaps[fmt.Sprintf("%s/%s/%s", metav1.NamespaceSystem, cm.Name, k)] = v
+		}
+	}
+	return s
+}
+
+// takeNodeSnapshot takes a snapshot of static pod manifests and kubelet config files on a node
+func takeNodeSnapshot(n *status.Node) snapshot {
+	s := snapshot{}
+
+	files := []string{}
+	if manifests, err := n.Command("ls", manifestsDir).Silent().RunAndCapture(); err == nil {
+		for _, m := range manifests {
+			files = append(files, filepath.Join(manifestsDir, m))
+		}
+	}
+	files = append(files, diffNodeFiles...)
+
+	for _, f := range files {
+		lines, err := n.Command("cat", f).Silent().RunAndCapture()
+		if err != nil {
+			continue
+		}
+		s[f] = strings.Join(lines, "\n") + "\n"
+	}
+	return s
+}
+
+// writeClusterDiff writes a diff file for each node and for ConfigMaps into the given folder
+func writeClusterDiff(c *status.Cluster, dir string, before, after *clusterSnapshot) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return errors.Wrapf(err, "failed to create the %s folder", dir)
+	}
+
+	for _, n := range c.K8sNodes() {
+		diff, changed, err := diffSnapshots(before.nodes[n.Name()], after.nodes[n.Name()])
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(dir, fmt.Sprintf("%s.diff", n.Name()))
+		if err := ioutil.WriteFile(path, []byte(diff), 0644); err != nil {
+			return errors.Wrapf(err, "failed to write %s", path)
+		}
+		n.Infof("%d files changed, see %s", changed, path)
+	}
+
+	diff, changed, err := diffSnapshots(before.configMaps, after.configMaps)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dir, "configmaps.diff")
+	if err := ioutil.WriteFile(path, []byte(diff), 0644); err != nil {
+		return errors.Wrapf(err, "failed to write %s", path)
+	}
+	fmt.Printf("%d ConfigMap keys changed, see %s\n", changed, path)
+	return nil
+}
+
+// diffSnapshots returns a unified diff between two snapshots and the number of changed items
+func diffSnapshots(before, after snapshot) (string, int, error) {
+	paths := []string{}
+	for p := range before {
+		paths = append(paths, p)
+	}
+	for p := range after {
+		if _, ok := before[p]; !ok {
+			paths = append(paths, p)
+		}
+	}
+	sort.Strings(paths)
+
+	var b strings.Builder
+	changed := 0
+	for _, p := range paths {
+		a, inBefore := before[p]
+		z, inAfter := after[p]
+		if inBefore && inAfter && a == z {
+			continue
+		}
+
+		diff := difflib.UnifiedDiff{
+			A:        splitLines(a),
+			B:        splitLines(z),
+			FromFile: "a" + p,
+			ToFile:   "b" + p,
+			Context:  3,
+		}
+		if !inBefore {
+			diff.A = nil
+			diff.FromFile = "/dev/null"
+		}
+		if !inAfter {
+			diff.B = nil
+			diff.ToFile = "/dev/null"
+		}
+		text, err := difflib.GetUnifiedDiffString(diff)
+		if err != nil {
+			return "", 0, errors.Wrapf(err, "failed to diff %s", p)
+		}
+		b.WriteString(text)
+		changed++
+	}
+	return b.String(), changed, nil
+}
+
+// splitLines splits a text into lines, preserving line terminators as expected by difflib
+func splitLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"testing"
+)
+
+func TestDiffSnapshots(t *testing.T) {
+	tests := []struct {
+		name            string
+		before          snapshot
+		after           snapshot
+		expectedChanged int
+		expectedDiff    string
+	}{
+		{
+			name:            "no changes",
+			before:          snapshot{"/a.yaml": "a: 1\n"},
+			after:           snapshot{"/a.yaml": "a: 1\n"},
+			expectedChanged: 0,
+			expectedDiff:    "",
+		},
+		{
+			name:            "changed file",
+			before:          snapshot{"/a.yaml": "a: 1\nb: 2\n"},
+			after:           snapshot{"/a.yaml": "a: 1\nb: 3\n"},
+			expectedChanged: 1,
+			expectedDiff:    "--- a/a.yaml\n+++ b/a.yaml\n@@ -1,2 +1,2 @@\n a: 1\n-b: 2\n+b: 3\n",
+		},
+		{
+			name:            "added and removed files",
+			before:          snapshot{"/a.yaml": "a: 1\n"},
+			after:           snapshot{"/b.yaml": "b: 1\n"},
+			expectedChanged: 2,
+			expectedDiff: "--- a/a.yaml\n+++ /dev/null\n@@ -1 +0,0 @@\n-a: 1\n" +
+				"--- /dev/null\n+++ b/b.yaml\n@@ -0,0 +1 @@\n+b: 1\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			diff, changed, err := diffSnapshots(test.before, test.after)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if changed != test.expectedChanged {
+				t.Errorf("expected %d changes, got %d", test.expectedChanged, changed)
+			}
+			if diff != test.expectedDiff {
+				t.Errorf("expected diff\n%q\ngot\n%q", test.expectedDiff, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,341 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+)
+
+// etcdRaftIndexDivergenceWarning defines the raft index divergence between etcd members above which
+// a warning is reported; members of a healthy cluster should converge to the same raft index quickly
+const etcdRaftIndexDivergenceWarning = 100
+
+// etcdMember defines the subset of the information returned by etcdctl member list -w json used by kinder
+type etcdMember struct {
+	ID         uint64   `json:"ID"`
+	Name       string   `json:"name"`
+	PeerURLs   []string `json:"peerURLs"`
+	ClientURLs []string `json:"clientURLs"`
+	IsLearner  bool     `json:"isLearner"`
+}
+
+// etcdMemberList defines the output of etcdctl member list -w json
+type etcdMemberList struct {
+	Members []etcdMember `json:"members"`
+}
+
+// etcdResponseHeader defines the subset of the etcd response header used by kinder
+type etcdResponseHeader struct {
+	MemberID uint64 `json:"member_id"`
+}
+
+// etcdStatus defines the subset of the information returned by etcdctl endpoint status -w json used by kinder
+type etcdStatus struct {
+	Header    etcdResponseHeader `json:"header"`
+	Version   string             `json:"version"`
+	DBSize    int64              `json:"dbSize"`
+	Leader    uint64             `json:"leader"`
+	RaftIndex uint64             `json:"raftIndex"`
+	RaftTerm  uint64             `json:"raftTerm"`
+	IsLearner bool               `json:"isLearner"`
+}
+
+// etcdEndpointStatus defines the status of an etcd endpoint as returned by etcdctl endpoint status -w json
+type etcdEndpointStatus struct {
+	Endpoint string     `json:"Endpoint"`
+	Status   etcdStatus `json:"Status"`
+}
+
+// EtcdHealth checks the health of the stacked etcd cluster: it verifies that etcd membership matches
+// the list of control-plane nodes, that there are no learner members left, that all the members are healthy
+// and agree on the same leader, and reports DB size and raft index for each member.
+// Optionally, all the members are defragmented before checking the status.
+func EtcdHealth(c *status.Cluster, defrag bool) error {
+	if c.ExternalEtcd() != nil {
+		return errors.New("etcd-health supports only stacked etcd")
+	}
+
+	cps := c.ControlPlanes().EligibleForActions()
+	if len(cps) == 0 {
+		return errors.New("etcd-health requires a control-plane node")
+	}
+	n := cps[0]
+
+	// NB. in dry run etcdctl commands are printed instead of being executed, so all the checks are skipped
+	e, err := newEtcdctl(c, n)
+	if err != nil {
+		return err
+	}
+
+	n.Infof("checking etcd membership")
+	members, err := e.members()
+	if err != nil {
+		return err
+	}
+	controlPlanes := []string{}
+	for _, cp := range c.ControlPlanes() {
+		controlPlanes = append(controlPlanes, cp.Name())
+	}
+	problems := checkEtcdMembership(members, controlPlanes)
+
+	// NB. learners do not serve linearizable reads, so endpoint health is checked on voting members only;
+	// learners are already reported as problem by the membership check
+	voters := etcdClientURLs(members, false)
+	all := etcdClientURLs(members, true)
+
+	n.Infof("checking etcd endpoint health")
+	healthy := e.endpointHealth(voters)
+	for _, u := range voters {
+		if !healthy[u] {
+			problems = append(problems, fmt.Sprintf("etcd endpoint %s is not healthy", u))
+		}
+	}
+
+	if defrag {
+		n.Infof("defragmenting etcd members")
+		if err := e.run(all, "defrag"); err != nil {
+			return errors.Wrap(err, "failed to defragment etcd members")
+		}
+	}
+
+	n.Infof("checking etcd endpoint status")
+	statuses, err := e.endpointsStatus(all)
+	if err != nil {
+		return err
+	}
+	if n.IsDryRun() {
+		return nil
+	}
+	printEtcdEndpointStatus(members, statuses)
+	problems = append(problems, checkEtcdEndpointStatus(all, statuses)...)
+
+	if divergence := etcdRaftIndexDivergence(statuses); divergence > etcdRaftIndexDivergenceWarning {
+		log.Warnf("etcd members raft index diverge by %d", divergence)
+	} else {
+		fmt.Printf("etcd members raft index diverge by %d\n", divergence)
+	}
+
+	if len(problems) > 0 {
+		fmt.Println()
+		for _, p := range problems {
+			fmt.Printf("ERROR: %s\n", p)
+		}
+		return errors.Errorf("etcd-health found %d problems", len(problems))
+	}
+
+	fmt.Println("\netcd cluster is healthy")
+	return nil
+}
+
+// members returns the list of etcd members as seen by the etcd static pod; in dry run the list is empty
+func (e *etcdctl) members() ([]etcdMember, error) {
+	lines, err := e.capture(nil, "member", "list", "-w", "json")
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list etcd members on node %s", e.n.Name())
+	}
+	if e.n.IsDryRun() {
+		return nil, nil
+	}
+	return parseEtcdMemberList(lines)
+}
+
+// endpointHealth returns the healthy endpoints among the given etcd endpoints
+func (e *etcdctl) endpointHealth(endpoints []string) map[string]bool {
+	// NB. etcdctl exits with an error if any endpoint is unhealthy, so the error is ignored
+	// and unhealthy endpoints are detected by parsing the output
+	lines, _ := e.capture(endpoints, "endpoint", "health")
+	return parseEtcdEndpointHealth(lines)
+}
+
+// endpointsStatus returns the status of the given etcd endpoints; in dry run the list is empty
+func (e *etcdctl) endpointsStatus(endpoints []string) ([]etcdEndpointStatus, error) {
+	// NB. etcdctl exits with an error if any endpoint does not respond, so the error is ignored
+	// and missing endpoints are detected when checking the status
+	lines, _ := e.capture(endpoints, "endpoint", "status", "-w", "json")
+	if e.n.IsDryRun() {
+		return nil, nil
+	}
+	return parseEtcdEndpointStatus(lines)
+}
+
+// printEtcdEndpointStatus prints a table with the status of each etcd endpoint
+func printEtcdEndpointStatus(members []etcdMember, statuses []etcdEndpointStatus) {
+	names := map[uint64]string{}
+	for _, m := range members {
+		names[m.ID] = m.Name
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "ENDPOINT\tMEMBER\tVERSION\tDB SIZE\tLEADER\tLEARNER\tRAFT TERM\tRAFT INDEX")
+	for _, s := range statuses {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%t\t%t\t%d\t%d\n",
+			s.Endpoint,
+			names[s.Status.Header.MemberID],
+			s.Status.Version,
+			s.Status.DBSize,
+			s.Status.Leader == s.Status.Header.MemberID,
+			s.Status.IsLearner,
+			s.Status.RaftTerm,
+			s.Status.RaftIndex,
+		)
+	}
+	w.Flush()
+}
+
+// etcdClientURLs returns the client URLs of the given etcd members, optionally including learners
+func etcdClientURLs(members []etcdMember, includeLearners bool) []string {
+	urls := []string{}
+	for _, m := range members {
+		if m.IsLearner && !includeLearners {
+			continue
+		}
+		urls = append(urls, m.ClientURLs...)
+	}
+	return urls
+}
+
+// checkEtcdMembership checks that etcd members match the given list of control-plane nodes and that
+// all the members are started voting members; a list of problems is returned
+func checkEtcdMembership(members []etcdMember, controlPlanes []string) []string {
+	problems := []string{}
+	names := map[string]bool{}
+	for _, m := range members {
+		// NB. members added but not yet started do not have a name
+		if m.Name == "" {
+			problems = append(problems, fmt.Sprintf("etcd member %x is not started", m.ID))
+			continue
+		}
+		if m.IsLearner {
+			problems = append(problems, fmt.Sprintf("etcd member %s is a learner", m.Name))
+		}
+		names[m.Name] = true
+	}
+
+	for _, cp := range controlPlanes {
+		if !names[cp] {
+			problems = append(problems, fmt.Sprintf("control-plane node %s is not an etcd member", cp))
+		}
+		delete(names, cp)
+	}
+
+	unknown := []string{}
+	for name := range names {
+		unknown = append(unknown, name)
+	}
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		problems = append(problems, fmt.Sprintf("etcd member %s does not match any control-plane node", name))
+	}
+	return problems
+}
+
+// checkEtcdEndpointStatus checks that all the given endpoints reported a status and that all the members
+// agree on the same leader; a list of problems is returned
+func checkEtcdEndpointStatus(endpoints []string, statuses []etcdEndpointStatus) []string {
+	problems := []string{}
+	reported := map[string]bool{}
+	leaders := map[uint64]bool{}
+	for _, s := range statuses {
+		reported[s.Endpoint] = true
+		leaders[s.Status.Leader] = true
+	}
+
+	for _, e := range endpoints {
+		if !reported[e] {
+			problems = append(problems, fmt.Sprintf("etcd endpoint %s did not report status", e))
+		}
+	}
+
+	switch {
+	case len(statuses) == 0:
+	case leaders[0]:
+		problems = append(problems, "one or more etcd members do not have a leader")
+	case len(leaders) > 1:
+		problems = append(problems, fmt.Sprintf("etcd members do not agree on the leader, %d different leaders reported", len(leaders)))
+	}
+	return problems
+}
+
+// etcdRaftIndexDivergence returns the difference between the highest and the lowest raft index of the given endpoints
+func etcdRaftIndexDivergence(statuses []etcdEndpointStatus) uint64 {
+	if len(statuses) == 0 {
+		return 0
+	}
+	min, max := statuses[0].Status.RaftIndex, statuses[0].Status.RaftIndex
+	for _, s := range statuses[1:] {
+		if s.Status.RaftIndex < min {
+			min = s.Status.RaftIndex
+		}
+		if s.Status.RaftIndex > max {
+			max = s.Status.RaftIndex
+		}
+	}
+	return max - min
+}
+
+// parseEtcdMemberList takes the output lines of 'etcdctl member list -w json' and returns the etcd members
+func parseEtcdMemberList(lines []string) ([]etcdMember, error) {
+	list := etcdMemberList{}
+	if err := json.Unmarshal([]byte(etcdJSONOutput(lines)), &list); err != nil {
+		return nil, errors.Wrap(err, "failed to parse the output of 'etcdctl member list'")
+	}
+	return list.Members, nil
+}
+
+// parseEtcdEndpointStatus takes the output lines of 'etcdctl endpoint status -w json' and returns the endpoints status
+func parseEtcdEndpointStatus(lines []string) ([]etcdEndpointStatus, error) {
+	statuses := []etcdEndpointStatus{}
+	if err := json.Unmarshal([]byte(etcdJSONOutput(lines)), &statuses); err != nil {
+		return nil, errors.Wrap(err, "failed to parse the output of 'etcdctl endpoint status'")
+	}
+	return statuses, nil
+}
+
+// parseEtcdEndpointHealth takes the output lines of 'etcdctl endpoint health' and returns the healthy endpoints, e.g.
+// https://172.17.0.2:2379 is healthy: successfully committed proposal: took = 10.392521ms
+func parseEtcdEndpointHealth(lines []string) map[string]bool {
+	healthy := map[string]bool{}
+	for _, l := range lines {
+		fields := strings.Fields(l)
+		if len(fields) >= 3 && fields[1] == "is" && fields[2] == "healthy:" {
+			healthy[fields[0]] = true
+		}
+	}
+	return healthy
+}
+
+// etcdJSONOutput returns the first line of the etcdctl output that looks like JSON, thus ignoring
+// warnings or other messages mixed with the command output
+func etcdJSONOutput(lines []string) string {
+	for _, l := range lines {
+		l = strings.TrimSpace(l)
+		if strings.HasPrefix(l, "{") || strings.HasPrefix(l, "[") {
+			return l
+		}
+	}
+	return ""
+}
@@ -0,0 +1,143 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseEtcdEndpointStatus(t *testing.T) {
+	tests := []struct {
+		name               string
+		inputLines         []string
+		expectedStatuses   []etcdEndpointStatus
+		expectedDivergence uint64
+		expectedError      bool
+	}{
+		{
+			name: "valid: etcd v3.4 output",
+			inputLines: []string{
+				`[{"Endpoint":"https://172.17.0.2:2379","Status":{"header":{"cluster_id":11,"member_id":1,"revision":5,"raft_term":2},"version":"3.4.13","dbSize":20480,"leader":1,"raftIndex":120,"raftTerm":2,"raftAppliedIndex":120,"dbSizeInUse":16384}},` +
+					`{"Endpoint":"https://172.17.0.3:2379","Status":{"header":{"cluster_id":11,"member_id":2,"revision":5,"raft_term":2},"version":"3.4.13","dbSize":24576,"leader":1,"raftIndex":115,"raftTerm":2,"raftAppliedIndex":115,"dbSizeInUse":16384,"isLearner":true}}]`,
+			},
+			expectedStatuses: []etcdEndpointStatus{
+				{Endpoint: "https://172.17.0.2:2379", Status: etcdStatus{Header: etcdResponseHeader{MemberID: 1}, Version: "3.4.13", DBSize: 20480, Leader: 1, RaftIndex: 120, RaftTerm: 2}},
+				{Endpoint: "https://172.17.0.3:2379", Status: etcdStatus{Header: etcdResponseHeader{MemberID: 2}, Version: "3.4.13", DBSize: 24576, Leader: 1, RaftIndex: 115, RaftTerm: 2, IsLearner: true}},
+			},
+			expectedDivergence: 5,
+		},
+		{
+			name: "valid: output mixed with warnings",
+			inputLines: []string{
+				"Defaulted container \"etcd\" out of: etcd",
+				`[{"Endpoint":"https://127.0.0.1:2379","Status":{"header":{"member_id":1},"version":"3.3.15","dbSize":1024,"leader":1,"raftIndex":7,"raftTerm":3}}]`,
+			},
+			expectedStatuses: []etcdEndpointStatus{
+				{Endpoint: "https://127.0.0.1:2379", Status: etcdStatus{Header: etcdResponseHeader{MemberID: 1}, Version: "3.3.15", DBSize: 1024, Leader: 1, RaftIndex: 7, RaftTerm: 3}},
+			},
+		},
+		{
+			name:          "invalid: no JSON output",
+			inputLines:    []string{"Error: context deadline exceeded"},
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			statuses, err := parseEtcdEndpointStatus(test.inputLines)
+			if (err != nil) != test.expectedError {
+				t.Fatalf("expected error %t, got %v", test.expectedError, err)
+			}
+			if test.expectedError {
+				return
+			}
+			if !reflect.DeepEqual(statuses, test.expectedStatuses) {
+				t.Errorf("expected statuses %+v, got %+v", test.expectedStatuses, statuses)
+			}
+			if divergence := etcdRaftIndexDivergence(statuses); divergence != test.expectedDivergence {
+				t.Errorf("expected raft index divergence %d, got %d", test.expectedDivergence, divergence)
+			}
+		})
+	}
+}
+
+func TestCheckEtcdMembership(t *testing.T) {
+	tests := []struct {
+		name             string
+		inputLines       []string
+		controlPlanes    []string
+		expectedProblems []string
+	}{
+		{
+			name: "membership matches control-plane nodes",
+			inputLines: []string{
+				`{"header":{"cluster_id":11,"member_id":1},"members":[{"ID":1,"name":"cp1","peerURLs":["https://172.17.0.2:2380"],"clientURLs":["https://172.17.0.2:2379"]},{"ID":2,"name":"cp2","peerURLs":["https://172.17.0.3:2380"],"clientURLs":["https://172.17.0.3:2379"]}]}`,
+			},
+			controlPlanes:    []string{"cp1", "cp2"},
+			expectedProblems: []string{},
+		},
+		{
+			name: "learner and not started members",
+			inputLines: []string{
+				`{"header":{"cluster_id":11,"member_id":1},"members":[{"ID":1,"name":"cp1","clientURLs":["https://172.17.0.2:2379"]},{"ID":2,"name":"cp2","clientURLs":["https://172.17.0.3:2379"],"isLearner":true},{"ID":171,"peerURLs":["https://172.17.0.4:2380"]}]}`,
+			},
+			controlPlanes: []string{"cp1", "cp2", "cp3"},
+			expectedProblems: []string{
+				"etcd member cp2 is a learner",
+				"etcd member ab is not started",
+				"control-plane node cp3 is not an etcd member",
+			},
+		},
+		{
+			name: "member without a control-plane node",
+			inputLines: []string{
+				`{"members":[{"ID":1,"name":"cp1"},{"ID":2,"name":"old-cp"}]}`,
+			},
+			controlPlanes: []string{"cp1"},
+			expectedProblems: []string{
+				"etcd member old-cp does not match any control-plane node",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			members, err := parseEtcdMemberList(test.inputLines)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			problems := checkEtcdMembership(members, test.controlPlanes)
+			if !reflect.DeepEqual(problems, test.expectedProblems) {
+				t.Errorf("expected problems %q, got %q", test.expectedProblems, problems)
+			}
+		})
+	}
+}
+
+func TestParseEtcdEndpointHealth(t *testing.T) {
+	lines := []string{
+		"https://172.17.0.2:2379 is healthy: successfully committed proposal: took = 10.392521ms",
+		"https://172.17.0.3:2379 is unhealthy: failed to commit proposal: context deadline exceeded",
+		"Error: unhealthy cluster",
+	}
+	expected := map[string]bool{"https://172.17.0.2:2379": true}
+	if healthy := parseEtcdEndpointHealth(lines); !reflect.DeepEqual(healthy, expected) {
+		t.Errorf("expected healthy endpoints %v, got %v", expected, healthy)
+	}
+}
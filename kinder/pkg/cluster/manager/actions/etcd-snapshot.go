@@ -0,0 +1,366 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	versionutils "k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/constants"
+)
+
+const (
+	// etcdDataDir defines the etcd data dir on control-plane nodes; this folder is mounted in the etcd static pod
+	etcdDataDir = "/var/lib/etcd"
+
+	// etcdSnapshotFile defines the path of the etcd snapshot on nodes while saving or restoring a snapshot;
+	// for stacked etcd the snapshot is stored in the etcd data dir, so it is accessible both from the node
+	// and from the etcd static pod
+	etcdSnapshotFile = "/var/lib/etcd/kinder-snapshot.db"
+
+	// etcdRestoreDir defines the path where the snapshot is restored before replacing the etcd data
+	etcdRestoreDir = "/var/lib/etcd/kinder-restore"
+
+	// etcdMemberBackupDir defines the path where the etcd data are saved before being replaced by a restored snapshot
+	etcdMemberBackupDir = "/kinder/etcd-member-backup"
+)
+
+// EtcdSnapshot executes etcdctl snapshot save or etcdctl snapshot restore; args are the
+// snapshot command (save or restore) and optionally the path of the snapshot file on the host.
+// If the path is not provided, the snapshot file is stored in the ARTIFACTS folder, if defined,
+// or in the current folder.
+func EtcdSnapshot(c *status.Cluster, args []string, wait time.Duration) error {
+	if len(args) < 1 || len(args) > 2 {
+		return errors.New("etcd-snapshot requires a command, save or restore, and optionally the path of the snapshot file")
+	}
+
+	path := filepath.Join(os.Getenv("ARTIFACTS"), fmt.Sprintf("%s-etcd-snapshot.db", c.Name()))
+	if len(args) == 2 {
+		path = args[1]
+	}
+
+	switch args[0] {
+	case "save":
+		return etcdSnapshotSave(c, path)
+	case "restore":
+		return etcdSnapshotRestore(c, path, wait)
+	}
+	return errors.Errorf("invalid etcd-snapshot command %q. Use one of [save restore]", args[0])
+}
+
+// etcdSnapshotSave saves a snapshot from the first external etcd member, if any, or from
+// the first control-plane node eligible for actions, and copies it to the host
+func etcdSnapshotSave(c *status.Cluster, path string) error {
+	if c.ExternalEtcd() != nil {
+		return externalEtcdSnapshotSave(c.ExternalEtcd(), path)
+	}
+
+	cps := c.ControlPlanes().EligibleForActions()
+	if len(cps) == 0 {
+		return errors.New("etcd-snapshot save requires a control-plane node")
+	}
+	n := cps[0]
+
+	n.Infof("saving etcd snapshot to %s", path)
+	etcdArgs, err := etcdctlV3Args(c, n)
+	if err != nil {
+		return err
+	}
+	if err := n.Command(
+		"kubectl", append(etcdArgs, "snapshot", "save", etcdSnapshotFile)...,
+	).RunWithEcho(); err != nil {
+		return errors.Wrapf(err, "failed to save etcd snapshot on node %s", n.Name())
+	}
+
+	if err := copySnapshotToHost(n, etcdSnapshotFile, path); err != nil {
+		return err
+	}
+	return n.Command("rm", "-f", etcdSnapshotFile).Silent().Run()
+}
+
+// externalEtcdSnapshotSave saves a snapshot from an external etcd member and copies it to the host
+func externalEtcdSnapshotSave(n *status.Node, path string) error {
+	n.Infof("saving etcd snapshot to %s", path)
+
+	// TLS secured external etcd members are labeled at creation time, while the insecure, single node,
+	// external etcd is listening on http; please note that etcd images do not provide a shell, and
+	// the etcdctl API version is set at container level
+	secure, err := n.IsSecureExternalEtcd()
+	if err != nil {
+		return err
+	}
+	etcdArgs := []string{"--endpoints=http://127.0.0.1:2379"}
+	if secure {
+		etcdArgs = []string{
+			"--endpoints=https://127.0.0.1:2379",
+			fmt.Sprintf("--cacert=%s/ca.crt", constants.ExternalEtcdPKIDir),
+			fmt.Sprintf("--cert=%s/healthcheck-client.crt", constants.ExternalEtcdPKIDir),
+			fmt.Sprintf("--key=%s/healthcheck-client.key", constants.ExternalEtcdPKIDir),
+		}
+	}
+
+	snapshotFile := "/tmp/kinder-snapshot.db"
+	if err := n.Command(
+		"etcdctl", append(etcdArgs, "snapshot", "save", snapshotFile)...,
+	).RunWithEcho(); err != nil {
+		return errors.Wrapf(err, "failed to save etcd snapshot on node %s", n.Name())
+	}
+
+	if err := copySnapshotToHost(n, snapshotFile, path); err != nil {
+		return err
+	}
+
+	// etcd images do not provide rm, so the snapshot is deleted only from TLS secured external etcd members,
+	// that are based on the node image
+	if !secure {
+		return nil
+	}
+	return n.Command("rm", "-f", snapshotFile).Silent().Run()
+}
+
+// copySnapshotToHost copies the etcd snapshot from a node to the host
+func copySnapshotToHost(n *status.Node, snapshotFile, path string) error {
+	if n.IsDryRun() {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return errors.Wrapf(err, "failed to create %s", filepath.Dir(path))
+	}
+	if err := n.CopyFrom(snapshotFile, path); err != nil {
+		return errors.Wrapf(err, "failed to copy etcd snapshot from node %s", n.Name())
+	}
+	return nil
+}
+
+// etcdSnapshotRestore restores a snapshot on all the etcd members hosted on control-plane nodes. This is
+// implemented following the etcd disaster recovery procedure: the snapshot is restored on all the members
+// in a temporary folder using the etcdctl binary in the etcd static pod; then, after stopping the API server
+// and etcd on all the control-plane nodes, the etcd data dir is replaced with the restored data, and finally
+// etcd and the API server are restarted.
+func etcdSnapshotRestore(c *status.Cluster, path string, wait time.Duration) error {
+	if c.ExternalEtcd() != nil {
+		return errors.New("etcd-snapshot restore is supported only for stacked etcd")
+	}
+
+	// the snapshot should be restored on all the etcd members, so all the control-plane nodes are
+	// used regardless of the nodes selected for actions
+	cps := c.ControlPlanes()
+	if len(cps) == 0 {
+		return errors.New("etcd-snapshot restore requires a control-plane node")
+	}
+	dryRun := cps[0].IsDryRun()
+
+	if _, err := os.Stat(path); err != nil && !dryRun {
+		return errors.Wrapf(err, "failed to read etcd snapshot %s", path)
+	}
+
+	initialCluster := []string{}
+	peerURLs := map[string]string{}
+	for _, n := range cps {
+		ip, ipv6, err := n.IP()
+		if err != nil {
+			return errors.Wrapf(err, "failed to get IP for node: %s", n.Name())
+		}
+		if c.Settings.IPFamily == status.IPv6Family {
+			ip = ipv6
+		}
+		peerURLs[n.Name()] = fmt.Sprintf("https://%s", net.JoinHostPort(ip, "2380"))
+		initialCluster = append(initialCluster, fmt.Sprintf("%s=%s", n.Name(), peerURLs[n.Name()]))
+	}
+
+	// restores the snapshot on all the members in a temporary folder, with a new cluster token
+	token := fmt.Sprintf("kinder-restore-%d", time.Now().Unix())
+	for _, n := range cps {
+		n.Infof("restoring etcd snapshot %s", path)
+
+		if err := n.Command("rm", "-rf", etcdRestoreDir).Silent().Run(); err != nil {
+			return errors.Wrapf(err, "failed to delete %s on node %s", etcdRestoreDir, n.Name())
+		}
+		if !dryRun {
+			if err := n.CopyTo(path, etcdSnapshotFile); err != nil {
+				return errors.Wrapf(err, "failed to copy etcd snapshot to node %s", n.Name())
+			}
+		}
+
+		etcdArgs, err := etcdctlV3Args(c, n)
+		if err != nil {
+			return err
+		}
+		if err := n.Command(
+			"kubectl", append(etcdArgs,
+				"snapshot", "restore", etcdSnapshotFile,
+				fmt.Sprintf("--data-dir=%s", etcdRestoreDir),
+				fmt.Sprintf("--name=%s", n.Name()),
+				fmt.Sprintf("--initial-cluster=%s", strings.Join(initialCluster, ",")),
+				fmt.Sprintf("--initial-cluster-token=%s", token),
+				fmt.Sprintf("--initial-advertise-peer-urls=%s", peerURLs[n.Name()]),
+			)...,
+		).RunWithEcho(); err != nil {
+			return errors.Wrapf(err, "failed to restore etcd snapshot on node %s", n.Name())
+		}
+	}
+
+	// stops the API server and etcd on all the control-plane nodes
+	for _, n := range cps {
+		if err := stopStaticPods(c, n, []string{"kube-apiserver", "etcd"}, wait); err != nil {
+			return err
+		}
+	}
+
+	// replaces the etcd data dir with the restored data, preserving a backup of the original data
+	for _, n := range cps {
+		n.Infof("replacing etcd data with the restored snapshot (original data are saved in %s)", etcdMemberBackupDir)
+		if err := n.Command(
+			"/bin/sh", "-c",
+			fmt.Sprintf("rm -rf %[1]s && mkdir -p %[1]s && mv %[2]s/member %[1]s/ && mv %[3]s/member %[2]s/ && rm -rf %[3]s %[4]s",
+				etcdMemberBackupDir, etcdDataDir, etcdRestoreDir, etcdSnapshotFile),
+		).RunWithEcho(); err != nil {
+			return errors.Wrapf(err, "failed to replace etcd data on node %s", n.Name())
+		}
+	}
+
+	// restarts etcd on all the control-plane nodes, and then the API server
+	for _, n := range cps {
+		if err := startStaticPods(n, []string{"etcd"}); err != nil {
+			return err
+		}
+	}
+	for _, n := range cps {
+		if err := startStaticPods(n, []string{"kube-apiserver"}); err != nil {
+			return err
+		}
+	}
+
+	for _, n := range cps {
+		if err := waitStaticPodsReady(c, n, []string{"etcd", "kube-apiserver"}, wait); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// etcdctlV3Args returns the kubectl arguments for running etcdctl with the v3 API inside the etcd static pod
+// hosted on the given control-plane node.
+// If no endpoints are provided, etcdctl connects to the local etcd member only.
+func etcdctlV3Args(c *status.Cluster, n *status.Node, endpoints ...string) ([]string, error) {
+	e, err := newEtcdctl(c, n)
+	if err != nil {
+		return nil, err
+	}
+	return e.args(endpoints...), nil
+}
+
+// etcdctl runs etcdctl with the v3 API inside the etcd static pod hosted on a control-plane node; the etcdctl version
+// is detected only once, when the etcdctl is created, so it is possible to run many etcdctl commands at the cost
+// of one exec each. Commands with captured output are executed using the cluster client, like waiter conditions do.
+type etcdctl struct {
+	c       *status.Cluster
+	n       *status.Node
+	version *versionutils.Version
+}
+
+// newEtcdctl returns an etcdctl for the etcd static pod hosted on the given control-plane node
+func newEtcdctl(c *status.Cluster, n *status.Node) (*etcdctl, error) {
+	// Get the version of etcdctl from the etcd binary; in dry run it is assumed the latest etcd version
+	etcdctlVersion := "3.4.0"
+	if !n.IsDryRun() {
+		lines, err := podExec(c, metav1.NamespaceSystem, etcdPodName(n), "etcd", "--version")
+		if err != nil {
+			return nil, err
+		}
+		etcdctlVersion, err = parseEtcdctlVersion(lines)
+		if err != nil {
+			return nil, err
+		}
+	}
+	version, err := versionutils.ParseGeneric(etcdctlVersion)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot parse etcd version")
+	}
+
+	log.Debugf("Using etcdctl version: %s", etcdctlVersion)
+	return &etcdctl{c: c, n: n, version: version}, nil
+}
+
+// args returns the kubectl arguments for running etcdctl against the given endpoints, to be used for commands
+// echoed to the user; see command for details about etcdctl arguments.
+func (e *etcdctl) args(endpoints ...string) []string {
+	return append(etcdctlExecArgs(e.n), e.command(endpoints...)...)
+}
+
+// command returns the command for running etcdctl inside the etcd static pod against the given endpoints.
+// Before etcd v3.4 the v3 API should be explicitly enabled, and certificate flags should use the v3 API names,
+// that are the same names used by etcdctl v3.4 or newer. If no endpoints are provided, etcdctl connects to the
+// local etcd member only.
+func (e *etcdctl) command(endpoints ...string) []string {
+	etcdArgs := []string{}
+	apiV3 := e.version.LessThan(versionutils.MustParseGeneric("v3.4.0"))
+	if apiV3 {
+		etcdArgs = append(etcdArgs, "env", "ETCDCTL_API=3")
+	}
+	if len(endpoints) == 0 {
+		endpoints = []string{"https://127.0.0.1:2379"}
+	}
+	etcdArgs = append(etcdArgs, "etcdctl", fmt.Sprintf("--endpoints=%s", strings.Join(endpoints, ",")))
+	etcdArgs = append(etcdArgs, etcdctlCertArgs(e.version, apiV3)...)
+
+	return etcdArgs
+}
+
+// capture runs an etcdctl command against the given endpoints and returns its output; in dry run the command
+// is printed instead of being executed, and the output is empty.
+func (e *etcdctl) capture(endpoints []string, command ...string) ([]string, error) {
+	if e.n.IsDryRun() {
+		return e.n.Command("kubectl", append(e.args(endpoints...), command...)...).RunAndCapture()
+	}
+	return podExec(e.c, metav1.NamespaceSystem, etcdPodName(e.n), append(e.command(endpoints...), command...)...)
+}
+
+// run runs an etcdctl command against the given endpoints like capture, and prints its output
+func (e *etcdctl) run(endpoints []string, command ...string) error {
+	lines, err := e.capture(endpoints, command...)
+	for _, l := range lines {
+		fmt.Println(l)
+	}
+	return err
+}
+
+// etcdPodName returns the name of the etcd static pod hosted on the given control-plane node
+func etcdPodName(n *status.Node) string {
+	return fmt.Sprintf("etcd-%s", n.Name())
+}
+
+// etcdctlExecArgs returns the kubectl arguments for executing a command inside the etcd static pod
+// hosted on the given control-plane node
+func etcdctlExecArgs(n *status.Node) []string {
+	return []string{
+		"--kubeconfig=/etc/kubernetes/admin.conf", "exec", "-n=kube-system", etcdPodName(n),
+		"--",
+	}
+}
@@ -0,0 +1,226 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/cri"
+)
+
+// diagnosticsErrorsFile is the file where items that could not be collected are recorded
+const diagnosticsErrorsFile = "errors.txt"
+
+// diagnosticsCommand defines a command to be executed on a node, whose output
+// is stored in a file of the diagnostics folder
+type diagnosticsCommand struct {
+	file string
+	args []string
+}
+
+// nodeDiagnosticsCommands defines the diagnostics collected on every Kubernetes node
+var nodeDiagnosticsCommands = []diagnosticsCommand{
+	{file: "kubeadm.conf", args: []string{"cat", "/kind/kubeadm.conf"}},
+	{file: "kubeadm-flags.env", args: []string{"cat", "/var/lib/kubelet/kubeadm-flags.env"}},
+	{file: "kubelet-config.yaml", args: []string{"cat", "/var/lib/kubelet/config.yaml"}},
+	{file: "kubelet.log", args: []string{"journalctl", "--no-pager", "-u", "kubelet"}},
+	{file: "containerd.log", args: []string{"journalctl", "--no-pager", "-u", "containerd"}},
+	{file: "docker.log", args: []string{"journalctl", "--no-pager", "-u", "docker"}},
+	{file: "iptables.txt", args: []string{"iptables-save"}},
+	{file: "ip6tables.txt", args: []string{"ip6tables-save"}},
+}
+
+// clusterDiagnosticsCommands defines the diagnostics collected at cluster level using kubectl on the
+// bootstrap control plane
+var clusterDiagnosticsCommands = []diagnosticsCommand{
+	{file: "nodes.txt", args: []string{"get", "nodes", "-o", "wide"}},
+	{file: "nodes.yaml", args: []string{"get", "nodes", "-o", "yaml"}},
+	{file: "pods.txt", args: []string{"get", "pods", "--all-namespaces", "-o", "wide"}},
+	{file: "pods.yaml", args: []string{"get", "pods", "--all-namespaces", "-o", "yaml"}},
+	{file: "workloads.yaml", args: []string{"get", "deployments,daemonsets", "--all-namespaces", "-o", "yaml"}},
+	{file: "events.txt", args: []string{"get", "events", "--all-namespaces", "--sort-by=.lastTimestamp"}},
+	{file: "configmaps/cluster-info.yaml", args: []string{"get", "configmap", "-n", "kube-public", "cluster-info", "-o", "yaml"}},
+}
+
+// kubeadmConfigMapPrefixes defines the name prefixes of the ConfigMaps in the kube-system namespace created by kubeadm
+var kubeadmConfigMapPrefixes = []string{"kubeadm-config", "kubelet-config", "kube-proxy", "coredns"}
+
+// ExportDiagnostics collects kubeadm related diagnostics from all the Kubernetes nodes and from the cluster,
+// and stores them into the given folder; diagnostics for each node are stored in a sub folder named
+// like the node, while cluster level diagnostics are stored in the cluster sub folder.
+// Collection is best effort: items that cannot be collected are listed in the errors.txt file
+// instead of stopping the export
+func ExportDiagnostics(c *status.Cluster, dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return errors.Wrapf(err, "failed to create the %s folder", dir)
+	}
+
+	d := &diagnostics{dir: dir}
+	for _, n := range c.K8sNodes() {
+		d.collectNode(n)
+	}
+	if cp1 := c.BootstrapControlPlane(); cp1 != nil {
+		d.collectCluster(cp1)
+	}
+
+	if len(d.failures) > 0 {
+		if err := d.write(diagnosticsErrorsFile, d.failures); err != nil {
+			return err
+		}
+		fmt.Printf("Diagnostics exported to %s; %d items could not be collected, see %s for details\n", dir, len(d.failures), diagnosticsErrorsFile)
+		return nil
+	}
+	fmt.Printf("Diagnostics exported to %s\n", dir)
+	return nil
+}
+
+// diagnostics collects diagnostics into a folder, keeping track of the items that could not be collected
+type diagnostics struct {
+	dir      string
+	failures []string
+}
+
+// collectNode collects diagnostics for a node
+func (d *diagnostics) collectNode(n *status.Node) {
+	for _, cmd := range nodeDiagnosticsCommands {
+		lines, err := n.Command(cmd.args[0], cmd.args[1:]...).Silent().RunAndCapture()
+		d.record(filepath.Join(n.Name(), cmd.file), lines, err)
+	}
+
+	manifests, err := n.Command("ls", manifestsDir).Silent().RunAndCapture()
+	if err != nil {
+		d.fail(filepath.Join(n.Name(), "manifests"), err)
+	}
+	for _, m := range manifests {
+		lines, err := n.Command("cat", filepath.Join(manifestsDir, m)).Silent().RunAndCapture()
+		d.record(filepath.Join(n.Name(), "manifests", m), lines, err)
+	}
+
+	if n.IsControlPlane() {
+		certsCmd, err := kubeadmCertsCommand(n)
+		if err != nil {
+			d.fail(filepath.Join(n.Name(), "certs-expiration.txt"), err)
+		} else {
+			lines, err := n.Command("kubeadm", append(certsCmd, "check-expiration")...).Silent().RunAndCapture()
+			d.record(filepath.Join(n.Name(), "certs-expiration.txt"), lines, err)
+		}
+	}
+
+	d.collectContainers(n)
+}
+
+// collectContainers collects the list of containers existing on a node and their logs
+func (d *diagnostics) collectContainers(n *status.Node) {
+	nodeCRI, err := n.CRI()
+	if err != nil {
+		d.fail(filepath.Join(n.Name(), "containers.txt"), err)
+		return
+	}
+	actionHelper, err := cri.NewActionHelper(nodeCRI)
+	if err != nil {
+		d.fail(filepath.Join(n.Name(), "containers.txt"), err)
+		return
+	}
+
+	list, err := actionHelper.ListContainers(n)
+	d.record(filepath.Join(n.Name(), "containers.txt"), list, err)
+
+	containers, err := actionHelper.GetAllContainers(n)
+	if err != nil {
+		d.fail(filepath.Join(n.Name(), "containers"), err)
+		return
+	}
+	for _, id := range containers {
+		logs, err := actionHelper.GetContainerLogs(n, id)
+		d.record(filepath.Join(n.Name(), "containers", fmt.Sprintf("%s.log", id)), logs, err)
+	}
+}
+
+// collectCluster collects cluster level diagnostics and the kubeadm ConfigMaps using kubectl on the given node
+func (d *diagnostics) collectCluster(cp *status.Node) {
+	kubectl := func(args ...string) ([]string, error) {
+		return cp.Command(
+			"kubectl", append([]string{"--kubeconfig=/etc/kubernetes/admin.conf"}, args...)...,
+		).Silent().RunAndCapture()
+	}
+
+	for _, cmd := range clusterDiagnosticsCommands {
+		lines, err := kubectl(cmd.args...)
+		d.record(filepath.Join("cluster", cmd.file), lines, err)
+	}
+
+	names, err := kubectl("get", "configmaps", "-n", "kube-system", "-o", "name")
+	if err != nil {
+		d.fail(filepath.Join("cluster", "configmaps"), err)
+		return
+	}
+	for _, name := range kubeadmConfigMaps(names) {
+		lines, err := kubectl("get", "configmap", "-n", "kube-system", name, "-o", "yaml")
+		d.record(filepath.Join("cluster", "configmaps", fmt.Sprintf("%s.yaml", name)), lines, err)
+	}
+}
+
+// record writes the output of a command to a file in the diagnostics folder; if the command failed,
+// the output is written anyway, because it usually contains the error details, and the failure is recorded
+func (d *diagnostics) record(file string, lines []string, err error) {
+	if err != nil {
+		d.fail(file, err)
+	}
+	if err := d.write(file, lines); err != nil {
+		d.fail(file, err)
+	}
+}
+
+// fail records that an item could not be collected
+func (d *diagnostics) fail(file string, err error) {
+	d.failures = append(d.failures, fmt.Sprintf("%s: %v", file, err))
+}
+
+// write writes lines to a file in the diagnostics folder
+func (d *diagnostics) write(file string, lines []string) error {
+	path := filepath.Join(d.dir, file)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return errors.Wrapf(err, "failed to create the %s folder", filepath.Dir(path))
+	}
+	if err := ioutil.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		return errors.Wrapf(err, "failed to write %s", path)
+	}
+	return nil
+}
+
+// kubeadmConfigMaps returns the names of the ConfigMaps created by kubeadm, given the output of
+// kubectl get configmaps -o name
+func kubeadmConfigMaps(names []string) []string {
+	result := []string{}
+	for _, n := range names {
+		name := strings.TrimPrefix(strings.TrimSpace(n), "configmap/")
+		for _, p := range kubeadmConfigMapPrefixes {
+			if strings.HasPrefix(name, p) {
+				result = append(result, name)
+				break
+			}
+		}
+	}
+	return result
+}
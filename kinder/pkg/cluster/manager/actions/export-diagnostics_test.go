@@ -0,0 +1,61 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestKubeadmConfigMaps(t *testing.T) {
+	tests := []struct {
+		name     string
+		names    []string
+		expected []string
+	}{
+		{
+			name:     "no ConfigMaps",
+			names:    nil,
+			expected: []string{},
+		},
+		{
+			name: "kubeadm ConfigMaps are selected",
+			names: []string{
+				"configmap/coredns",
+				"configmap/extension-apiserver-authentication",
+				"configmap/kube-proxy",
+				"configmap/kubeadm-config",
+				"configmap/kubelet-config-1.19",
+				"configmap/calico-config",
+			},
+			expected: []string{"coredns", "kube-proxy", "kubeadm-config", "kubelet-config-1.19"},
+		},
+		{
+			name:     "names without the resource prefix",
+			names:    []string{"kubelet-config", "kube-root-ca.crt"},
+			expected: []string{"kubelet-config"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := kubeadmConfigMaps(test.names)
+			if !reflect.DeepEqual(got, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, got)
+			}
+		})
+	}
+}
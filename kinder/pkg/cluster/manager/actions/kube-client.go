@@ -0,0 +1,362 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/remotecommand"
+	watchtools "k8s.io/client-go/tools/watch"
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/constants"
+)
+
+const (
+	// kubeClientTimeout defines the timeout for requests sent to the API server
+	kubeClientTimeout = 30 * time.Second
+
+	// watchTimeout defines the duration of watches used by conditions; when a watch expires
+	// the condition is tested again by the waiter loop
+	watchTimeout = 5 * time.Second
+)
+
+// kubeClients caches, for each cluster, the client created from the admin.conf file of the bootstrap control
+// plane node; the cached client is dropped when the admin.conf file changes, see forgetKubeClient
+var kubeClients = struct {
+	sync.Mutex
+	clients map[string]cachedKubeClient
+}{clients: map[string]cachedKubeClient{}}
+
+type cachedKubeClient struct {
+	config *rest.Config
+	client kubernetes.Interface
+}
+
+// kubeClient returns a client for the cluster using the admin.conf file of the bootstrap control plane node;
+// the API server is reached on 127.0.0.1 through the port published on the host by the external load balancer,
+// or by the bootstrap control plane node
+func kubeClient(c *status.Cluster) (kubernetes.Interface, error) {
+	_, client, err := kubeClientConfig(c)
+	return client, err
+}
+
+// kubeClientConfig returns the client config and the client for the cluster; see kubeClient
+func kubeClientConfig(c *status.Cluster) (*rest.Config, kubernetes.Interface, error) {
+	kubeClients.Lock()
+	defer kubeClients.Unlock()
+
+	if cached, ok := kubeClients.clients[c.Name()]; ok {
+		return cached.config, cached.client, nil
+	}
+
+	hostPort, err := publishedAPIServerPort(c)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to get the API server port for cluster %s", c.Name())
+	}
+	lines, err := c.BootstrapControlPlane().Command("cat", "/etc/kubernetes/admin.conf").Silent().RunAndCapture()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to get kubeconfig from node")
+	}
+
+	config, err := clientcmd.RESTConfigFromKubeConfig([]byte(strings.Join(lines, "\n")))
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to load the kubeconfig file for cluster %s", c.Name())
+	}
+	config.Host = fmt.Sprintf("https://%s", net.JoinHostPort("127.0.0.1", fmt.Sprintf("%d", hostPort)))
+	// the API server certificate is valid for localhost, but not for 127.0.0.1; see certSANs in the kubeadm config
+	config.TLSClientConfig.ServerName = "localhost"
+	config.Timeout = kubeClientTimeout
+
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to create a client for cluster %s", c.Name())
+	}
+	kubeClients.clients[c.Name()] = cachedKubeClient{config: config, client: client}
+	return config, client, nil
+}
+
+// forgetKubeClient drops the cached client for the cluster, so the next client is created from the current
+// admin.conf file; this should be called when admin.conf changes, e.g. after kubeadm-init or kubeadm-certs-renew
+func forgetKubeClient(c *status.Cluster) {
+	kubeClients.Lock()
+	defer kubeClients.Unlock()
+
+	delete(kubeClients.clients, c.Name())
+}
+
+// publishedAPIServerPort returns the port published on the host for the external load balancer or, if the cluster
+// does not have an external load balancer, for the API server of the bootstrap control plane node
+func publishedAPIServerPort(c *status.Cluster) (int32, error) {
+	if c.ExternalLoadBalancer() != nil {
+		return c.ExternalLoadBalancer().Ports(constants.ControlPlanePort)
+	}
+	return c.BootstrapControlPlane().Ports(constants.APIServerPort)
+}
+
+// podExec runs a command in the first container of a pod and returns the output lines, stdout first
+func podExec(c *status.Cluster, namespace, name string, command ...string) ([]string, error) {
+	config, client, err := kubeClientConfig(c)
+	if err != nil {
+		return nil, err
+	}
+
+	req := client.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(namespace).
+		Name(name).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Command: command,
+			Stdout:  true,
+			Stderr:  true,
+		}, scheme.ParameterCodec)
+
+	executor, err := remotecommand.NewSPDYExecutor(config, "POST", req.URL())
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to exec into pod %s/%s", namespace, name)
+	}
+
+	var stdout, stderr bytes.Buffer
+	err = executor.Stream(remotecommand.StreamOptions{Stdout: &stdout, Stderr: &stderr})
+	lines := append(outputLines(stdout.String()), outputLines(stderr.String())...)
+	if err != nil {
+		return lines, errors.Wrapf(err, "command %q failed in pod %s/%s", strings.Join(command, " "), namespace, name)
+	}
+	return lines, nil
+}
+
+// outputLines splits a command output into lines, discarding the trailing newline
+func outputLines(s string) []string {
+	s = strings.TrimRight(s, "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
+
+// watchUntil gets an object and, if the object does not satisfy the condition yet, watches the object
+// until the condition is satisfied or the watch expires
+func watchUntil(
+	name string,
+	get func() (runtime.Object, error),
+	watchFn func(metav1.ListOptions) (watch.Interface, error),
+	condition func(runtime.Object) bool,
+) (bool, error) {
+	obj, err := get()
+	if err != nil {
+		return false, err
+	}
+	if condition(obj) {
+		return true, nil
+	}
+
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return false, err
+	}
+	timeoutSeconds := int64(watchTimeout.Seconds())
+	w, err := watchFn(metav1.ListOptions{
+		FieldSelector:   fields.OneTermEqualSelector("metadata.name", name).String(),
+		ResourceVersion: accessor.GetResourceVersion(),
+		TimeoutSeconds:  &timeoutSeconds,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), watchTimeout)
+	defer cancel()
+	_, err = watchtools.UntilWithoutRetry(ctx, w, func(e watch.Event) (bool, error) {
+		switch e.Type {
+		case watch.Error:
+			return false, apierrors.FromObject(e.Object)
+		case watch.Deleted:
+			return false, nil
+		}
+		return condition(e.Object), nil
+	})
+	switch err {
+	case nil:
+		return true, nil
+	case wait.ErrWaitTimeout, watchtools.ErrWatchClosed:
+		return false, nil
+	}
+	return false, err
+}
+
+// watchNode gets and watches a node until the condition is satisfied or the watch expires;
+// errors are logged at debug level, and the condition is tested again by the waiter loop
+func watchNode(c *status.Cluster, name string, condition func(*corev1.Node) bool) bool {
+	client, err := kubeClient(c)
+	if err != nil {
+		log.Debugf("failed to get node %s: %v", name, err)
+		return false
+	}
+	pass, err := watchUntil(name,
+		func() (runtime.Object, error) {
+			return client.CoreV1().Nodes().Get(name, metav1.GetOptions{})
+		},
+		func(options metav1.ListOptions) (watch.Interface, error) {
+			return client.CoreV1().Nodes().Watch(options)
+		},
+		func(obj runtime.Object) bool {
+			node, ok := obj.(*corev1.Node)
+			return ok && condition(node)
+		},
+	)
+	if err != nil {
+		log.Debugf("failed to watch node %s: %v", name, err)
+	}
+	return pass
+}
+
+// watchPod gets and watches a pod until the condition is satisfied or the watch expires;
+// errors are logged at debug level, and the condition is tested again by the waiter loop
+func watchPod(c *status.Cluster, namespace, name string, condition func(*corev1.Pod) bool) bool {
+	client, err := kubeClient(c)
+	if err != nil {
+		log.Debugf("failed to get pod %s/%s: %v", namespace, name, err)
+		return false
+	}
+	pass, err := watchUntil(name,
+		func() (runtime.Object, error) {
+			return client.CoreV1().Pods(namespace).Get(name, metav1.GetOptions{})
+		},
+		func(options metav1.ListOptions) (watch.Interface, error) {
+			return client.CoreV1().Pods(namespace).Watch(options)
+		},
+		func(obj runtime.Object) bool {
+			pod, ok := obj.(*corev1.Pod)
+			return ok && condition(pod)
+		},
+	)
+	if err != nil {
+		log.Debugf("failed to watch pod %s/%s: %v", namespace, name, err)
+	}
+	return pass
+}
+
+// watchWorkload gets and watches a workload until the condition is satisfied or the watch expires;
+// errors are logged at debug level, and the condition is tested again by the waiter loop
+func watchWorkload(c *status.Cluster, w workload, condition func(runtime.Object) bool) bool {
+	client, err := kubeClient(c)
+	if err != nil {
+		log.Debugf("failed to get %s: %v", w, err)
+		return false
+	}
+	get, watchFn, err := workloadClient(client, w)
+	if err != nil {
+		log.Debugf("failed to get %s: %v", w, err)
+		return false
+	}
+
+	pass, err := watchUntil(w.Name, get, watchFn, condition)
+	if err != nil {
+		log.Debugf("failed to watch %s: %v", w, err)
+	}
+	return pass
+}
+
+// workloadClient returns the functions for getting and watching a Deployment, a StatefulSet or a DaemonSet
+func workloadClient(client kubernetes.Interface, w workload) (
+	get func() (runtime.Object, error),
+	watchFn func(metav1.ListOptions) (watch.Interface, error),
+	err error,
+) {
+	switch w.Kind {
+	case "Deployment":
+		get = func() (runtime.Object, error) {
+			return client.AppsV1().Deployments(w.Namespace).Get(w.Name, metav1.GetOptions{})
+		}
+		return get, client.AppsV1().Deployments(w.Namespace).Watch, nil
+	case "StatefulSet":
+		get = func() (runtime.Object, error) {
+			return client.AppsV1().StatefulSets(w.Namespace).Get(w.Name, metav1.GetOptions{})
+		}
+		return get, client.AppsV1().StatefulSets(w.Namespace).Watch, nil
+	case "DaemonSet":
+		get = func() (runtime.Object, error) {
+			return client.AppsV1().DaemonSets(w.Namespace).Get(w.Name, metav1.GetOptions{})
+		}
+		return get, client.AppsV1().DaemonSets(w.Namespace).Watch, nil
+	}
+	return nil, nil, errors.Errorf("unsupported workload kind %s", w.Kind)
+}
+
+// workloadReplicas returns the number of desired replicas and the number of replicas ready/available for a
+// Deployment, a StatefulSet or a DaemonSet
+func workloadReplicas(obj runtime.Object) (desired, ready int32, ok bool) {
+	switch o := obj.(type) {
+	case *appsv1.Deployment:
+		desired = 1
+		if o.Spec.Replicas != nil {
+			desired = *o.Spec.Replicas
+		}
+		return desired, o.Status.AvailableReplicas, true
+	case *appsv1.StatefulSet:
+		desired = 1
+		if o.Spec.Replicas != nil {
+			desired = *o.Spec.Replicas
+		}
+		return desired, o.Status.ReadyReplicas, true
+	case *appsv1.DaemonSet:
+		return o.Status.DesiredNumberScheduled, o.Status.NumberReady, true
+	}
+	return 0, 0, false
+}
+
+// nodeReadyStatus returns the status of the Ready condition of a node
+func nodeReadyStatus(node *corev1.Node) corev1.ConditionStatus {
+	for _, c := range node.Status.Conditions {
+		if c.Type == corev1.NodeReady {
+			return c.Status
+		}
+	}
+	return ""
+}
+
+// podReadyStatus returns the status of the Ready condition of a pod
+func podReadyStatus(pod *corev1.Pod) corev1.ConditionStatus {
+	for _, c := range pod.Status.Conditions {
+		if c.Type == corev1.PodReady {
+			return c.Status
+		}
+	}
+	return ""
+}
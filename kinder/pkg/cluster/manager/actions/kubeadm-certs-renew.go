@@ -0,0 +1,263 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"crypto/x509"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/kubeadm/kinder/pkg/certs"
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/constants"
+)
+
+// staticPodsBackupDir defines the path where static pod manifests are temporarily moved
+// for forcing the kubelet to restart static pods
+const staticPodsBackupDir = "/kinder/static-pods-backup"
+
+// KubeadmCertsRenew executes the kubeadm certs renew workflow on control-plane nodes; after
+// renewal, static pods using the renewed certificates are restarted and the new certificates
+// are verified.
+func KubeadmCertsRenew(c *status.Cluster, certNames []string, wait time.Duration, vLevel int) error {
+	selected, err := certs.SelectKubeadmCertificates(certNames)
+	if err != nil {
+		return err
+	}
+
+	renewAll := len(selected) == len(certs.KubeadmCertificates())
+	for _, n := range c.ControlPlanes().EligibleForActions() {
+		if err := kubeadmCertsRenew(c, n, selected, renewAll, wait, vLevel); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func kubeadmCertsRenew(c *status.Cluster, n *status.Node, selected []certs.KubeadmCertificate, renewAll bool, wait time.Duration, vLevel int) error {
+	certsCmd, err := kubeadmCertsCommand(n)
+	if err != nil {
+		return err
+	}
+
+	if err := n.Command(
+		"kubeadm", append(certsCmd, "check-expiration", fmt.Sprintf("--v=%d", vLevel))...,
+	).RunWithEcho(); err != nil {
+		return err
+	}
+
+	before, err := readKubeadmCertificates(n, selected)
+	if err != nil {
+		return err
+	}
+
+	if renewAll {
+		if err := n.Command(
+			"kubeadm", append(certsCmd, "renew", "all", fmt.Sprintf("--v=%d", vLevel))...,
+		).RunWithEcho(); err != nil {
+			return err
+		}
+	} else {
+		for _, cert := range selected {
+			if err := n.Command(
+				"kubeadm", append(certsCmd, "renew", cert.Name, fmt.Sprintf("--v=%d", vLevel))...,
+			).RunWithEcho(); err != nil {
+				return err
+			}
+		}
+	}
+
+	// restarts the static pods using the renewed certificates, so the new certificates are picked up
+	components := []string{}
+	renewedAdminConf := false
+	for _, cert := range selected {
+		if _, ok := before[cert.Name]; !ok {
+			continue
+		}
+		if cert.Name == "admin.conf" {
+			renewedAdminConf = true
+		}
+		if cert.Component != "" && !contains(components, cert.Component) {
+			components = append(components, cert.Component)
+		}
+	}
+	if err := restartStaticPods(c, n, components, wait); err != nil {
+		return err
+	}
+	if err := waitStaticPodsReady(c, n, components, wait); err != nil {
+		return err
+	}
+
+	if n.IsDryRun() {
+		return nil
+	}
+
+	after, err := readKubeadmCertificates(n, selected)
+	if err != nil {
+		return err
+	}
+	if err := verifyRenewedCertificates(n, before, after); err != nil {
+		return err
+	}
+
+	// refresh the kubeconfig file on the host, so it uses the renewed admin.conf
+	if renewedAdminConf && n.Name() == c.BootstrapControlPlane().Name() {
+		if err := copyKubeConfigToHost(c); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// kubeadmCertsCommand returns the kubeadm command for managing certificates, that graduated
+// from kubeadm alpha certs to kubeadm certs in v1.20
+func kubeadmCertsCommand(n *status.Node) ([]string, error) {
+	kubeadmVersion, err := n.KubeadmVersion()
+	if err != nil {
+		return nil, err
+	}
+	if kubeadmVersion.LessThan(constants.V1_20) {
+		return []string{"alpha", "certs"}, nil
+	}
+	return []string{"certs"}, nil
+}
+
+// readKubeadmCertificates reads the given certificates from a node; certificates not existing
+// on the node e.g. etcd certificates when using external etcd, are ignored
+func readKubeadmCertificates(n *status.Node, selected []certs.KubeadmCertificate) (map[string]*x509.Certificate, error) {
+	result := map[string]*x509.Certificate{}
+	for _, cert := range selected {
+		data, err := n.ReadFile(cert.Path)
+		if err != nil {
+			continue
+		}
+		if n.IsDryRun() {
+			result[cert.Name] = nil
+			continue
+		}
+
+		var x *x509.Certificate
+		if cert.KubeConfig {
+			x, err = certs.ParseKubeConfigCertificate(data)
+		} else {
+			var list []*x509.Certificate
+			list, err = certs.ParseCertificates(data)
+			if err == nil {
+				x = list[0]
+			}
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read certificate %s from %s", cert.Name, cert.Path)
+		}
+		result[cert.Name] = x
+	}
+	return result, nil
+}
+
+// verifyRenewedCertificates checks that certificates read before and after renewal are actually different,
+// and that the new certificates do not expire earlier than the old ones
+func verifyRenewedCertificates(n *status.Node, before, after map[string]*x509.Certificate) error {
+	n.Infof("verifying renewed certificates")
+	for name, old := range before {
+		renewed, ok := after[name]
+		if !ok {
+			return errors.Errorf("certificate %s does not exist anymore after renewal", name)
+		}
+		if renewed.SerialNumber.Cmp(old.SerialNumber) == 0 {
+			return errors.Errorf("certificate %s was not renewed", name)
+		}
+		if renewed.NotAfter.Before(old.NotAfter) {
+			return errors.Errorf("renewed certificate %s expires earlier than the original one (%s < %s)", name, renewed.NotAfter, old.NotAfter)
+		}
+		fmt.Printf("Certificate %s renewed, NotAfter: %s -> %s\n", name, old.NotAfter.UTC(), renewed.NotAfter.UTC())
+	}
+	fmt.Println()
+	return nil
+}
+
+// restartStaticPods forces the kubelet to restart static pods by temporarily moving
+// the static pod manifests out of the manifest folder; please note that this function
+// does not wait for static pods to become ready after restart
+func restartStaticPods(c *status.Cluster, n *status.Node, pods []string, wait time.Duration) error {
+	for _, p := range pods {
+		n.Infof("restarting %s", p)
+		if err := stopStaticPods(c, n, []string{p}, wait); err != nil {
+			return err
+		}
+		if err := startStaticPods(n, []string{p}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// stopStaticPods stops static pods by moving the static pod manifests out of the manifest folder,
+// and waits for the static pods to be stopped
+func stopStaticPods(c *status.Cluster, n *status.Node, pods []string, wait time.Duration) error {
+	if err := n.Command(
+		"mkdir", "-p", staticPodsBackupDir,
+	).Silent().Run(); err != nil {
+		return errors.Wrapf(err, "failed to create %s", staticPodsBackupDir)
+	}
+
+	for _, p := range pods {
+		manifest := filepath.Join(manifestsDir, fmt.Sprintf("%s.yaml", p))
+		backup := filepath.Join(staticPodsBackupDir, fmt.Sprintf("%s.yaml", p))
+		if err := n.Command(
+			"mv", manifest, backup,
+		).RunWithEcho(); err != nil {
+			return errors.Wrapf(err, "failed to move %s", manifest)
+		}
+
+		if err := waitStaticPodStopped(c, n, p, wait); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// startStaticPods starts static pods previously stopped with stopStaticPods, by moving
+// the static pod manifests back to the manifest folder
+func startStaticPods(n *status.Node, pods []string) error {
+	for _, p := range pods {
+		manifest := filepath.Join(manifestsDir, fmt.Sprintf("%s.yaml", p))
+		backup := filepath.Join(staticPodsBackupDir, fmt.Sprintf("%s.yaml", p))
+		if err := n.Command(
+			"mv", backup, manifest,
+		).RunWithEcho(); err != nil {
+			return errors.Wrapf(err, "failed to restore %s", manifest)
+		}
+	}
+
+	return nil
+}
+
+func contains(l []string, s string) bool {
+	for _, x := range l {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
@@ -18,6 +18,7 @@ package actions
 
 import (
 	"fmt"
+	"net"
 	"strings"
 
 	"github.com/pkg/errors"
@@ -25,6 +26,7 @@ import (
 
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/cni"
 	"k8s.io/kubeadm/kinder/pkg/constants"
 	"k8s.io/kubeadm/kinder/pkg/cri"
 	"k8s.io/kubeadm/kinder/pkg/kubeadm"
@@ -92,8 +94,8 @@ func KubeadmConfig(c *status.Cluster, kubeDNS bool, copyCertsMode CopyCertsMode,
 		APIBindPort:          constants.APIServerPort,
 		APIServerAddress:     controlPlaneIP,
 		Token:                constants.Token,
-		PodSubnet:            "192.168.0.0/16", // default for calico
-		ServiceSubnet:        "",               // let kubeadm apply default
+		PodSubnet:            cni.PodSubnet(cni.Resolve(c.Settings.CNI, cp1.MustKubeVersion())),
+		ServiceSubnet:        "", // let kubeadm apply default
 		ControlPlane:         true,
 		IPv6:                 c.Settings.IPFamily == status.IPv6Family,
 	}
@@ -116,9 +118,14 @@ func KubeadmConfig(c *status.Cluster, kubeDNS bool, copyCertsMode CopyCertsMode,
 }
 
 // getControlPlaneAddress return the join address that is the control plane endpoint in case the cluster has
-// an external load balancer in front of the control-plane nodes, otherwise the address of the
+// an external load balancer in front of the control-plane nodes or a control plane VIP, otherwise the address of the
 // bootstrap control plane node.
 func getControlPlaneAddress(c *status.Cluster) (string, string, int, error) {
+	// get the control plane endpoint, in case the cluster has a VIP managed by control-plane nodes
+	if c.Settings.ControlPlaneVIP != "" {
+		return c.Settings.ControlPlaneVIP, "", constants.APIServerPort, nil
+	}
+
 	// get the control plane endpoint, in case the cluster has an external load balancer in
 	// front of the control-plane nodes
 	if c.ExternalLoadBalancer() != nil {
@@ -269,20 +276,36 @@ func getKubeadmConfig(c *status.Cluster, n *status.Node, data kubeadm.ConfigData
 		}
 	}
 
-	// if the cluster is using an external etcd node, add patches for configuring access
+	// if the cluster is using external etcd nodes, add patches for configuring access
 	// to external etcd cluster
 	if c.ExternalEtcd() != nil {
-		externalEtcdIP, externalEtcdIPV6, err := c.ExternalEtcd().IP()
+		// TLS secured external etcd members are labeled at creation time, while the insecure, single node,
+		// external etcd is not
+		secure, err := c.ExternalEtcd().IsSecureExternalEtcd()
 		if err != nil {
-			return "", errors.Wrapf(err, "failed to get IP for node: %s", c.ExternalEtcd().Name())
+			return "", err
 		}
+		scheme := "http"
+		if secure {
+			scheme = "https"
+		}
+
+		endpoints := []string{}
+		for _, n := range c.ExternalEtcds() {
+			externalEtcdIP, externalEtcdIPV6, err := n.IP()
+			if err != nil {
+				return "", errors.Wrapf(err, "failed to get IP for node: %s", n.Name())
+			}
+
+			// configure the right protocol addresses
+			if c.Settings.IPFamily == status.IPv6Family {
+				externalEtcdIP = externalEtcdIPV6
+			}
 
-		// configure the right protocol addresses
-		if c.Settings.IPFamily == status.IPv6Family {
-			externalEtcdIP = externalEtcdIPV6
+			endpoints = append(endpoints, fmt.Sprintf("%s://%s", scheme, net.JoinHostPort(externalEtcdIP, "2379")))
 		}
 
-		externalEtcdPatch, err := kubeadm.GetExternalEtcdPatch(kubeadmVersion, externalEtcdIP)
+		externalEtcdPatch, err := kubeadm.GetExternalEtcdPatch(kubeadmVersion, endpoints, secure)
 		if err != nil {
 			return "", err
 		}
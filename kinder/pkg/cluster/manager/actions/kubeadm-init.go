@@ -31,8 +31,8 @@ import (
 	"github.com/pkg/errors"
 
 	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/cni"
 	"k8s.io/kubeadm/kinder/pkg/constants"
-	"k8s.io/kubeadm/kinder/pkg/data"
 )
 
 // KubeadmInit executes the kubeadm init workflow including also post init task
@@ -70,6 +70,12 @@ func KubeadmInit(c *status.Cluster, usePhases, kubeDNS bool, copyCertsMode CopyC
 		return err
 	}
 
+	// prepares the keepalived static pod for the control plane VIP, if any
+	if err := ControlPlaneVIP(c, cp1); err != nil {
+		return err
+	}
+	ignorePreflightErrors = ignoreManifestsDirPreflightError(c, ignorePreflightErrors)
+
 	// execs the kubeadm init workflow
 	if usePhases {
 		err = kubeadmInitWithPhases(cp1, copyCertsMode, patchesDir, ignorePreflightErrors, vLevel)
@@ -80,6 +86,14 @@ func KubeadmInit(c *status.Cluster, usePhases, kubeDNS bool, copyCertsMode CopyC
 		return err
 	}
 
+	// if the load balancer is verifying control plane nodes, updates the loadbalancer config
+	// now that the cluster CA exists
+	if c.Settings.LoadBalancerVerifyCA {
+		if err := LoadBalancer(c, cp1); err != nil {
+			return err
+		}
+	}
+
 	// completes post init task by installing the CNI network plugin
 	if err := postInit(c, wait); err != nil {
 		return err
@@ -219,28 +233,7 @@ func postInit(c *status.Cluster, wait time.Duration) error {
 		return err
 	}
 
-	// Calico requires net.ipv4.conf.all.rp_filter to be set to 0 or 1.
-	// If you require loose RPF and you are not concerned about spoofing, this check can be disabled by setting the IgnoreLooseRPF configuration parameter to 'true'.
-	for _, cp := range c.K8sNodes() {
-		if err := cp.Command(
-			"sysctl", "-w", "net.ipv4.conf.all.rp_filter=1",
-		).Silent().Run(); err != nil {
-			return err
-		}
-	}
-
-	// Apply a CNI plugin using a hardcoded manifest
-	cmd := cp1.Command("kubectl", "apply", "--kubeconfig=/etc/kubernetes/admin.conf", "-f", "-")
-	cp1.Infof("applying Calico version 3.8.2")
-	cmd.Stdin(strings.NewReader(data.CalicoCNI3_8_2))
-	if err := cmd.RunWithEcho(); err != nil {
-		return err
-	}
-
-	// Fix calico as per https://alexbrand.dev/post/creating-a-kind-cluster-with-calico-networking/
-	if err := cp1.Command(
-		"kubectl", "--kubeconfig=/etc/kubernetes/admin.conf", "-n=kube-system", "set", "env", "daemonset/calico-node", "FELIX_IGNORELOOSERPF=true",
-	).RunWithEcho(); err != nil {
+	if err := installCNI(c, wait); err != nil {
 		return err
 	}
 
@@ -272,6 +265,87 @@ func postInit(c *status.Cluster, wait time.Duration) error {
 	return nil
 }
 
+// installCNI installs the CNI plugin selected at create time, using an embedded manifest compatible with
+// the Kubernetes version or a manifest file from the host, and waits for the CNI plugin DaemonSet to be ready
+func installCNI(c *status.Cluster, wait time.Duration) error {
+	cp1 := c.BootstrapControlPlane()
+
+	name := cni.Resolve(c.Settings.CNI, cp1.MustKubeVersion())
+
+	if name == cni.None {
+		cp1.Infof("skipping CNI plugin installation; nodes are not expected to become Ready")
+		return nil
+	}
+
+	if path, ok := cni.ManifestFile(name); ok {
+		manifest, err := ioutil.ReadFile(path)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read CNI manifest %s", path)
+		}
+
+		// the CNI plugin DaemonSets are detected from the manifest, so it is possible to wait for them
+		// without requiring the user to provide their names
+		workloads, err := parseWorkloads(manifest)
+		if err != nil {
+			return errors.Wrapf(err, "failed to parse CNI manifest %s", path)
+		}
+		daemonSets := []workload{}
+		for _, w := range workloads {
+			if w.Kind == "DaemonSet" {
+				daemonSets = append(daemonSets, w)
+			}
+		}
+		if len(daemonSets) == 0 {
+			return errors.Errorf("CNI manifest %s does not define any DaemonSet", path)
+		}
+
+		cp1.Infof("applying CNI manifest %s", path)
+		cmd := cp1.Command("kubectl", "apply", "--kubeconfig=/etc/kubernetes/admin.conf", "-f", "-")
+		cmd.Stdin(bytes.NewReader(manifest))
+		if err := cmd.RunWithEcho(); err != nil {
+			return err
+		}
+
+		return waitWorkloadsReady(c, cp1, daemonSets, wait)
+	}
+
+	plugin, err := cni.SelectPlugin(name, cp1.MustKubeVersion())
+	if err != nil {
+		return err
+	}
+
+	if plugin.Name == cni.Calico {
+		// Calico requires net.ipv4.conf.all.rp_filter to be set to 0 or 1.
+		// If you require loose RPF and you are not concerned about spoofing, this check can be disabled by setting the IgnoreLooseRPF configuration parameter to 'true'.
+		for _, cp := range c.K8sNodes() {
+			if err := cp.Command(
+				"sysctl", "-w", "net.ipv4.conf.all.rp_filter=1",
+			).Silent().Run(); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Apply a CNI plugin using an embedded manifest
+	cmd := cp1.Command("kubectl", "apply", "--kubeconfig=/etc/kubernetes/admin.conf", "-f", "-")
+	cp1.Infof("applying %s version %s", plugin.Name, plugin.Version)
+	cmd.Stdin(strings.NewReader(plugin.Manifest))
+	if err := cmd.RunWithEcho(); err != nil {
+		return err
+	}
+
+	if plugin.Name == cni.Calico {
+		// Fix calico as per https://alexbrand.dev/post/creating-a-kind-cluster-with-calico-networking/
+		if err := cp1.Command(
+			"kubectl", "--kubeconfig=/etc/kubernetes/admin.conf", "-n=kube-system", "set", "env", "daemonset/calico-node", "FELIX_IGNORELOOSERPF=true",
+		).RunWithEcho(); err != nil {
+			return err
+		}
+	}
+
+	return waitWorkloadsReady(c, cp1, []workload{{Kind: "DaemonSet", Namespace: "kube-system", Name: plugin.DaemonSet}}, wait)
+}
+
 // copyKubeConfigToHost copies the admin.conf file to the host in order to make the cluster
 // usable with kubectl.
 // the kubeconfig file created by kubeadm internally to the node must be modified in order to use
@@ -279,6 +353,9 @@ func postInit(c *status.Cluster, wait time.Duration) error {
 func copyKubeConfigToHost(c *status.Cluster) error {
 	c.BootstrapControlPlane().Infof("copying the admin.conf file to the host")
 
+	// the admin.conf file changed, so the client used by actions must be created again
+	forgetKubeClient(c)
+
 	hostPort, err := getAPIServerPort(c)
 	if err != nil {
 		return errors.Wrap(err, "failed to get kubeconfig from node")
@@ -293,6 +370,11 @@ func copyKubeConfigToHost(c *status.Cluster) error {
 
 // getAPIServerPort returns the port on the host on which the APIServer is exposed
 func getAPIServerPort(c *status.Cluster) (int32, error) {
+	// select the control plane VIP first; nb. the VIP is reachable from the host on the API server port
+	if c.Settings.ControlPlaneVIP != "" {
+		return constants.APIServerPort, nil
+	}
+
 	// select the external loadbalancer first
 	if c.ExternalLoadBalancer() != nil {
 		return c.ExternalLoadBalancer().Ports(constants.ControlPlanePort)
@@ -312,11 +394,31 @@ var serverAddressRE = regexp.MustCompile(`^(\s+server:) https://.*:\d+$`)
 // this should only be called on a control plane node
 // While copying to the host machine the control plane address
 // is replaced with local host and the control plane port with
-// a randomly generated port reserved during node creation.
+// a randomly generated port reserved during node creation;
+// if the cluster uses a control plane VIP, the VIP is used instead.
 func writeKubeConfig(c *status.Cluster, hostPort int32) error {
+	data, err := hostKubeConfig(c, hostPort)
+	if err != nil {
+		return err
+	}
+
+	// create the directory to contain the KUBECONFIG file.
+	// 0755 is taken from client-go's config handling logic: https://github.com/kubernetes/client-go/blob/5d107d4ebc00ee0ea606ad7e39fd6ce4b0d9bf9e/tools/clientcmd/loader.go#L412
+	dest := c.KubeConfigPath()
+	err = os.MkdirAll(filepath.Dir(dest), 0755)
+	if err != nil {
+		return errors.Wrap(err, "failed to create kubeconfig output directory")
+	}
+
+	return ioutil.WriteFile(dest, data, 0600)
+}
+
+// hostKubeConfig reads the admin.conf file from the bootstrap control plane node and returns it with
+// the server address replaced by the address reachable from the host
+func hostKubeConfig(c *status.Cluster, hostPort int32) ([]byte, error) {
 	lines, err := c.BootstrapControlPlane().Command("cat", "/etc/kubernetes/admin.conf").Silent().RunAndCapture()
 	if err != nil {
-		return errors.Wrap(err, "failed to get kubeconfig from node")
+		return nil, errors.Wrap(err, "failed to get kubeconfig from node")
 	}
 
 	// fix the config file, swapping out the server for the forwarded localhost:port
@@ -324,22 +426,17 @@ func writeKubeConfig(c *status.Cluster, hostPort int32) error {
 	for _, line := range lines {
 		match := serverAddressRE.FindStringSubmatch(line)
 		if len(match) > 1 {
-			addr := net.JoinHostPort("localhost", fmt.Sprintf("%d", hostPort))
+			host := "localhost"
+			if c.Settings.ControlPlaneVIP != "" {
+				host = c.Settings.ControlPlaneVIP
+			}
+			addr := net.JoinHostPort(host, fmt.Sprintf("%d", hostPort))
 			line = fmt.Sprintf("%s https://%s", match[1], addr)
 		}
 		buff.WriteString(line)
 		buff.WriteString("\n")
 	}
-
-	// create the directory to contain the KUBECONFIG file.
-	// 0755 is taken from client-go's config handling logic: https://github.com/kubernetes/client-go/blob/5d107d4ebc00ee0ea606ad7e39fd6ce4b0d9bf9e/tools/clientcmd/loader.go#L412
-	dest := c.KubeConfigPath()
-	err = os.MkdirAll(filepath.Dir(dest), 0755)
-	if err != nil {
-		return errors.Wrap(err, "failed to create kubeconfig output directory")
-	}
-
-	return ioutil.WriteFile(dest, buff.Bytes(), 0600)
+	return buff.Bytes(), nil
 }
 
 func copyPatchesToNode(n *status.Node, dir string) error {
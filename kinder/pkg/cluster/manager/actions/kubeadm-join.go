@@ -76,11 +76,16 @@ func joinControlPlanes(c *status.Cluster, usePhases bool, copyCertsMode CopyCert
 			return err
 		}
 
+		// prepares the keepalived static pod for the control plane VIP, if any
+		if err := ControlPlaneVIP(c, cp2); err != nil {
+			return err
+		}
+
 		// executes the kubeadm join control-plane workflow
 		if usePhases {
-			err = kubeadmJoinControlPlaneWithPhases(cp2, patchesDir, ignorePreflightErrors, vLevel)
+			err = kubeadmJoinControlPlaneWithPhases(cp2, patchesDir, ignoreManifestsDirPreflightError(c, ignorePreflightErrors), vLevel)
 		} else {
-			err = kubeadmJoinControlPlane(cp2, patchesDir, ignorePreflightErrors, vLevel)
+			err = kubeadmJoinControlPlane(cp2, patchesDir, ignoreManifestsDirPreflightError(c, ignorePreflightErrors), vLevel)
 		}
 		if err != nil {
 			return err
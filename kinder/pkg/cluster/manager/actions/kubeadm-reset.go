@@ -18,19 +18,172 @@ package actions
 
 import (
 	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
 
 	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/cri"
 )
 
-// KubeadmReset executes the kubeadm reset workflow
-func KubeadmReset(c *status.Cluster, vLevel int) error {
+// resetCheck defines a check for something that should not exist on a node after kubeadm reset
+type resetCheck struct {
+	// description of what is checked
+	description string
+	// command is a bash command returning the list of leftovers, if any
+	command string
+	// notCleanedByKubeadm is set for the checks about something that kubeadm documents as not cleaned
+	// by kubeadm reset; leftovers for those checks are reported as failures too, with a hint for the user
+	notCleanedByKubeadm bool
+}
+
+// resetChecks defines the list of checks to be executed for verifying that kubeadm reset cleaned up a node
+var resetChecks = []resetCheck{
+	{
+		description: "static pod manifests",
+		command:     "find /etc/kubernetes/manifests -mindepth 1 2> /dev/null",
+	},
+	{
+		description: "kubeconfig files",
+		command:     "find /etc/kubernetes -maxdepth 1 -name '*.conf' 2> /dev/null",
+	},
+	{
+		description: "certificates",
+		command:     "find /etc/kubernetes/pki -mindepth 1 2> /dev/null",
+	},
+	{
+		description: "etcd data",
+		command:     "find /var/lib/etcd -mindepth 1 2> /dev/null",
+	},
+	{
+		description:         "CNI configuration",
+		command:             "find /etc/cni/net.d -mindepth 1 2> /dev/null",
+		notCleanedByKubeadm: true,
+	},
+	{
+		description:         "iptables rules",
+		command:             "iptables-save 2> /dev/null | grep -E '^-A (KUBE-|cali-)'",
+		notCleanedByKubeadm: true,
+	},
+}
+
+// KubeadmReset executes the kubeadm reset workflow; if requested, it verifies that
+// kubeadm reset cleaned up the node and that the etcd member hosted on control-plane nodes
+// is removed from the etcd cluster.
+func KubeadmReset(c *status.Cluster, verify bool, wait time.Duration, vLevel int) error {
 	//TODO: implements kubeadm reset with phases
-	for _, n := range c.K8sNodes().EligibleForActions() {
+	nodes := c.K8sNodes().EligibleForActions()
+	leftovers := []string{}
+	for i, n := range nodes {
+		// if verification of etcd member removal is required, detect a control-plane node not yet reset
+		// to be used for checking the etcd member list
+		var observer *status.Node
+		if verify && n.IsControlPlane() && c.ExternalEtcd() == nil {
+			observer = etcdObserver(c, n, nodes[i+1:])
+		}
+
 		if err := n.Command(
 			"kubeadm", "reset", "--force", fmt.Sprintf("--v=%d", vLevel),
 		).RunWithEcho(); err != nil {
 			return err
 		}
+
+		if !verify || n.IsDryRun() {
+			continue
+		}
+
+		nodeLeftovers, err := verifyReset(n)
+		if err != nil {
+			return err
+		}
+		leftovers = append(leftovers, nodeLeftovers...)
+
+		if observer != nil {
+			if err := waitEtcdMemberRemoved(c, observer, n, wait); err != nil {
+				leftovers = append(leftovers, fmt.Sprintf("%s: the etcd member was not removed from the etcd cluster", n.Name()))
+			}
+		}
+	}
+
+	if len(leftovers) > 0 {
+		return errors.Errorf("kubeadm reset did not clean up nodes properly:\n%s", strings.Join(leftovers, "\n"))
 	}
 	return nil
 }
+
+// etcdObserver returns a control-plane node that will keep hosting an etcd member after the
+// given node is reset, if any
+func etcdObserver(c *status.Cluster, n *status.Node, nextNodes status.NodeList) *status.Node {
+	eligible := c.ControlPlanes().EligibleForActions()
+	for _, cp := range c.ControlPlanes() {
+		if cp.Name() == n.Name() {
+			continue
+		}
+		// a control-plane node is a valid observer if it is not going to be reset or if it will be reset afterwards
+		if !isInNodeList(cp, eligible) || isInNodeList(cp, nextNodes) {
+			return cp
+		}
+	}
+	return nil
+}
+
+func isInNodeList(n *status.Node, l status.NodeList) bool {
+	for _, x := range l {
+		if x.Name() == n.Name() {
+			return true
+		}
+	}
+	return false
+}
+
+// verifyReset checks what is left on a node after kubeadm reset; leftovers are returned as
+// a list of failures, including leftovers for things that kubeadm documents as not cleaned
+func verifyReset(n *status.Node) ([]string, error) {
+	n.Infof("verify kubeadm reset cleaned up the node")
+
+	leftovers := []string{}
+	for _, check := range resetChecks {
+		lines, err := n.Command(
+			"bash", "-c", check.command,
+		).Silent().RunAndCapture()
+		// NB. find and grep return an error if no file/no match is found, so the check is based on the output only
+		if err != nil && len(lines) > 0 {
+			return nil, errors.Wrapf(err, "failed to check %s on node %s", check.description, n.Name())
+		}
+
+		if len(lines) == 0 {
+			fmt.Printf("%s: cleaned up\n", check.description)
+			continue
+		}
+
+		fmt.Printf("%s: %d leftovers\n%s\n", check.description, len(lines), strings.Join(lines, "\n"))
+		leftover := fmt.Sprintf("%s: %s were not cleaned up (%s)", n.Name(), check.description, strings.Join(lines, ", "))
+		if check.notCleanedByKubeadm {
+			leftover += "; kubeadm reset does not clean this up, so the user is expected to do it"
+		}
+		leftovers = append(leftovers, leftover)
+	}
+
+	// checks running containers using the CRI tools
+	nodeCRI, err := n.CRI()
+	if err != nil {
+		return nil, err
+	}
+	actionHelper, err := cri.NewActionHelper(nodeCRI)
+	if err != nil {
+		return nil, err
+	}
+	containers, err := actionHelper.GetRunningContainers(n, "")
+	if err != nil {
+		return nil, err
+	}
+	if len(containers) == 0 {
+		fmt.Println("running containers: cleaned up")
+	} else {
+		fmt.Printf("running containers: %d leftovers\n%s\n", len(containers), strings.Join(containers, "\n"))
+		leftovers = append(leftovers, fmt.Sprintf("%s: %d containers are still running", n.Name(), len(containers)))
+	}
+
+	return leftovers, nil
+}
@@ -0,0 +1,221 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	K8sVersion "k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/constants"
+)
+
+const (
+	// rollbackPatchesDir defines the path on the node where the patches used for breaking the upgrade are stored
+	rollbackPatchesDir = "/kinder/rollback-patches"
+
+	// kubeadmBackupManifestsDirPattern defines the pattern for the folders where kubeadm stores
+	// the static pod manifest backups during upgrades
+	kubeadmBackupManifestsDirPattern = "/etc/kubernetes/tmp/kubeadm-backup-manifests-*"
+
+	// manifestsDir defines the path of the static pod manifests folder
+	manifestsDir = "/etc/kubernetes/manifests"
+)
+
+// KnownUpgradeFailComponents returns the list of control-plane components that can be used
+// for interrupting the upgrade with the kubeadm-upgrade-rollback action, in the same order
+// kubeadm upgrades them
+func KnownUpgradeFailComponents() []string {
+	return []string{
+		"etcd",
+		"kube-apiserver",
+		"kube-controller-manager",
+		"kube-scheduler",
+	}
+}
+
+// ValidateUpgradeFailComponent validates the component used for interrupting the upgrade
+func ValidateUpgradeFailComponent(component string) error {
+	for _, c := range KnownUpgradeFailComponents() {
+		if c == component {
+			return nil
+		}
+	}
+	return errors.Errorf("invalid upgrade fail component. Use one of %s", KnownUpgradeFailComponents())
+}
+
+// KubeadmUpgradeRollback executes kubeadm upgrade apply on the bootstrap control-plane node forcing the
+// upgrade to fail after the static pod manifest for the given component is rewritten; then it verifies
+// that kubeadm restored all the static pod manifests from the backups in /etc/kubernetes/tmp and
+// that the control-plane is healthy and still running the original version.
+//
+// The failure is injected using a kubeadm patch that sets an image that does not exist for the
+// selected component, so kubeadm will hit the upgrade timeout waiting for the component to start;
+// for this reason this action requires the target kubeadm version to support patches (>= v1.19).
+//
+// Please note that the kubeadm binary on the bootstrap control-plane node is left upgraded, so it is possible to
+// run kubeadm-upgrade afterwards.
+func KubeadmUpgradeRollback(c *status.Cluster, upgradeVersion *K8sVersion.Version, failComponent, patchesDir string, wait time.Duration, vLevel int) (err error) {
+	if upgradeVersion == nil {
+		return errors.New("kubeadm-upgrade-rollback actions requires the --upgrade-version parameter to be set")
+	}
+	if upgradeVersion.LessThan(constants.V1_19) {
+		return errors.New("kubeadm-upgrade-rollback actions requires --upgrade-version v1.19 or greater")
+	}
+	if patchesDir != "" {
+		return errors.New("kubeadm-upgrade-rollback actions can't be used with --patches")
+	}
+	if err := ValidateUpgradeFailComponent(failComponent); err != nil {
+		return err
+	}
+
+	cp1 := c.BootstrapControlPlane()
+	currentVersion := cp1.MustKubeVersion()
+
+	preloadUpgradeImages(c, upgradeVersion)
+
+	if err := upgradeKubeadmBinary(cp1, upgradeVersion); err != nil {
+		return err
+	}
+
+	// gets the checksum of the static pod manifests before the upgrade, so it will be possible
+	// to check that kubeadm restores all of them when the upgrade fails
+	before, err := manifestChecksums(cp1, manifestsDir)
+	if err != nil {
+		return err
+	}
+
+	// the patches breaking the upgrade are removed also if the action fails, so they can't affect following upgrades
+	defer func() {
+		if cleanupErr := cp1.Command("rm", "-rf", rollbackPatchesDir).Silent().Run(); cleanupErr != nil && err == nil {
+			err = errors.Wrapf(cleanupErr, "failed to remove %s", rollbackPatchesDir)
+		}
+	}()
+	if err := writeRollbackPatch(cp1, failComponent); err != nil {
+		return err
+	}
+
+	cp1.Infof("kubeadm upgrade apply (expected to fail while upgrading %s)", failComponent)
+	err = cp1.Command(
+		"kubeadm", "upgrade", "apply", "-f", fmt.Sprintf("v%s", upgradeVersion), fmt.Sprintf("--v=%d", vLevel),
+		"--experimental-patches", rollbackPatchesDir,
+	).RunWithEcho()
+	if cp1.IsDryRun() {
+		return nil
+	}
+	if err == nil {
+		return errors.Errorf("kubeadm upgrade apply was expected to fail while upgrading %s, but it succeeded", failComponent)
+	}
+	fmt.Printf("kubeadm upgrade apply failed as expected: %v\n", err)
+
+	if err := verifyUpgradeRollback(cp1, failComponent, before); err != nil {
+		return err
+	}
+
+	return waitControlPlaneRolledBack(c, cp1, currentVersion, wait)
+}
+
+// writeRollbackPatch writes on the node a kubeadm patch that breaks the given component
+// by setting an image that does not exist
+func writeRollbackPatch(n *status.Node, component string) error {
+	if err := n.Command("mkdir", "-p", rollbackPatchesDir).Silent().Run(); err != nil {
+		return errors.Wrapf(err, "failed to create %s folder", rollbackPatchesDir)
+	}
+
+	patch := fmt.Sprintf(`spec:
+  containers:
+  - name: %[1]s
+    image: kinder.invalid/%[1]s:upgrade-rollback
+`, component)
+
+	return n.WriteFile(filepath.Join(rollbackPatchesDir, fmt.Sprintf("%s.yaml", component)), []byte(patch))
+}
+
+// verifyUpgradeRollback checks that kubeadm left a backup of the static pod manifests, including the
+// one for the component that failed, and that the manifests in /etc/kubernetes/manifests are the same
+// existing before the upgrade
+func verifyUpgradeRollback(n *status.Node, failComponent string, before map[string]string) error {
+	n.Infof("verify kubeadm rolled back static pod manifests")
+
+	lines, err := n.Command(
+		"bash", "-c", fmt.Sprintf("ls -1dt %s | head -1", kubeadmBackupManifestsDirPattern),
+	).Silent().RunAndCapture()
+	if err != nil || len(lines) != 1 {
+		return errors.Errorf("failed to find the kubeadm backup folder for static pod manifests matching %s", kubeadmBackupManifestsDirPattern)
+	}
+	backupDir := lines[0]
+	fmt.Printf("kubeadm backup folder for static pod manifests: %s\n", backupDir)
+
+	backup, err := manifestChecksums(n, backupDir)
+	if err != nil {
+		return err
+	}
+	if _, ok := backup[fmt.Sprintf("%s.yaml", failComponent)]; !ok {
+		return errors.Errorf("the kubeadm backup folder %s does not contains the manifest for %s", backupDir, failComponent)
+	}
+
+	after, err := manifestChecksums(n, manifestsDir)
+	if err != nil {
+		return err
+	}
+
+	var failures []string
+	for file, checksum := range before {
+		if after[file] != checksum {
+			failures = append(failures, fmt.Sprintf("%s was not restored to the original content", file))
+		}
+		if b, ok := backup[file]; ok && b != checksum {
+			failures = append(failures, fmt.Sprintf("%s in the backup folder does not match the original content", file))
+		}
+	}
+	for file := range after {
+		if _, ok := before[file]; !ok {
+			failures = append(failures, fmt.Sprintf("%s was not existing before the upgrade", file))
+		}
+	}
+	if len(failures) > 0 {
+		return errors.Errorf("kubeadm failed to rollback static pod manifests:\n%s", strings.Join(failures, "\n"))
+	}
+
+	fmt.Printf("All the %d static pod manifests are restored to the original content\n", len(before))
+	return nil
+}
+
+// manifestChecksums returns the sha256 checksum of all the static pod manifests in a folder
+func manifestChecksums(n *status.Node, dir string) (map[string]string, error) {
+	lines, err := n.Command(
+		"bash", "-c", fmt.Sprintf("sha256sum %s/*.yaml", dir),
+	).Silent().RunAndCapture()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get checksums for static pod manifests in %s", dir)
+	}
+
+	checksums := map[string]string{}
+	for _, l := range lines {
+		parts := strings.Fields(l)
+		if len(parts) != 2 {
+			continue
+		}
+		checksums[filepath.Base(parts[1])] = parts[0]
+	}
+	return checksums, nil
+}
@@ -77,6 +77,44 @@ func KubeadmUpgrade(c *status.Cluster, upgradeVersion *K8sVersion.Version, patch
 	return nil
 }
 
+// KubeadmUpgradeChain executes many consecutive kubeadm upgrade workflows, one for each version
+// in the upgrade path, and verifies the cluster reached the expected state after each hop.
+//
+// The implementation assumes that the kubeadm/kubelet/kubectl binaries and all the necessary images
+// for all the kubernetes versions in the upgrade path are available in the /kinder/upgrade/{version} folders.
+func KubeadmUpgradeChain(c *status.Cluster, upgradePath []*K8sVersion.Version, patchesDir string, wait time.Duration, vLevel int) error {
+	if len(upgradePath) == 0 {
+		return errors.New("kubeadm-upgrade chain requires the --upgrade-path parameter to be set")
+	}
+
+	for i, upgradeVersion := range upgradePath {
+		fmt.Printf("\nUpgrade hop %d of %d: upgrading to v%s\n", i+1, len(upgradePath), upgradeVersion)
+
+		if err := KubeadmUpgrade(c, upgradeVersion, patchesDir, wait, vLevel); err != nil {
+			return errors.Wrapf(err, "upgrade hop %d to v%s failed", i+1, upgradeVersion)
+		}
+
+		if err := verifyUpgradeHop(c, upgradeVersion, wait); err != nil {
+			return errors.Wrapf(err, "verification of upgrade hop %d to v%s failed", i+1, upgradeVersion)
+		}
+	}
+
+	return nil
+}
+
+// verifyUpgradeHop checks that all the nodes upgraded during an upgrade hop are running the expected
+// kubeadm binary, and that the cluster reports the expected Kubernetes version for all of them
+// before moving to the next hop
+func verifyUpgradeHop(c *status.Cluster, upgradeVersion *K8sVersion.Version, wait time.Duration) error {
+	for _, n := range c.K8sNodes().EligibleForActions() {
+		if err := waitNodeUpgraded(c, n, upgradeVersion, wait); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func preloadUpgradeImages(c *status.Cluster, upgradeVersion *K8sVersion.Version) {
 	srcFolder := filepath.Join("/kinder", "upgrade", fmt.Sprintf("v%s", upgradeVersion))
 
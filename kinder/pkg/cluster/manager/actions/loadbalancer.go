@@ -18,6 +18,7 @@ package actions
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
@@ -59,20 +60,34 @@ func LoadBalancer(c *status.Cluster, nodes ...*status.Node) error {
 		}
 	}
 
+	// gets the load balancer implementation
+	impl, err := loadbalancer.Get(c.Settings.LoadBalancer)
+	if err != nil {
+		return err
+	}
+
+	// if requested, copies the cluster CA on the load balancer node for verifying control plane nodes
+	caFile, err := loadBalancerCA(c, lb, impl)
+	if err != nil {
+		return err
+	}
+
 	// create loadbalancer config data
-	loadbalancerConfig, err := loadbalancer.Config(&loadbalancer.ConfigData{
+	loadbalancerConfig, err := impl.Config(&loadbalancer.ConfigData{
 		ControlPlanePort: constants.ControlPlanePort,
 		BackendServers:   backendServers,
 		IPv6:             ipv6,
+		CAFile:           caFile,
+		StatsPort:        impl.StatsPort,
 	})
 	if err != nil {
 		return errors.Wrap(err, "failed to generate loadbalancer config data")
 	}
 
 	// create loadbalancer config on the node
-	log.Debugf("Writing loadbalancer config on %s...", lb.Name())
+	log.Debugf("Writing %s loadbalancer config on %s...", impl.Name, lb.Name())
 
-	if err := lb.WriteFile(constants.LoadBalancerConfigPath, []byte(loadbalancerConfig)); err != nil {
+	if err := lb.WriteFile(impl.ConfigPath, []byte(loadbalancerConfig)); err != nil {
 		return errors.Wrap(err, "failed to copy loadbalancer config to node")
 	}
 
@@ -83,3 +98,86 @@ func LoadBalancer(c *status.Cluster, nodes ...*status.Node) error {
 
 	return nil
 }
+
+// loadBalancerCA copies the cluster CA from the bootstrap control plane to the load balancer node, if CA-verified
+// health checks are enabled, and returns the path of the CA file on the load balancer node.
+// If the cluster CA does not exist yet, e.g. before kubeadm init, an empty path is returned, and health checks
+// will be verified when the load balancer config is updated after kubeadm init.
+func loadBalancerCA(c *status.Cluster, lb *status.Node, impl *loadbalancer.Implementation) (string, error) {
+	if !c.Settings.LoadBalancerVerifyCA {
+		return "", nil
+	}
+	if !impl.SupportsCAVerification() {
+		return "", errors.Errorf("the %s load balancer does not support CA-verified health checks", impl.Name)
+	}
+
+	cp1 := c.BootstrapControlPlane()
+	if err := cp1.Command("test", "-f", constants.CACertPath).Silent().Run(); err != nil {
+		lb.Infof("Cluster CA does not exist yet, health checks against control plane nodes are not verified")
+		return "", nil
+	}
+
+	ca, err := cp1.ReadFile(constants.CACertPath)
+	if err != nil {
+		return "", err
+	}
+	if len(ca) == 0 && !cp1.IsDryRun() {
+		return "", errors.Errorf("%s on node %s is empty", constants.CACertPath, cp1.Name())
+	}
+
+	if err := lb.WriteFile(impl.CAPath, ca); err != nil {
+		return "", errors.Wrap(err, "failed to copy the cluster CA to the load balancer node")
+	}
+
+	return impl.CAPath, nil
+}
+
+// loadBalancerHasStats returns true if the cluster has an external load balancer exposing backend status;
+// the load balancer config is checked too, because configs written by older versions of kinder or by kind
+// don't have the stats frontend
+func loadBalancerHasStats(c *status.Cluster) bool {
+	lb := c.ExternalLoadBalancer()
+	if lb == nil {
+		return false
+	}
+
+	impl, err := loadbalancer.Get(c.Settings.LoadBalancer)
+	if err != nil || !impl.SupportsBackendStatus() {
+		return false
+	}
+
+	lines, err := lb.Command("cat", impl.ConfigPath).Silent().RunAndCapture()
+	if err != nil {
+		return false
+	}
+	config := strings.Join(lines, "\n")
+	return strings.Contains(config, "frontend stats") && strings.Contains(config, fmt.Sprintf("bind *:%d", impl.StatsPort))
+}
+
+// LoadBalancerBackends returns the status of the control plane nodes as seen by the external load balancer
+func LoadBalancerBackends(c *status.Cluster) ([]loadbalancer.BackendStatus, error) {
+	lb := c.ExternalLoadBalancer()
+	if lb == nil {
+		return nil, errors.Errorf("cluster %s does not have an external load balancer", c.Name())
+	}
+
+	impl, err := loadbalancer.Get(c.Settings.LoadBalancer)
+	if err != nil {
+		return nil, err
+	}
+	if !impl.SupportsBackendStatus() {
+		return nil, errors.Errorf("the %s load balancer does not support reporting backend status", impl.Name)
+	}
+
+	lines, err := lb.Command(
+		"wget", "-q", "-O", "-", impl.StatsURL(),
+	).Silent().RunAndCapture()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read stats from the load balancer %s", lb.Name())
+	}
+	if lb.IsDryRun() {
+		return []loadbalancer.BackendStatus{}, nil
+	}
+
+	return loadbalancer.ParseHAProxyStats(lines)
+}
@@ -0,0 +1,421 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/kubeadm/kinder/pkg/certs"
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/kubeadm"
+)
+
+const (
+	// negativeTestConfigPath defines the path of the kubeadm config used by negative-test scenarios
+	negativeTestConfigPath = "/kinder/negative-test.yaml"
+
+	// negativeTestDiscoveryTimeout defines the discovery timeout for kubeadm join in negative-test scenarios;
+	// it is shorter than the kubeadm default in order to fail fast when the discovery is expected to fail
+	negativeTestDiscoveryTimeout = 30 * time.Second
+
+	// invalidToken defines a well formed bootstrap token that does not exist in the cluster
+	invalidToken = "kinder.0123456789kinder"
+
+	// expiredTokenTTL defines the TTL of the token created by the join-expired-token scenario
+	expiredTokenTTL = 1 * time.Second
+
+	// nodeFingerprintCommand defines a bash command returning the checksum of the files written by kubeadm on a node
+	nodeFingerprintCommand = "find /etc/kubernetes /var/lib/kubelet/config.yaml /var/lib/kubelet/kubeadm-flags.env -type f -exec sha256sum {} + 2> /dev/null || true"
+)
+
+// wrongCACertHash defines a CA cert hash that does not match the cluster CA
+var wrongCACertHash = "sha256:" + strings.Repeat("0", 64)
+
+// negativeContext holds the settings of a negative-test scenario
+type negativeContext struct {
+	c                     *status.Cluster
+	ignorePreflightErrors string
+	wait                  time.Duration
+	vLevel                int
+}
+
+// negativeScenario defines a named scenario executed by the negative-test action
+type negativeScenario struct {
+	name        string
+	description string
+	run         func(x *negativeContext) error
+}
+
+// negativeScenarios defines the list of scenarios supported by the negative-test action
+var negativeScenarios = []negativeScenario{
+	{name: "join-invalid-token", description: "join a worker node using a bootstrap token that does not exist", run: negativeJoinInvalidToken},
+	{name: "join-expired-token", description: "join a worker node using an expired bootstrap token", run: negativeJoinExpiredToken},
+	{name: "join-wrong-ca-hash", description: "join a worker node using a CA cert hash that does not match the cluster CA", run: negativeJoinWrongCAHash},
+	{name: "init-bad-config", description: "init using a config with an invalid pod subnet", run: negativeInitBadConfig},
+	{name: "upgrade-unsupported-skew", description: "upgrade to a version two minors ahead of kubeadm", run: negativeUpgradeUnsupportedSkew},
+}
+
+// KnownNegativeScenarios returns the list of negative-test scenarios
+func KnownNegativeScenarios() []string {
+	names := []string{}
+	for _, s := range negativeScenarios {
+		names = append(names, s.name)
+	}
+	return names
+}
+
+// NegativeTest executes a kubeadm command that is expected to fail according to the selected scenario, and verifies
+// that kubeadm fails with the expected error and that it leaves the node clean, that is files in /etc/kubernetes
+// and the kubelet config files are not changed; join scenarios also verify that the node is not registered in the cluster.
+// Join scenarios are executed on the last worker node eligible for actions that is not yet joined, while other scenarios
+// are executed on the bootstrap control-plane node.
+func NegativeTest(c *status.Cluster, args []string, ignorePreflightErrors string, wait time.Duration, vLevel int) error {
+	if len(args) != 1 {
+		return errors.Errorf("negative-test requires a scenario. Use one of %s", KnownNegativeScenarios())
+	}
+
+	x := &negativeContext{
+		c:                     c,
+		ignorePreflightErrors: ignorePreflightErrors,
+		wait:                  wait,
+		vLevel:                vLevel,
+	}
+	for _, s := range negativeScenarios {
+		if s.name == args[0] {
+			fmt.Printf("Executing negative-test scenario %s: %s\n", s.name, s.description)
+			return s.run(x)
+		}
+	}
+	return errors.Errorf("invalid negative-test scenario %q. Use one of %s", args[0], KnownNegativeScenarios())
+}
+
+// negativeJoinInvalidToken executes kubeadm join using a bootstrap token that does not exist in the cluster
+func negativeJoinInvalidToken(x *negativeContext) error {
+	caCertHash, err := clusterCACertHash(x.c)
+	if err != nil {
+		return err
+	}
+	return negativeJoin(x, invalidToken, caCertHash, invalidTokenError(invalidToken))
+}
+
+// negativeJoinExpiredToken creates a bootstrap token with a short TTL, waits for the token to expire and to be deleted
+// by the token cleaner, then executes kubeadm join using this token
+func negativeJoinExpiredToken(x *negativeContext) error {
+	caCertHash, err := clusterCACertHash(x.c)
+	if err != nil {
+		return err
+	}
+
+	token, err := createBootstrapToken(x.c, expiredTokenTTL)
+	if err != nil {
+		return err
+	}
+	if err := waitBootstrapTokenDeleted(x.c, x.c.BootstrapControlPlane(), token, x.wait); err != nil {
+		return err
+	}
+	return negativeJoin(x, token, caCertHash, invalidTokenError(token))
+}
+
+// negativeJoinWrongCAHash creates a valid bootstrap token, then executes kubeadm join using this token and a CA cert hash
+// that does not match the cluster CA; the token is deleted afterwards
+func negativeJoinWrongCAHash(x *negativeContext) (err error) {
+	token, err := createBootstrapToken(x.c, DefaultTokenTTL)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if deleteErr := deleteBootstrapToken(x.c, token); deleteErr != nil && err == nil {
+			err = deleteErr
+		}
+	}()
+	if err := waitBootstrapTokenSigned(x.c, x.c.BootstrapControlPlane(), token, x.wait); err != nil {
+		return err
+	}
+	return negativeJoin(x, token, wrongCACertHash, literalError("none of the public keys"))
+}
+
+// negativeInitBadConfig executes kubeadm init in dry-run mode on the bootstrap control-plane node using a config
+// with an invalid pod subnet
+func negativeInitBadConfig(x *negativeContext) error {
+	cp1 := x.c.BootstrapControlPlane()
+
+	// NB. the Kubernetes version is set for avoiding kubeadm to resolve the default version label from the internet
+	kubeVersion, err := cp1.KubeVersion()
+	if err != nil {
+		return err
+	}
+	apiVersion, err := kubeadm.GetConfigAPIVersion(cp1.MustKubeadmVersion())
+	if err != nil {
+		return err
+	}
+	config := fmt.Sprintf(`apiVersion: %s
+kind: ClusterConfiguration
+kubernetesVersion: %s
+networking:
+  podSubnet: "kinder-invalid-subnet"
+`, apiVersion, kubeVersion)
+	if err := cp1.WriteFile(negativeTestConfigPath, []byte(config)); err != nil {
+		return errors.Wrapf(err, "failed to write the kubeadm config to node %s", cp1.Name())
+	}
+
+	return expectKubeadmFailure(cp1, literalError("networking.podSubnet: Invalid value"),
+		"init",
+		fmt.Sprintf("--config=%s", negativeTestConfigPath),
+		fmt.Sprintf("--ignore-preflight-errors=%s", x.ignorePreflightErrors),
+		"--dry-run",
+		fmt.Sprintf("--v=%d", x.vLevel),
+	)
+}
+
+// negativeUpgradeUnsupportedSkew executes kubeadm upgrade apply in dry-run mode on the bootstrap control-plane node
+// using a target version two minors ahead of kubeadm, without forcing the upgrade
+func negativeUpgradeUnsupportedSkew(x *negativeContext) error {
+	cp1 := x.c.BootstrapControlPlane()
+
+	kubeadmVersion, err := cp1.KubeadmVersion()
+	if err != nil {
+		return err
+	}
+	upgradeVersion := fmt.Sprintf("v%d.%d.0", kubeadmVersion.Major(), kubeadmVersion.Minor()+2)
+
+	return expectKubeadmFailure(cp1, literalError("Specified version to upgrade to"),
+		"upgrade", "apply", upgradeVersion,
+		"--dry-run",
+		fmt.Sprintf("--v=%d", x.vLevel),
+	)
+}
+
+// negativeJoin executes kubeadm join on a worker node not yet joined using the given token and CA cert hash, and verifies
+// that kubeadm fails with the expected error and that the node is not registered in the cluster
+func negativeJoin(x *negativeContext, token, caCertHash string, expectedError *regexp.Regexp) error {
+	n, err := negativeJoinTarget(x.c)
+	if err != nil {
+		return err
+	}
+
+	criSocket, err := negativeCRISocket(n)
+	if err != nil {
+		return err
+	}
+
+	endpoint, endpointIPv6, port, err := getControlPlaneAddress(x.c)
+	if err != nil {
+		return err
+	}
+	if x.c.Settings.IPFamily == status.IPv6Family {
+		endpoint = endpointIPv6
+	}
+
+	config := negativeJoinConfig(criSocket, net.JoinHostPort(endpoint, fmt.Sprintf("%d", port)), token, caCertHash, negativeTestDiscoveryTimeout)
+	if err := n.WriteFile(negativeTestConfigPath, []byte(config)); err != nil {
+		return errors.Wrapf(err, "failed to write the kubeadm config to node %s", n.Name())
+	}
+
+	if err := expectKubeadmFailure(n, expectedError,
+		"join",
+		fmt.Sprintf("--config=%s", negativeTestConfigPath),
+		fmt.Sprintf("--ignore-preflight-errors=%s", x.ignorePreflightErrors),
+		fmt.Sprintf("--v=%d", x.vLevel),
+	); err != nil {
+		return err
+	}
+
+	if n.IsDryRun() {
+		return nil
+	}
+	client, err := kubeClient(x.c)
+	if err != nil {
+		return err
+	}
+	if _, err := client.CoreV1().Nodes().Get(n.Name(), metav1.GetOptions{}); !apierrors.IsNotFound(err) {
+		return errors.Errorf("node %s is registered in the cluster after a failed kubeadm join", n.Name())
+	}
+	fmt.Printf("node %s is not registered in the cluster\n", n.Name())
+	return nil
+}
+
+// negativeJoinTarget returns the last worker node eligible for actions that is not yet joined
+func negativeJoinTarget(c *status.Cluster) (*status.Node, error) {
+	workers := c.Workers().EligibleForActions()
+	for i := len(workers) - 1; i >= 0; i-- {
+		n := workers[i]
+		if n.IsDryRun() || n.Command("test", "-f", "/etc/kubernetes/kubelet.conf").Silent().Run() != nil {
+			return n, nil
+		}
+	}
+	return nil, errors.New("join scenarios require a worker node eligible for actions that is not yet joined")
+}
+
+// negativeCRISocket returns the CRI socket to be used by kubeadm on a node
+func negativeCRISocket(n *status.Node) (string, error) {
+	nodeCRI, err := n.CRI()
+	if err != nil {
+		return "", err
+	}
+	if nodeCRI == status.DockerRuntime {
+		return "/var/run/dockershim.sock", nil
+	}
+	return "/run/containerd/containerd.sock", nil
+}
+
+// negativeJoinConfig returns a JoinConfiguration for token discovery using the given token and CA cert hash
+func negativeJoinConfig(criSocket, endpoint, token, caCertHash string, timeout time.Duration) string {
+	return fmt.Sprintf(`apiVersion: kubeadm.k8s.io/v1beta2
+kind: JoinConfiguration
+nodeRegistration:
+  criSocket: "%s"
+  kubeletExtraArgs:
+    fail-swap-on: "false"
+discovery:
+  bootstrapToken:
+    apiServerEndpoint: "%s"
+    token: "%s"
+    caCertHashes:
+    - "%s"
+  timeout: %s
+`, criSocket, endpoint, token, caCertHash, timeout)
+}
+
+// clusterCACertHash returns the hash of the cluster CA on the bootstrap control-plane node, in the format
+// expected by kubeadm join --discovery-token-ca-cert-hash
+func clusterCACertHash(c *status.Cluster) (string, error) {
+	cp1 := c.BootstrapControlPlane()
+	lines, err := cp1.Command("cat", "/etc/kubernetes/pki/ca.crt").Silent().RunAndCapture()
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to read the cluster CA from node %s", cp1.Name())
+	}
+	if cp1.IsDryRun() {
+		return wrongCACertHash, nil
+	}
+
+	caCerts, err := certs.ParseCertificates([]byte(strings.Join(lines, "\n")))
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to parse the cluster CA from node %s", cp1.Name())
+	}
+	if len(caCerts) == 0 {
+		return "", errors.Errorf("failed to parse the cluster CA from node %s", cp1.Name())
+	}
+	return caCertHash(caCerts[0]), nil
+}
+
+// caCertHash returns the hash of the public key of a CA certificate, in the format expected by kubeadm
+func caCertHash(cert *x509.Certificate) string {
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(cert.RawSubjectPublicKeyInfo))
+}
+
+// invalidTokenError returns the expected error for a join with a token that does not exist in the cluster;
+// depending on the kubeadm version, the token is reported as invalid or as not having a JWS signature
+// in the cluster-info ConfigMap, and in both cases the error refers to the token ID
+func invalidTokenError(token string) *regexp.Regexp {
+	id := regexp.QuoteMeta(bootstrapTokenID(token))
+	return regexp.MustCompile(fmt.Sprintf(
+		`%[1]s"? is invalid for this cluster|could not find a JWS signature in the cluster-info ConfigMap for token ID "?%[1]s`, id,
+	))
+}
+
+// literalError returns an expected error matching the given text
+func literalError(text string) *regexp.Regexp {
+	return regexp.MustCompile(regexp.QuoteMeta(text))
+}
+
+// expectKubeadmFailure executes a kubeadm command on a node and verifies that it fails with the expected error and that
+// it leaves the node clean
+func expectKubeadmFailure(n *status.Node, expectedError *regexp.Regexp, args ...string) error {
+	before, err := nodeFingerprint(n)
+	if err != nil {
+		return err
+	}
+
+	lines, err := n.Command("kubeadm", args...).RunAndCapture()
+	if n.IsDryRun() {
+		return nil
+	}
+	fmt.Println(strings.Join(lines, "\n"))
+
+	if err == nil {
+		return errors.Errorf("kubeadm %s was expected to fail on node %s, but it succeeded", args[0], n.Name())
+	}
+	if !expectedError.MatchString(strings.Join(lines, "\n")) {
+		return errors.Errorf("kubeadm %s failed on node %s without the expected error %q", args[0], n.Name(), expectedError)
+	}
+	n.Infof("kubeadm %s failed with the expected error %q", args[0], expectedError)
+
+	after, err := nodeFingerprint(n)
+	if err != nil {
+		return err
+	}
+	if changes := compareFingerprints(before, after); len(changes) > 0 {
+		return errors.Errorf("kubeadm %s did not leave node %s clean:\n%s", args[0], n.Name(), strings.Join(changes, "\n"))
+	}
+	n.Infof("kubeadm %s left the node clean", args[0])
+	return nil
+}
+
+// nodeFingerprint returns the checksum of the files written by kubeadm on a node, indexed by path
+func nodeFingerprint(n *status.Node) (map[string]string, error) {
+	lines, err := n.Command("bash", "-c", nodeFingerprintCommand).Silent().RunAndCapture()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read files written by kubeadm on node %s", n.Name())
+	}
+	return parseFingerprint(lines), nil
+}
+
+// parseFingerprint parses the output of sha256sum into a map of checksums indexed by path
+func parseFingerprint(lines []string) map[string]string {
+	fingerprint := map[string]string{}
+	for _, l := range lines {
+		fields := strings.Fields(l)
+		if len(fields) != 2 {
+			continue
+		}
+		fingerprint[fields[1]] = fields[0]
+	}
+	return fingerprint
+}
+
+// compareFingerprints returns the list of files added, removed or changed between two fingerprints
+func compareFingerprints(before, after map[string]string) []string {
+	changes := []string{}
+	for path, sum := range after {
+		beforeSum, ok := before[path]
+		if !ok {
+			changes = append(changes, fmt.Sprintf("%s: added", path))
+			continue
+		}
+		if beforeSum != sum {
+			changes = append(changes, fmt.Sprintf("%s: changed", path))
+		}
+	}
+	for path := range before {
+		if _, ok := after[path]; !ok {
+			changes = append(changes, fmt.Sprintf("%s: removed", path))
+		}
+	}
+	sort.Strings(changes)
+	return changes
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompareFingerprints(t *testing.T) {
+	tests := []struct {
+		name     string
+		before   []string
+		after    []string
+		expected []string
+	}{
+		{
+			name:     "no changes",
+			before:   []string{"aaa  /etc/kubernetes/a.conf"},
+			after:    []string{"aaa  /etc/kubernetes/a.conf"},
+			expected: []string{},
+		},
+		{
+			name:   "added, changed and removed files",
+			before: []string{"aaa  /etc/kubernetes/a.conf", "bbb  /etc/kubernetes/b.conf"},
+			after:  []string{"ccc  /etc/kubernetes/a.conf", "ddd  /etc/kubernetes/bootstrap-kubelet.conf"},
+			expected: []string{
+				"/etc/kubernetes/a.conf: changed",
+				"/etc/kubernetes/b.conf: removed",
+				"/etc/kubernetes/bootstrap-kubelet.conf: added",
+			},
+		},
+		{
+			name:     "lines that are not checksums are ignored",
+			before:   []string{},
+			after:    []string{"find: '/var/lib/kubelet/config.yaml': No such file or directory"},
+			expected: []string{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			changes := compareFingerprints(parseFingerprint(test.before), parseFingerprint(test.after))
+			if !reflect.DeepEqual(changes, test.expected) {
+				t.Errorf("expected changes %v, got %v", test.expected, changes)
+			}
+		})
+	}
+}
+
+func TestParseBootstrapToken(t *testing.T) {
+	tests := []struct {
+		name     string
+		lines    []string
+		expected string
+	}{
+		{
+			name:     "token only",
+			lines:    []string{"abcdef.0123456789abcdef"},
+			expected: "abcdef.0123456789abcdef",
+		},
+		{
+			name:     "token after warnings",
+			lines:    []string{"W1019 10:00:00.000000 configset.go:348] WARNING: kubeadm cannot validate component configs", "abcdef.0123456789abcdef"},
+			expected: "abcdef.0123456789abcdef",
+		},
+		{
+			name:     "no token",
+			lines:    []string{"timed out waiting for the condition"},
+			expected: "",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if token := parseBootstrapToken(test.lines); token != test.expected {
+				t.Errorf("expected token %q, got %q", test.expected, token)
+			}
+		})
+	}
+}
+
+func TestInvalidTokenError(t *testing.T) {
+	tests := []struct {
+		name     string
+		output   string
+		expected bool
+	}{
+		{
+			name:     "invalid token",
+			output:   `couldn't validate the identity of the API Server: token id "kinder" is invalid for this cluster or it has expired`,
+			expected: true,
+		},
+		{
+			name:     "missing JWS signature",
+			output:   `could not find a JWS signature in the cluster-info ConfigMap for token ID "kinder"`,
+			expected: true,
+		},
+		{
+			name:     "another token ID",
+			output:   `token id "abcdef" is invalid for this cluster or it has expired`,
+			expected: false,
+		},
+		{
+			name:     "another error",
+			output:   "none of the public keys match",
+			expected: false,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := invalidTokenError(invalidToken).MatchString(test.output); got != test.expected {
+				t.Errorf("expected %v, got %v", test.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,274 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/kubeadm/kinder/pkg/build/alter"
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/cri"
+	"k8s.io/kubeadm/kinder/pkg/extract"
+	kinddocker "sigs.k8s.io/kind/pkg/container/docker"
+)
+
+// replaceImagesDir defines the folder on the nodes where image tarballs are copied before importing them
+const replaceImagesDir = "/kinder/replace-images"
+
+// staticPodComponents defines the components running as static pods whose manifests can be updated
+// by replace-binaries
+var staticPodComponents = []string{"kube-apiserver", "kube-controller-manager", "kube-scheduler", "etcd"}
+
+// replaceBits defines the artifacts to be pushed into the nodes by replace-binaries
+type replaceBits struct {
+	kubeadm string
+	kubelet string
+	images  []string
+	// staticPodImages maps static pod components to the new images, if any
+	staticPodImages map[string]string
+}
+
+// ReplaceBinaries pushes new kubeadm and kubelet binaries into running nodes, restarting the kubelet,
+// and imports new image tarballs into the container runtime of the nodes; kubeadm, kubelet and images
+// can be a version, a build label, a URL or a local path, as supported by kinder build node-image-variant.
+// If updateManifests is set, static pod manifests on control-plane nodes are updated to use the new images
+func ReplaceBinaries(c *status.Cluster, kubeadm, kubelet, images string, updateManifests bool, wait time.Duration) error {
+	if kubeadm == "" && kubelet == "" && images == "" {
+		return errors.New("replace-binaries requires at least one of --kubeadm, --kubelet or --images")
+	}
+	if updateManifests && images == "" {
+		return errors.New("replace-binaries --update-manifests requires --images")
+	}
+
+	dir, err := ioutil.TempDir("", "kinder-replace-binaries")
+	if err != nil {
+		return errors.Wrap(err, "failed to create a temporary folder")
+	}
+	defer os.RemoveAll(dir)
+
+	bits, err := extractReplaceBits(dir, kubeadm, kubelet, images)
+	if err != nil {
+		return err
+	}
+
+	for _, n := range c.K8sNodes().EligibleForActions() {
+		if err := replaceNodeBits(c, n, bits, updateManifests, wait); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// extractReplaceBits extracts the given kubeadm, kubelet and images sources into a local folder
+func extractReplaceBits(dir, kubeadm, kubelet, images string) (*replaceBits, error) {
+	bits := &replaceBits{
+		staticPodImages: map[string]string{},
+	}
+
+	var err error
+	if kubeadm != "" {
+		bits.kubeadm, err = extractBinary(kubeadm, filepath.Join(dir, "kubeadm"), extract.OnlyKubeadm(true))
+		if err != nil {
+			return nil, err
+		}
+	}
+	if kubelet != "" {
+		bits.kubelet, err = extractBinary(kubelet, filepath.Join(dir, "kubelet"), extract.OnlyKubelet(true))
+		if err != nil {
+			return nil, err
+		}
+	}
+	if images != "" {
+		dst := filepath.Join(dir, "images")
+		if err := os.Mkdir(dst, 0755); err != nil {
+			return nil, errors.Wrap(err, "failed to create the images folder")
+		}
+
+		e := extract.NewExtractor(images, dst, extract.OnlyKubernetesImages(true))
+		// if the source is a local repository, import all the image tarballs, not only the kubernetes ones
+		if extract.GetSourceType(images) == extract.LocalRepositorySource {
+			e.SetFiles(extract.AllImagesPattern)
+		}
+		paths, err := e.Extract()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to extract %s", images)
+		}
+
+		for _, p := range paths {
+			if err := alter.FixImageTar(p); err != nil {
+				return nil, errors.Wrapf(err, "failed to fix %s", p)
+			}
+			tags, err := kinddocker.GetArchiveTags(p)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to read image tags from %s", p)
+			}
+			for _, t := range tags {
+				if component := staticPodComponent(t); component != "" {
+					bits.staticPodImages[component] = t
+				}
+			}
+			bits.images = append(bits.images, p)
+		}
+		sort.Strings(bits.images)
+	}
+
+	return bits, nil
+}
+
+// extractBinary extracts a binary from the given source into a local folder and returns its path
+func extractBinary(src, dst string, option extract.Option) (string, error) {
+	if err := os.Mkdir(dst, 0755); err != nil {
+		return "", errors.Wrapf(err, "failed to create the %s folder", dst)
+	}
+
+	paths, err := extract.NewExtractor(src, dst, option).Extract()
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to extract %s", src)
+	}
+	if len(paths) != 1 {
+		return "", errors.Errorf("expected one binary from %s, got %d files", src, len(paths))
+	}
+	for _, p := range paths {
+		return p, nil
+	}
+	return "", nil
+}
+
+// replaceNodeBits pushes the replace-binaries artifacts into a node
+func replaceNodeBits(c *status.Cluster, n *status.Node, bits *replaceBits, updateManifests bool, wait time.Duration) error {
+	if bits.kubeadm != "" {
+		n.Infof("replace kubeadm binary")
+		if err := replaceBinary(n, bits.kubeadm, "kubeadm"); err != nil {
+			return err
+		}
+	}
+
+	if len(bits.images) > 0 {
+		n.Infof("import images")
+		if err := importImages(n, bits.images); err != nil {
+			return err
+		}
+	}
+
+	if bits.kubelet != "" {
+		n.Infof("replace kubelet binary")
+		if err := replaceBinary(n, bits.kubelet, "kubelet"); err != nil {
+			return err
+		}
+		if err := n.Command(
+			"systemctl", "restart", "kubelet",
+		).Silent().Run(); err != nil {
+			return errors.Wrapf(err, "failed to restart the kubelet on %s", n.Name())
+		}
+	}
+
+	updated := map[string]string{}
+	if updateManifests && n.IsControlPlane() {
+		n.Infof("update static pod manifests")
+		for _, component := range staticPodComponents {
+			image, ok := bits.staticPodImages[component]
+			if !ok {
+				continue
+			}
+			if err := n.Command(
+				"sed", "-i", fmt.Sprintf("s|image: .*|image: %s|", image), filepath.Join(manifestsDir, fmt.Sprintf("%s.yaml", component)),
+			).Run(); err != nil {
+				return errors.Wrapf(err, "failed to update the %s manifest on %s", component, n.Name())
+			}
+			updated[component] = image
+		}
+	}
+
+	return waitBinariesReplaced(c, n, updated, wait)
+}
+
+// replaceBinary copies a binary from the host into /usr/bin on a node; the binary is copied to a temporary
+// file and then renamed, so running binaries can be replaced as well
+func replaceBinary(n *status.Node, src, binaryName string) error {
+	dest := filepath.Join("/usr", "bin", binaryName)
+	tmp := fmt.Sprintf("%s.kinder-new", dest)
+
+	if err := hostDocker(n, "cp", src, fmt.Sprintf("%s:%s", n.Name(), tmp)); err != nil {
+		return err
+	}
+	if err := n.Command(
+		"chmod", "+x", tmp,
+	).Silent().Run(); err != nil {
+		return errors.Wrapf(err, "failed to make %s executable on %s", tmp, n.Name())
+	}
+	if err := n.Command(
+		"mv", "-f", tmp, dest,
+	).Silent().Run(); err != nil {
+		return errors.Wrapf(err, "failed to replace %s on %s", dest, n.Name())
+	}
+	return nil
+}
+
+// importImages copies image tarballs from the host into a node and imports them into the container runtime
+func importImages(n *status.Node, images []string) error {
+	if err := n.Command(
+		"mkdir", "-p", replaceImagesDir,
+	).Silent().Run(); err != nil {
+		return errors.Wrapf(err, "failed to create %s on %s", replaceImagesDir, n.Name())
+	}
+	for _, i := range images {
+		if err := hostDocker(n, "cp", i, fmt.Sprintf("%s:%s", n.Name(), filepath.Join(replaceImagesDir, filepath.Base(i)))); err != nil {
+			return err
+		}
+	}
+
+	nodeCRI, err := n.CRI()
+	if err != nil {
+		return err
+	}
+	actionHelper, err := cri.NewActionHelper(nodeCRI)
+	if err != nil {
+		return err
+	}
+	if err := actionHelper.PreLoadUpgradeImages(n, replaceImagesDir); err != nil {
+		return errors.Wrapf(err, "failed to import images on %s", n.Name())
+	}
+
+	return n.Command(
+		"rm", "-rf", replaceImagesDir,
+	).Silent().Run()
+}
+
+// staticPodComponent returns the static pod component an image tag refers to, if any,
+// e.g. kube-apiserver for k8s.gcr.io/kube-apiserver:v1.20.0
+func staticPodComponent(tag string) string {
+	repository := tag
+	if i := strings.LastIndex(tag, ":"); i > strings.LastIndex(tag, "/") {
+		repository = tag[:i]
+	}
+	name := repository[strings.LastIndex(repository, "/")+1:]
+	for _, c := range staticPodComponents {
+		if name == c {
+			return c
+		}
+	}
+	return ""
+}
@@ -0,0 +1,43 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"testing"
+)
+
+func TestStaticPodComponent(t *testing.T) {
+	tests := []struct {
+		tag      string
+		expected string
+	}{
+		{tag: "k8s.gcr.io/kube-apiserver:v1.20.0", expected: "kube-apiserver"},
+		{tag: "k8s.gcr.io/kube-controller-manager:v1.20.0-alpha.1.123_0123456789abcd", expected: "kube-controller-manager"},
+		{tag: "kube-scheduler:v1.20.0", expected: "kube-scheduler"},
+		{tag: "localhost:5000/etcd:3.4.13-0", expected: "etcd"},
+		{tag: "localhost:5000/kube-apiserver", expected: "kube-apiserver"},
+		{tag: "k8s.gcr.io/kube-proxy:v1.20.0", expected: ""},
+		{tag: "k8s.gcr.io/kube-apiserver-amd64:v1.20.0", expected: ""},
+	}
+	for _, test := range tests {
+		t.Run(test.tag, func(t *testing.T) {
+			if got := staticPodComponent(test.tag); got != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, got)
+			}
+		})
+	}
+}
@@ -17,21 +17,35 @@ limitations under the License.
 package actions
 
 import (
+	"crypto/x509"
 	"fmt"
+	"path/filepath"
+	"strings"
 
 	"github.com/pkg/errors"
 
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/kubeadm/kinder/pkg/certs"
 	"k8s.io/kubeadm/kinder/pkg/cluster/status"
 )
 
 // SetupExternalCA setups certificates and kubeconfig files to be able to create a cluster without CA keys.
-func SetupExternalCA(c *status.Cluster, vLevel int) error {
+// By default only the cluster CA is external, but also the front-proxy CA and the etcd CA can be made external.
+// If requested, the cluster CA is created as an intermediate CA signed by an offline root CA that is
+// never copied to the nodes; in this case the ca.crt file on the nodes contains the whole CA chain.
+func SetupExternalCA(c *status.Cluster, externalCAs []ExternalCA, intermediate bool, vLevel int) error {
 	fmt.Println("Setuping external CA for the cluster...")
 
-	// gets the IP of the load balancer
-	loadBalancerIP, _, err := c.ExternalLoadBalancer().IP()
+	if intermediate {
+		if err := setupIntermediateCA(c.BootstrapControlPlane()); err != nil {
+			return err
+		}
+	}
+
+	// gets the control plane endpoint, that is the IP of the load balancer or the control plane VIP
+	loadBalancerIP, _, _, err := getControlPlaneAddress(c)
 	if err != nil {
-		return errors.Wrapf(err, "failed to get IP for node: %s", c.ExternalLoadBalancer().Name())
+		return err
 	}
 
 	// generate certs on the primary node
@@ -113,12 +127,240 @@ func SetupExternalCA(c *status.Cluster, vLevel int) error {
 		}
 	}
 
-	// delete the ca.key from all nodes
+	// delete the keys of external CAs from all nodes
 	for _, n := range c.AllNodes() {
-		if err := n.Command("rm", "-f", "/etc/kubernetes/pki/ca.key").Run(); err != nil {
-			return errors.Wrapf(err, "could not delete ca.key on node: %s", n.Name())
+		for _, ca := range externalCAs {
+			if err := n.Command("rm", "-f", ca.keyPath()).Run(); err != nil {
+				return errors.Wrapf(err, "could not delete %s on node: %s", ca.keyPath(), n.Name())
+			}
 		}
 	}
 
 	return nil
 }
+
+// setupIntermediateCA creates an offline root CA and an intermediate CA signed by the root CA,
+// and stores the intermediate CA on the node, to be used as the cluster CA; the root CA key is
+// never written to the node
+func setupIntermediateCA(n *status.Node) error {
+	n.Infof("Creating the cluster CA as an intermediate CA signed by an offline root CA")
+
+	rootCA, rootKey, err := certs.NewCA("kinder-offline-root-ca", nil, nil)
+	if err != nil {
+		return err
+	}
+	ca, caKey, err := certs.NewCA("kubernetes", rootCA, rootKey)
+	if err != nil {
+		return err
+	}
+	caKeyData, err := certs.EncodePrivateKey(caKey)
+	if err != nil {
+		return err
+	}
+
+	if err := n.Command("mkdir", "-p", etcKubernetes+"/pki").Silent().Run(); err != nil {
+		return errors.Wrap(err, "failed to create pki folder")
+	}
+
+	// the ca.crt file contains the whole chain, with the intermediate CA first, so kubeadm uses it as a cluster CA
+	if err := n.WriteFile(ClusterCA.certPath(), certs.EncodeCertificates(ca, rootCA)); err != nil {
+		return err
+	}
+	return n.WriteFile(ClusterCA.keyPath(), caKeyData)
+}
+
+// VerifyExternalCA verifies the cluster after init/join with external CAs, checking that the keys of external CAs
+// do not exist on nodes, that CA certificates are the same on all the nodes and that all the certificates,
+// including client certificates embedded in kubeconfig files, can be verified using the CA certificates.
+func VerifyExternalCA(c *status.Cluster, externalCAs []ExternalCA, intermediate bool) error {
+	problems := []string{}
+	caInfos := []certs.CertificateInfo{}
+	for _, n := range c.K8sNodes() {
+		n.Infof("Verifying external CA setup")
+		if n.IsDryRun() {
+			continue
+		}
+
+		// checks that the keys of external CAs do not exist on nodes
+		for _, ca := range externalCAs {
+			if err := n.Command("test", "-e", ca.keyPath()).Silent().Run(); err == nil {
+				problems = append(problems, fmt.Sprintf("%s: the %s key %s exists", n.Name(), ca, ca.keyPath()))
+			}
+		}
+
+		bundles := map[ExternalCA][]*x509.Certificate{}
+		for _, ca := range KnownExternalCAs() {
+			data, err := n.ReadFile(ExternalCA(ca).certPath())
+			if err != nil {
+				continue
+			}
+			bundle, err := certs.ParseCertificates(data)
+			if err != nil {
+				return errors.Wrapf(err, "failed to parse %s on %s", ExternalCA(ca).certPath(), n.Name())
+			}
+			bundles[ExternalCA(ca)] = bundle
+			caInfos = append(caInfos, certs.NewCertificateInfo(n.Name(), ExternalCA(ca).certPath(), "", bundle[0]))
+		}
+
+		if _, ok := bundles[ClusterCA]; !ok {
+			return errors.Errorf("%s does not exist on %s", ClusterCA.certPath(), n.Name())
+		}
+
+		// checks that the cluster CA is an intermediate CA signed by the root CA included in ca.crt
+		if intermediate {
+			bundle := bundles[ClusterCA]
+			if len(bundle) < 2 {
+				problems = append(problems, fmt.Sprintf("%s: %s does not contain the CA chain", n.Name(), ClusterCA.certPath()))
+			} else if err := certs.VerifyCertificate(bundle[0], bundle[1:]); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: the intermediate CA is not signed by the root CA: %v", n.Name(), err))
+			}
+		}
+
+		leaves, err := readLeafCertificates(n)
+		if err != nil {
+			return err
+		}
+		for path, cert := range leaves {
+			ca := issuingCA(path)
+			bundle, ok := bundles[ca]
+			if !ok {
+				problems = append(problems, fmt.Sprintf("%s: the CA for %s does not exist", n.Name(), path))
+				continue
+			}
+			if err := certs.VerifyCertificate(cert, bundle); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %s: %v", n.Name(), path, err))
+			}
+		}
+	}
+
+	// checks that CA certificates are the same on all the nodes
+	for _, ca := range KnownExternalCAs() {
+		problems = append(problems, certs.CheckSameCertificate(caInfos, ExternalCA(ca).certPath())...)
+	}
+
+	if len(problems) > 0 {
+		return errors.Errorf("external CA verification failed:\n%s", strings.Join(problems, "\n"))
+	}
+	fmt.Println("External CA verification passed")
+	return nil
+}
+
+// readLeafCertificates reads from a node all the certificates signed by the cluster CAs, including the
+// kubelet client certificate and client certificates embedded in kubeconfig files
+func readLeafCertificates(n *status.Node) (map[string]*x509.Certificate, error) {
+	certFiles, err := n.Command(
+		"find", etcKubernetes+"/pki", "-name", "*.crt",
+	).Silent().RunAndCapture()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list certificates on %s", n.Name())
+	}
+
+	kubeletClientCert, err := n.Command(
+		"readlink", "-f", "/var/lib/kubelet/pki/kubelet-client-current.pem",
+	).Silent().RunAndCapture()
+	if err == nil && len(kubeletClientCert) == 1 {
+		certFiles = append(certFiles, kubeletClientCert[0])
+	}
+
+	leaves := map[string]*x509.Certificate{}
+	for _, f := range certFiles {
+		data, err := n.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		fileCerts, err := certs.ParseCertificates(data)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse %s on %s", f, n.Name())
+		}
+		if fileCerts[0].IsCA {
+			continue
+		}
+		leaves[f] = fileCerts[0]
+	}
+
+	kubeConfigFiles, err := n.Command(
+		"find", etcKubernetes, "-maxdepth", "1", "-name", "*.conf",
+	).Silent().RunAndCapture()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list kubeconfig files on %s", n.Name())
+	}
+	for _, f := range kubeConfigFiles {
+		data, err := n.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		config, err := clientcmd.Load(data)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse %s on %s", f, n.Name())
+		}
+		for user, authInfo := range config.AuthInfos {
+			if len(authInfo.ClientCertificateData) == 0 {
+				continue
+			}
+			userCerts, err := certs.ParseCertificates(authInfo.ClientCertificateData)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to parse the client certificate for user %s in %s on %s", user, f, n.Name())
+			}
+			leaves[fmt.Sprintf("%s (%s)", f, user)] = userCerts[0]
+		}
+	}
+
+	return leaves, nil
+}
+
+// issuingCA returns the CA expected to sign the certificate with the given path
+func issuingCA(path string) ExternalCA {
+	switch {
+	case strings.HasPrefix(path, etcKubernetes+"/pki/etcd/"), filepath.Base(path) == "apiserver-etcd-client.crt":
+		return EtcdCA
+	case filepath.Base(path) == "front-proxy-client.crt":
+		return FrontProxyCA
+	}
+	return ClusterCA
+}
+
+// ExternalCA defines a CA that can be external, that is a CA with the key not available on the nodes
+type ExternalCA string
+
+const (
+	// ClusterCA is the CA signing certificates for the API server, the kubelets and the control-plane components
+	ClusterCA = ExternalCA("ca")
+
+	// FrontProxyCA is the CA signing certificates for the front proxy
+	FrontProxyCA = ExternalCA("front-proxy-ca")
+
+	// EtcdCA is the CA signing certificates for etcd
+	EtcdCA = ExternalCA("etcd-ca")
+)
+
+// KnownExternalCAs returns the list of known ExternalCA
+func KnownExternalCAs() []string {
+	return []string{
+		string(ClusterCA),
+		string(FrontProxyCA),
+		string(EtcdCA),
+	}
+}
+
+// ValidateExternalCA validates an ExternalCA
+func ValidateExternalCA(ca ExternalCA) error {
+	switch ca {
+	case ClusterCA:
+	case FrontProxyCA:
+	case EtcdCA:
+	default:
+		return errors.Errorf("invalid external CA. Use one of %s", KnownExternalCAs())
+	}
+	return nil
+}
+
+func (ca ExternalCA) certPath() string {
+	if ca == EtcdCA {
+		return etcKubernetes + "/pki/etcd/ca.crt"
+	}
+	return fmt.Sprintf("%s/pki/%s.crt", etcKubernetes, ca)
+}
+
+func (ca ExternalCA) keyPath() string {
+	return strings.TrimSuffix(ca.certPath(), ".crt") + ".key"
+}
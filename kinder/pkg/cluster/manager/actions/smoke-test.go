@@ -17,144 +17,596 @@ limitations under the License.
 package actions
 
 import (
+	"bytes"
 	"fmt"
+	"net"
+	"os"
+	"path/filepath"
 	"strings"
+	"text/template"
 	"time"
 
 	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
 
 	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/test/junit"
 )
 
-// SmokeTest actions execute a set of simple test checking proper functioning of
-// deployments, services/type node port, kubectl logs & exec & DNS resolution
-func SmokeTest(c *status.Cluster, wait time.Duration) error {
-	// test are executed on the bootstrap control-plane
-	cp1 := c.BootstrapControlPlane()
+const (
+	// DefaultSmokeTestImage defines the image used by smoke tests; the image should serve HTTP on port 80,
+	// and it should include sh, wget and nslookup
+	DefaultSmokeTestImage = "nginx:1.15.9-alpine"
 
-	// cleanups garbage from previous test
-	cleanupSmokeTest(cp1)
+	// smokeTestNamespace defines the namespace where smoke test resources are created
+	smokeTestNamespace = "kinder-smoke-test"
 
-	// Test deployments
-	cp1.Infof("test deployments")
+	// smokeTestPortForwardPort defines the local port used when testing kubectl port-forward
+	smokeTestPortForwardPort = 18080
 
-	if err := cp1.Command(
-		"kubectl",
-		"--kubeconfig=/etc/kubernetes/admin.conf",
-		"create", "deployment", "nginx", "--image=nginx:1.15.9-alpine",
-	).RunWithEcho(); err != nil {
-		return err
+	// smokeTestClassName defines the junit class name for smoke tests
+	smokeTestClassName = "kinder.smoke-test"
+)
+
+// smokeTestCheck defines a named check executed by the smoke-test action
+type smokeTestCheck struct {
+	name        string
+	description string
+	run         func(s *smokeTestContext) error
+}
+
+// smokeTestChecks defines the list of checks executed by the smoke-test action, in order
+var smokeTestChecks = []smokeTestCheck{
+	{name: "dns", description: "test DNS resolution of the kubernetes service", run: checkSmokeTestDNS},
+	{name: "clusterip", description: "test ClusterIP service", run: checkSmokeTestClusterIP},
+	{name: "nodeport", description: "test NodePort service on all the nodes", run: checkSmokeTestNodePort},
+	{name: "pod-to-pod", description: "test pod to pod connectivity across nodes", run: checkSmokeTestPodToPod},
+	{name: "hostpath-pv", description: "test hostPath persistent volume", run: checkSmokeTestHostPathPV},
+	{name: "rbac", description: "test RBAC authorization for a service account", run: checkSmokeTestRBAC},
+	{name: "logs", description: "test kubectl logs", run: checkSmokeTestLogs},
+	{name: "exec", description: "test kubectl exec", run: checkSmokeTestExec},
+	{name: "port-forward", description: "test kubectl port-forward", run: checkSmokeTestPortForward},
+}
+
+// KnownSmokeTests returns the list of smoke tests checks
+func KnownSmokeTests() []string {
+	names := []string{}
+	for _, c := range smokeTestChecks {
+		names = append(names, c.name)
 	}
+	return names
+}
 
-	if err := waitForPodsRunning(c, cp1, wait, "nginx", 1); err != nil {
-		return err
+// SelectSmokeTests returns the list of smoke tests checks corresponding to the given names;
+// "all" or an empty list select all the checks
+func SelectSmokeTests(names []string) ([]string, error) {
+	if len(names) == 0 || (len(names) == 1 && names[0] == "all") {
+		return KnownSmokeTests(), nil
 	}
 
-	// Test service type NodePort
-	cp1.Infof("test NodePort service")
+	selected := []string{}
+	for _, n := range names {
+		if !contains(KnownSmokeTests(), n) {
+			return nil, errors.Errorf("unknown smoke test %q. Use all or one of %s", n, KnownSmokeTests())
+		}
+		selected = append(selected, n)
+	}
+	return selected, nil
+}
 
-	if err := cp1.Command(
-		"kubectl",
-		"--kubeconfig=/etc/kubernetes/admin.conf",
-		"expose", "deployment", "nginx", "--port=80", "--type=NodePort",
-	).Run(); err != nil {
-		return err
+// smokeTestPod defines a pod of the smoke test DaemonSet
+type smokeTestPod struct {
+	Name string
+	Node string
+	IP   string
+}
+
+// smokeTestContext defines the context shared by smoke tests checks
+type smokeTestContext struct {
+	c     *status.Cluster
+	cp1   *status.Node
+	image string
+	wait  time.Duration
+	pods  []smokeTestPod
+}
+
+// SmokeTest actions execute a set of named checks verifying proper functioning of the cluster,
+// like e.g. DNS resolution, services, pod to pod connectivity, volumes, RBAC and kubectl logs/exec/port-forward.
+// Checks are executed against a DaemonSet running the given image on all the nodes; all the selected checks
+// are executed even if one of them fails, and if the ARTIFACTS environment variable is set a junit report
+// is written to junit_smoke-test.xml in the ARTIFACTS folder
+func SmokeTest(c *status.Cluster, checks []string, image string, wait time.Duration) error {
+	if len(checks) == 0 {
+		checks = KnownSmokeTests()
+	}
+	if image == "" {
+		image = DefaultSmokeTestImage
 	}
 
-	nodePort, err := getNodePort(cp1, "nginx")
-	if err != nil {
-		return err
+	s := &smokeTestContext{
+		c:     c,
+		cp1:   c.BootstrapControlPlane(),
+		image: image,
+		wait:  wait,
 	}
 
-	for _, n := range c.K8sNodes() {
-		err = waitForNodePort(c, n, 30*time.Second, nodePort)
-		if err != nil {
+	// cleanups garbage from previous test
+	s.cleanup()
+
+	if s.cp1.IsDryRun() {
+		if err := s.setup(); err != nil {
 			return err
 		}
+		s.cp1.Infof("skipping smoke test checks %s in dry run", strings.Join(checks, ", "))
+		return nil
 	}
 
-	podName, err := getPodName(cp1, "nginx")
-	if err != nil {
-		return err
+	start := time.Now()
+	suite := &junit.TestSuite{Name: "smoke-test"}
+
+	setupStart := time.Now()
+	setupErr := s.setup()
+	if setupErr != nil {
+		suite.AddTestCase(smokeTestClassName, "setup", junit.WithFailure(setupErr.Error()), junit.WithDuration(time.Since(setupStart)))
+	} else {
+		suite.AddTestCase(smokeTestClassName, "setup", junit.WithDuration(time.Since(setupStart)))
 	}
 
-	// Test kubectl logs
-	cp1.Infof("test kubectl logs")
+	failed := []string{}
+	for _, check := range smokeTestChecks {
+		if !contains(checks, check.name) {
+			continue
+		}
 
-	lines, err := cp1.Command(
-		"kubectl", "--kubeconfig=/etc/kubernetes/admin.conf", "logs", podName,
-	).RunAndCapture()
-	if err != nil {
-		return errors.Wrapf(err, "failed to run kubectl logs")
-	}
-	fmt.Printf("%d logs lines returned\n", len(lines))
+		if setupErr != nil {
+			suite.AddTestCase(smokeTestClassName, check.name, junit.WithSkipped("skipping because the smoke test setup failed"))
+			continue
+		}
 
-	// Test kubectl exec
-	cp1.Infof("test kubectl exec")
+		s.cp1.Infof(check.description)
+		checkStart := time.Now()
+		if err := check.run(s); err != nil {
+			fmt.Printf("FAIL: %s: %v\n", check.name, err)
+			failed = append(failed, check.name)
+			suite.AddTestCase(smokeTestClassName, check.name, junit.WithFailure(err.Error()), junit.WithDuration(time.Since(checkStart)))
+			continue
+		}
+		fmt.Printf("PASS: %s\n", check.name)
+		suite.AddTestCase(smokeTestClassName, check.name, junit.WithDuration(time.Since(checkStart)))
+	}
+	suite.Time = time.Since(start).Seconds()
 
-	lines, err = cp1.Command(
-		"kubectl", "--kubeconfig=/etc/kubernetes/admin.conf", "exec", podName, "--", "nslookup", "kubernetes",
-	).RunAndCapture()
-	if err != nil {
-		return errors.Wrapf(err, "failed to run kubectl exec")
+	if artifacts := os.Getenv("ARTIFACTS"); artifacts != "" {
+		file := filepath.Join(artifacts, "junit_smoke-test.xml")
+		if err := suite.WriteFile(file); err != nil {
+			return err
+		}
+		fmt.Printf("\nsee %s for more details\n", file)
 	}
-	fmt.Printf("%d output lines returned\n", len(lines))
 
-	// Test DNS resolution
-	cp1.Infof("test DNS resolution")
+	if setupErr != nil {
+		return errors.Wrap(setupErr, "smoke test setup failed")
+	}
 
-	if len(lines) < 3 || !strings.Contains(lines[3], "kubernetes.default.svc.cluster.local") {
-		return errors.Wrapf(err, "dns resolution error")
+	// NB. in case of failures, smoke test resources are preserved for debugging purposes
+	if len(failed) > 0 {
+		return errors.Errorf("smoke test failed: %s", strings.Join(failed, ", "))
 	}
-	fmt.Printf("kubernetes service answers to %s\n", lines[3])
 
 	// cleanups and print final message
-	cleanupSmokeTest(cp1)
+	s.cleanup()
 	fmt.Printf("\nSmoke test passed!\n")
 
 	return nil
 }
 
-func cleanupSmokeTest(cp1 *status.Node) {
-	cp1.Command(
-		"kubectl",
-		"--kubeconfig=/etc/kubernetes/admin.conf",
-		"delete", "deployments/nginx",
+// smokeTestManifest defines the resources shared by smoke tests checks, that are a DaemonSet running
+// the smoke test image on all the nodes, a ClusterIP service and a NodePort service
+const smokeTestManifest = `apiVersion: v1
+kind: Namespace
+metadata:
+  name: {{ .Namespace }}
+---
+apiVersion: apps/v1
+kind: DaemonSet
+metadata:
+  name: smoke
+  namespace: {{ .Namespace }}
+spec:
+  selector:
+    matchLabels:
+      app: smoke
+  template:
+    metadata:
+      labels:
+        app: smoke
+    spec:
+      tolerations:
+      - operator: Exists
+      containers:
+      - name: smoke
+        image: {{ .Image }}
+        imagePullPolicy: IfNotPresent
+        ports:
+        - containerPort: 80
+        readinessProbe:
+          httpGet:
+            path: /
+            port: 80
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: smoke
+  namespace: {{ .Namespace }}
+spec:
+  selector:
+    app: smoke
+  ports:
+  - port: 80
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: smoke-nodeport
+  namespace: {{ .Namespace }}
+spec:
+  type: NodePort
+  selector:
+    app: smoke
+  ports:
+  - port: 80
+`
+
+// smokeTestPVManifest defines a hostPath persistent volume, the corresponding claim and a pod writing on it
+const smokeTestPVManifest = `apiVersion: v1
+kind: PersistentVolume
+metadata:
+  name: {{ .Namespace }}
+spec:
+  capacity:
+    storage: 10Mi
+  accessModes:
+  - ReadWriteOnce
+  persistentVolumeReclaimPolicy: Retain
+  storageClassName: {{ .Namespace }}
+  hostPath:
+    path: /tmp/{{ .Namespace }}
+    type: DirectoryOrCreate
+---
+apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: smoke-pv
+  namespace: {{ .Namespace }}
+spec:
+  accessModes:
+  - ReadWriteOnce
+  storageClassName: {{ .Namespace }}
+  resources:
+    requests:
+      storage: 10Mi
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: smoke-pv
+  namespace: {{ .Namespace }}
+spec:
+  tolerations:
+  - operator: Exists
+  containers:
+  - name: smoke
+    image: {{ .Image }}
+    imagePullPolicy: IfNotPresent
+    command: ["sh", "-c", "echo {{ .Namespace }} > /data/smoke && sleep 3600"]
+    volumeMounts:
+    - name: data
+      mountPath: /data
+  volumes:
+  - name: data
+    persistentVolumeClaim:
+      claimName: smoke-pv
+`
+
+// setup creates the resources shared by smoke tests checks and waits for them to be ready
+func (s *smokeTestContext) setup() error {
+	s.cp1.Infof("setup smoke test resources using %s", s.image)
+	if err := s.apply(smokeTestManifest); err != nil {
+		return err
+	}
+
+	if err := waitWorkloadsReady(s.c, s.cp1, []workload{{Kind: "DaemonSet", Namespace: smokeTestNamespace, Name: "smoke"}}, s.wait); err != nil {
+		return err
+	}
+
+	if s.cp1.IsDryRun() {
+		return nil
+	}
+
+	lines, err := s.kubectl("get", "pods", "-l=app=smoke",
+		"-o=jsonpath={range .items[*]}{.metadata.name} {.spec.nodeName} {.status.podIP}{\"\\n\"}{end}",
+	)
+	if err != nil {
+		return err
+	}
+	s.pods = parseSmokeTestPods(lines)
+	if len(s.pods) == 0 {
+		return errors.New("failed to get smoke test pods")
+	}
+	return nil
+}
+
+// cleanup deletes all the smoke test resources
+func (s *smokeTestContext) cleanup() {
+	s.cp1.Command(
+		"kubectl", "--kubeconfig=/etc/kubernetes/admin.conf", "delete", "namespace", smokeTestNamespace, "--ignore-not-found", "--wait=true",
 	).Silent().Run()
 
-	cp1.Command(
-		"kubectl",
-		"--kubeconfig=/etc/kubernetes/admin.conf",
-		"delete", "service/nginx",
+	s.cp1.Command(
+		"kubectl", "--kubeconfig=/etc/kubernetes/admin.conf", "delete", "persistentvolume", smokeTestNamespace, "--ignore-not-found",
 	).Silent().Run()
 }
 
-func getNodePort(n *status.Node, svc string) (string, error) {
-	lines, err := n.Command(
-		"kubectl", "--kubeconfig=/etc/kubernetes/admin.conf", "get", "svc", svc, "--output=jsonpath='{range .spec.ports[0]}{.nodePort}'",
+// apply applies a manifest template to the cluster
+func (s *smokeTestContext) apply(manifest string) error {
+	t, err := template.New("smoke-test").Parse(manifest)
+	if err != nil {
+		return errors.Wrap(err, "failed to parse smoke test manifest")
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, map[string]string{"Namespace": smokeTestNamespace, "Image": s.image}); err != nil {
+		return errors.Wrap(err, "failed to render smoke test manifest")
+	}
+
+	cmd := s.cp1.Command("kubectl", "--kubeconfig=/etc/kubernetes/admin.conf", "apply", "-f", "-")
+	cmd.Stdin(&buf)
+	return cmd.RunWithEcho()
+}
+
+// kubectl runs a kubectl command in the smoke test namespace and returns the output; in case of errors,
+// the output is included in the error message
+func (s *smokeTestContext) kubectl(args ...string) ([]string, error) {
+	lines, err := s.cp1.Command(
+		"kubectl", append([]string{"--kubeconfig=/etc/kubernetes/admin.conf", fmt.Sprintf("-n=%s", smokeTestNamespace)}, args...)...,
 	).Silent().RunAndCapture()
 	if err != nil {
-		return "", errors.Wrapf(err, "failed to get node port")
+		return lines, errors.Wrapf(err, "kubectl %s failed: %s", strings.Join(args, " "), strings.Join(lines, "\n"))
+	}
+	return lines, nil
+}
+
+// exec runs a command into the given smoke test pod
+func (s *smokeTestContext) exec(pod string, command ...string) ([]string, error) {
+	return s.kubectl(append([]string{"exec", pod, "--"}, command...)...)
+}
+
+// jsonPath returns the value of a jsonpath expression for the given resource
+func (s *smokeTestContext) jsonPath(resource, expression string) (string, error) {
+	lines, err := s.kubectl("get", resource, fmt.Sprintf("-o=jsonpath=%s", expression))
+	if err != nil {
+		return "", err
 	}
-	if len(lines) != 1 {
-		return "", errors.New("failed to parse node port")
+	if len(lines) != 1 || lines[0] == "" {
+		return "", errors.Errorf("failed to get %s for %s", expression, resource)
 	}
+	return lines[0], nil
+}
 
-	return strings.Trim(lines[0], "'"), nil
+// smokeTestURL returns the URL served by the smoke test image at the given IPv4 or IPv6 address
+func smokeTestURL(ip string) string {
+	return fmt.Sprintf("http://%s", net.JoinHostPort(ip, "80"))
 }
 
-func getPodName(n *status.Node, label string) (string, error) {
-	lines, err := n.Command(
-		"kubectl",
-		"--kubeconfig=/etc/kubernetes/admin.conf",
-		"get", "pods", "-l", fmt.Sprintf("app=%s", label), "-o", "jsonpath='{.items[0].metadata.name}'",
+func checkSmokeTestDNS(s *smokeTestContext) error {
+	// NB. the kubernetes service is in the default namespace
+	lines, err := s.cp1.Command(
+		"kubectl", "--kubeconfig=/etc/kubernetes/admin.conf", "get", "service", "kubernetes", "-o=jsonpath={.spec.clusterIP}",
 	).Silent().RunAndCapture()
 	if err != nil {
-		return "", errors.Wrapf(err, "failed to get pod name")
+		return errors.Wrapf(err, "failed to get the kubernetes service ClusterIP: %s", strings.Join(lines, "\n"))
+	}
+	if len(lines) != 1 || lines[0] == "" {
+		return errors.Errorf("failed to get the kubernetes service ClusterIP: %s", strings.Join(lines, "\n"))
+	}
+	clusterIP := lines[0]
+
+	name := "kubernetes.default.svc.cluster.local"
+	lines, err = s.exec(s.pods[0].Name, "nslookup", name)
+	if err != nil {
+		return err
+	}
+	if !hasSmokeTestAnswer(lines, name, clusterIP) {
+		return errors.Errorf("%s was not resolved to %s: %s", name, clusterIP, strings.Join(lines, "\n"))
+	}
+	fmt.Printf("%s resolved to %s\n", name, clusterIP)
+	return nil
+}
+
+func checkSmokeTestClusterIP(s *smokeTestContext) error {
+	clusterIP, err := s.jsonPath("service/smoke", "{.spec.clusterIP}")
+	if err != nil {
+		return err
+	}
+
+	for _, p := range s.pods {
+		if _, err := s.exec(p.Name, "wget", "-q", "-O", "/dev/null", "-T", "5", smokeTestURL(clusterIP)); err != nil {
+			return errors.Wrapf(err, "ClusterIP %s is not reachable from pod %s on node %s", clusterIP, p.Name, p.Node)
+		}
+		fmt.Printf("ClusterIP %s is reachable from pod %s on node %s\n", clusterIP, p.Name, p.Node)
+	}
+	return nil
+}
+
+func checkSmokeTestNodePort(s *smokeTestContext) error {
+	nodePort, err := s.jsonPath("service/smoke-nodeport", "{.spec.ports[0].nodePort}")
+	if err != nil {
+		return err
+	}
+
+	for _, n := range s.c.K8sNodes() {
+		if err := waitForNodePort(s.c, n, 30*time.Second, nodePort); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkSmokeTestPodToPod(s *smokeTestContext) error {
+	for _, from := range s.pods {
+		for _, to := range s.pods {
+			// NB. with a single node cluster, the only pod tests connectivity with itself
+			if from.Name == to.Name && len(s.pods) > 1 {
+				continue
+			}
+			if _, err := s.exec(from.Name, "wget", "-q", "-O", "/dev/null", "-T", "5", smokeTestURL(to.IP)); err != nil {
+				return errors.Wrapf(err, "pod %s on node %s is not reachable from pod %s on node %s", to.Name, to.Node, from.Name, from.Node)
+			}
+			fmt.Printf("pod %s on node %s is reachable from pod %s on node %s\n", to.Name, to.Node, from.Name, from.Node)
+		}
+	}
+	return nil
+}
+
+func checkSmokeTestHostPathPV(s *smokeTestContext) error {
+	if err := s.apply(smokeTestPVManifest); err != nil {
+		return err
+	}
+
+	if _, err := s.kubectl("wait", "--for=condition=Ready", "pod/smoke-pv", fmt.Sprintf("--timeout=%s", s.wait)); err != nil {
+		return err
+	}
+
+	lines, err := s.exec("smoke-pv", "cat", "/data/smoke")
+	if err != nil {
+		return err
 	}
-	if len(lines) != 1 {
-		return "", errors.New("failed to parse pod name")
+	if len(lines) != 1 || lines[0] != smokeTestNamespace {
+		return errors.Errorf("unexpected content of the persistent volume: %s", strings.Join(lines, "\n"))
 	}
+	fmt.Println("persistent volume is writable and readable")
+	return nil
+}
 
-	return strings.Trim(lines[0], "'"), nil
+func checkSmokeTestRBAC(s *smokeTestContext) error {
+	for _, args := range [][]string{
+		{"create", "serviceaccount", "smoke"},
+		{"create", "role", "smoke", "--verb=get,list", "--resource=pods"},
+		{"create", "rolebinding", "smoke", "--role=smoke", fmt.Sprintf("--serviceaccount=%s:smoke", smokeTestNamespace)},
+	} {
+		if _, err := s.kubectl(args...); err != nil {
+			return err
+		}
+	}
+
+	user := fmt.Sprintf("--as=system:serviceaccount:%s:smoke", smokeTestNamespace)
+	expectations := []struct {
+		args     []string
+		expected string
+	}{
+		{args: []string{"auth", "can-i", "list", "pods", user}, expected: "yes"},
+		{args: []string{"auth", "can-i", "delete", "pods", user}, expected: "no"},
+		{args: []string{"auth", "can-i", "list", "secrets", user}, expected: "no"},
+	}
+
+	for _, e := range expectations {
+		var answer string
+		// NB. the RBAC authorizer could take a while to observe the new role binding
+		// and kubectl auth can-i exits with an error when the answer is no, so the error is ignored
+		_ = wait.PollImmediate(1*time.Second, 30*time.Second, func() (bool, error) {
+			lines, _ := s.kubectl(e.args...)
+			if len(lines) > 0 {
+				answer = lines[0]
+			}
+			return answer == e.expected, nil
+		})
+		if answer != e.expected {
+			return errors.Errorf("kubectl %s: expected %s, got %q", strings.Join(e.args, " "), e.expected, answer)
+		}
+		fmt.Printf("kubectl %s: %s\n", strings.Join(e.args, " "), answer)
+	}
+	return nil
+}
+
+func checkSmokeTestLogs(s *smokeTestContext) error {
+	lines, err := s.kubectl("logs", s.pods[0].Name)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%d logs lines returned\n", len(lines))
+	return nil
+}
+
+func checkSmokeTestExec(s *smokeTestContext) error {
+	lines, err := s.exec(s.pods[0].Name, "echo", smokeTestNamespace)
+	if err != nil {
+		return err
+	}
+	if len(lines) != 1 || lines[0] != smokeTestNamespace {
+		return errors.Errorf("unexpected output of kubectl exec: %s", strings.Join(lines, "\n"))
+	}
+	fmt.Println("kubectl exec returned the expected output")
+	return nil
+}
+
+func checkSmokeTestPortForward(s *smokeTestContext) error {
+	// port-forward runs in background while curl checks the forwarded port, retrying until port-forward is ready
+	script := fmt.Sprintf(
+		"kubectl --kubeconfig=/etc/kubernetes/admin.conf -n=%[1]s port-forward pod/%[2]s %[3]d:80 > /dev/null 2>&1 & pid=$!; "+
+			"for i in $(seq 10); do sleep 1; curl -s -o /dev/null -w '%%{http_code}\\n' http://127.0.0.1:%[3]d/ && break; done; "+
+			"kill $pid",
+		smokeTestNamespace, s.pods[0].Name, smokeTestPortForwardPort,
+	)
+	lines, err := s.cp1.Command("/bin/sh", "-c", script).Silent().RunAndCapture()
+	if err != nil {
+		return errors.Wrapf(err, "kubectl port-forward failed: %s", strings.Join(lines, "\n"))
+	}
+	if len(lines) == 0 || lines[len(lines)-1] != "200" {
+		return errors.Errorf("unexpected answer from the forwarded port: %s", strings.Join(lines, "\n"))
+	}
+	fmt.Printf("pod %s answered on the forwarded port %d\n", s.pods[0].Name, smokeTestPortForwardPort)
+	return nil
+}
+
+// parseSmokeTestPods takes output lines with name, node and IP of the smoke test pods and returns the corresponding list of pods
+func parseSmokeTestPods(lines []string) []smokeTestPod {
+	pods := []smokeTestPod{}
+	for _, l := range lines {
+		fields := strings.Fields(l)
+		if len(fields) != 3 {
+			continue
+		}
+		pods = append(pods, smokeTestPod{Name: fields[0], Node: fields[1], IP: fields[2]})
+	}
+	return pods
+}
+
+// hasSmokeTestAnswer checks if the output of nslookup contains an answer for the given name with the given address.
+// Both busybox and bind nslookup output formats are supported, e.g.
+//
+//	Name:      kubernetes.default.svc.cluster.local
+//	Address 1: 10.96.0.1 kubernetes.default.svc.cluster.local
+//
+//	Name:	kubernetes.default.svc.cluster.local
+//	Address: 10.96.0.1
+func hasSmokeTestAnswer(lines []string, name, address string) bool {
+	answer := false
+	for _, l := range lines {
+		fields := strings.Fields(l)
+		if len(fields) == 0 {
+			continue
+		}
+		if fields[0] == "Name:" {
+			answer = len(fields) > 1 && fields[1] == name
+			continue
+		}
+		if answer && strings.HasPrefix(fields[0], "Address") {
+			for _, f := range fields[1:] {
+				if f == address {
+					return true
+				}
+			}
+		}
+	}
+	return false
 }
@@ -0,0 +1,120 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"testing"
+)
+
+func TestHasSmokeTestAnswer(t *testing.T) {
+	tests := []struct {
+		name     string
+		lines    []string
+		expected bool
+	}{
+		{
+			name: "busybox format",
+			lines: []string{
+				"Server:    10.96.0.10",
+				"Address 1: 10.96.0.10 kube-dns.kube-system.svc.cluster.local",
+				"",
+				"Name:      kubernetes.default.svc.cluster.local",
+				"Address 1: 10.96.0.1 kubernetes.default.svc.cluster.local",
+			},
+			expected: true,
+		},
+		{
+			name: "bind format",
+			lines: []string{
+				"Server:\t\t10.96.0.10",
+				"Address:\t10.96.0.10#53",
+				"",
+				"Name:\tkubernetes.default.svc.cluster.local",
+				"Address: 10.96.0.1",
+			},
+			expected: true,
+		},
+		{
+			name: "server address only",
+			lines: []string{
+				"Server:    10.96.0.1",
+				"Address 1: 10.96.0.1",
+				"",
+				"nslookup: can't resolve 'kubernetes.default.svc.cluster.local'",
+			},
+			expected: false,
+		},
+		{
+			name: "wrong address",
+			lines: []string{
+				"Name:\tkubernetes.default.svc.cluster.local",
+				"Address: 10.96.0.2",
+			},
+			expected: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if answer := hasSmokeTestAnswer(test.lines, "kubernetes.default.svc.cluster.local", "10.96.0.1"); answer != test.expected {
+				t.Errorf("expected %t, got %t", test.expected, answer)
+			}
+		})
+	}
+}
+
+func TestSelectSmokeTests(t *testing.T) {
+	tests := []struct {
+		name          string
+		input         []string
+		expected      []string
+		expectedError bool
+	}{
+		{
+			name:     "all",
+			input:    []string{"all"},
+			expected: KnownSmokeTests(),
+		},
+		{
+			name:     "subset",
+			input:    []string{"dns", "rbac"},
+			expected: []string{"dns", "rbac"},
+		},
+		{
+			name:          "unknown",
+			input:         []string{"dns", "deployments"},
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			selected, err := SelectSmokeTests(test.input)
+			if (err != nil) != test.expectedError {
+				t.Fatalf("expected error %t, got %v", test.expectedError, err)
+			}
+			if len(selected) != len(test.expected) {
+				t.Fatalf("expected %v, got %v", test.expected, selected)
+			}
+			for i := range selected {
+				if selected[i] != test.expected[i] {
+					t.Errorf("expected %v, got %v", test.expected, selected)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,443 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/kubeadm/kinder/pkg/certs"
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+)
+
+// TimeTravel simulates the clock of the selected nodes moving forward by offset, for all the time based logic
+// kubeadm clusters depend on: the validity of the certificates signed by the cluster CAs - including kubelet client
+// certificates and client certificates embedded in kubeconfig files -, the validity of the cluster CAs and
+// the expiration of bootstrap tokens.
+//
+// The node clock itself can't be moved: K8s components are Go binaries reading the clock through the vDSO,
+// so libfaketime-like LD_PRELOAD tricks have no effect on them, and CLOCK_REALTIME is shared by all the
+// containers running on the host. Instead, time travel moves backwards by offset all the above timestamps:
+// certificates are signed again with the cluster CA keys, CA certificates are self-signed again with the same
+// keys, and bootstrap token expirations are updated; this is equivalent to the clock moving forward e.g. for
+// kubelet client certificate rotation, kubeadm certs check-expiration, CA expiry or bootstrap token TTLs.
+// CA keys are required on the bootstrap control-plane node.
+func TimeTravel(c *status.Cluster, offset, wait time.Duration) error {
+	if offset == 0 {
+		return errors.New("time-travel requires a non zero offset")
+	}
+
+	cp1 := c.BootstrapControlPlane()
+	cas, err := readCertificateAuthorities(cp1, offset)
+	if err != nil {
+		return err
+	}
+
+	// cluster wide timestamps are moved only if the bootstrap control-plane node is selected, and before
+	// moving certificates, because afterwards the API server might not be reachable anymore
+	travelCluster := isInNodeList(cp1, c.K8sNodes().EligibleForActions())
+	if travelCluster {
+		cp1.Infof("moving bootstrap token expirations and the cluster-info CA %s backwards", offset)
+		if !cp1.IsDryRun() {
+			if err := shiftBootstrapTokens(c, offset); err != nil {
+				return err
+			}
+			if err := shiftClusterInfo(c, cas); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, n := range c.K8sNodes().EligibleForActions() {
+		n.Infof("moving the validity of certificates %s backwards", offset)
+
+		expired := false
+		if !n.IsDryRun() {
+			expired, err = shiftNodeCertificates(n, cas, offset)
+			if err != nil {
+				return err
+			}
+		}
+
+		// restarts the kubelet and the static pods so the shifted certificates are picked up
+		if err := n.Command(
+			"systemctl", "restart", "kubelet",
+		).RunWithEcho(); err != nil {
+			return errors.Wrapf(err, "failed to restart the kubelet on %s", n.Name())
+		}
+
+		if !n.IsControlPlane() {
+			continue
+		}
+
+		components := []string{"kube-apiserver", "kube-controller-manager", "kube-scheduler"}
+		if c.ExternalEtcd() == nil {
+			components = append(components, "etcd")
+		}
+		if err := restartStaticPods(c, n, components, wait); err != nil {
+			return err
+		}
+
+		// if certificates are expired, control-plane components cannot become ready; this is expected
+		if expired {
+			log.Warnf("certificates on %s are expired after time travel, skipping wait for control-plane components", n.Name())
+			continue
+		}
+		if err := waitStaticPodsReady(c, n, components, wait); err != nil {
+			return err
+		}
+	}
+
+	// refresh the kubeconfig file on the host, so it uses the shifted admin.conf
+	if travelCluster && !cp1.IsDryRun() {
+		if err := copyKubeConfigToHost(c); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// certificateAuthority defines a CA certificate, the corresponding key, and the CA certificate
+// with the validity moved backwards by the time travel offset
+type certificateAuthority struct {
+	cert    *x509.Certificate
+	key     crypto.Signer
+	shifted *x509.Certificate
+}
+
+// readCertificateAuthorities reads from a node the cluster CAs with the corresponding keys, and self-signs
+// again the CA certificates moving their validity backwards by offset; CAs without a key, e.g. when using
+// external CA, are ignored
+func readCertificateAuthorities(n *status.Node, offset time.Duration) ([]certificateAuthority, error) {
+	if n.IsDryRun() {
+		return nil, nil
+	}
+
+	cas := []certificateAuthority{}
+	for _, name := range []string{"ca", "front-proxy-ca", "etcd/ca"} {
+		keyData, err := n.ReadFile(fmt.Sprintf("/etc/kubernetes/pki/%s.key", name))
+		if err != nil {
+			log.Debugf("CA %s ignored: %v", name, err)
+			continue
+		}
+		key, err := certs.ParsePrivateKey(keyData)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse the %s key", name)
+		}
+
+		certData, err := n.ReadFile(fmt.Sprintf("/etc/kubernetes/pki/%s.crt", name))
+		if err != nil {
+			return nil, err
+		}
+		caCerts, err := certs.ParseCertificates(certData)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse the %s certificate", name)
+		}
+
+		// NB. the shifted CA certificate is computed only once, so all the nodes get the same certificate
+		shifted, err := certs.ShiftCertificate(caCerts[0], caCerts[0], key, offset)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to shift the %s certificate", name)
+		}
+
+		cas = append(cas, certificateAuthority{cert: caCerts[0], key: key, shifted: shifted})
+	}
+
+	if len(cas) == 0 {
+		return nil, errors.Errorf("time-travel requires CA keys in /etc/kubernetes/pki on %s", n.Name())
+	}
+	return cas, nil
+}
+
+// shiftNodeCertificates moves backwards the validity of certificates and of certificates embedded in kubeconfig
+// files stored on a node; it returns true if any of the certificates is expired after time travel
+func shiftNodeCertificates(n *status.Node, cas []certificateAuthority, offset time.Duration) (bool, error) {
+	certFiles, err := n.Command(
+		"find", "/etc/kubernetes/pki", "-name", "*.crt",
+	).Silent().RunAndCapture()
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to list certificates on %s", n.Name())
+	}
+
+	// the kubelet client certificate is a symlink to a file containing both the certificate and the key
+	kubeletClientCert, err := n.Command(
+		"readlink", "-f", "/var/lib/kubelet/pki/kubelet-client-current.pem",
+	).Silent().RunAndCapture()
+	if err == nil && len(kubeletClientCert) == 1 {
+		certFiles = append(certFiles, kubeletClientCert[0])
+	}
+
+	kubeConfigFiles, err := n.Command(
+		"find", "/etc/kubernetes", "-maxdepth", "1", "-name", "*.conf",
+	).Silent().RunAndCapture()
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to list kubeconfig files on %s", n.Name())
+	}
+
+	expired := false
+	for _, f := range certFiles {
+		cert, err := shiftCertificateFile(n, f, cas, offset)
+		if err != nil {
+			return false, err
+		}
+		if cert != nil && time.Now().After(cert.NotAfter) {
+			expired = true
+		}
+	}
+
+	for _, f := range kubeConfigFiles {
+		cert, err := shiftKubeConfigFile(n, f, cas, offset)
+		if err != nil {
+			return false, err
+		}
+		if cert != nil && time.Now().After(cert.NotAfter) {
+			expired = true
+		}
+	}
+
+	return expired, nil
+}
+
+// shiftCertificateFile moves backwards the validity of the certificate in a PEM file; certificates
+// that are not cluster CAs or that are not signed by one of the cluster CAs are ignored
+func shiftCertificateFile(n *status.Node, path string, cas []certificateAuthority, offset time.Duration) (*x509.Certificate, error) {
+	data, err := n.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	fileCerts, err := certs.ParseCertificates(data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %s", path)
+	}
+
+	shifted, err := shiftCertificate(fileCerts[0], cas, offset)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to shift %s", path)
+	}
+	if shifted == nil {
+		return nil, nil
+	}
+
+	newData, err := certs.ReplaceCertificate(data, shifted)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to shift %s", path)
+	}
+	if err := n.WriteFile(path, newData); err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("Certificate %s shifted, NotAfter: %s\n", path, shifted.NotAfter.UTC())
+	return shifted, nil
+}
+
+// shiftKubeConfigFile moves backwards the validity of client certificates and of CA certificates embedded in
+// a kubeconfig file; kubeconfig files referencing external certificate files, e.g. kubelet.conf, are ignored
+func shiftKubeConfigFile(n *status.Node, path string, cas []certificateAuthority, offset time.Duration) (*x509.Certificate, error) {
+	data, err := n.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config, err := clientcmd.Load(data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %s", path)
+	}
+
+	changed, err := shiftKubeConfigCAs(config, cas)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to shift the CA certificates in %s", path)
+	}
+
+	var last *x509.Certificate
+	for user, authInfo := range config.AuthInfos {
+		if len(authInfo.ClientCertificateData) == 0 {
+			continue
+		}
+		userCerts, err := certs.ParseCertificates(authInfo.ClientCertificateData)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse the client certificate for user %s in %s", user, path)
+		}
+
+		shifted, err := shiftCertificate(userCerts[0], cas, offset)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to shift the client certificate for user %s in %s", user, path)
+		}
+		if shifted == nil {
+			continue
+		}
+
+		authInfo.ClientCertificateData, err = certs.ReplaceCertificate(authInfo.ClientCertificateData, shifted)
+		if err != nil {
+			return nil, err
+		}
+		last = shifted
+	}
+
+	if last == nil && !changed {
+		return nil, nil
+	}
+
+	newData, err := clientcmd.Write(*config)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to encode %s", path)
+	}
+	if err := n.WriteFile(path, newData); err != nil {
+		return nil, err
+	}
+
+	if last == nil {
+		fmt.Printf("Kubeconfig %s shifted\n", path)
+		return nil, nil
+	}
+	fmt.Printf("Kubeconfig %s shifted, NotAfter: %s\n", path, last.NotAfter.UTC())
+	return last, nil
+}
+
+// shiftKubeConfigCAs replaces the cluster CA certificates embedded in a kubeconfig with the corresponding
+// shifted CA certificates; it returns true if any CA certificate was replaced
+func shiftKubeConfigCAs(config *clientcmdapi.Config, cas []certificateAuthority) (bool, error) {
+	changed := false
+	for name, cluster := range config.Clusters {
+		if len(cluster.CertificateAuthorityData) == 0 {
+			continue
+		}
+		caCerts, err := certs.ParseCertificates(cluster.CertificateAuthorityData)
+		if err != nil {
+			return false, errors.Wrapf(err, "failed to parse the CA certificate for cluster %s", name)
+		}
+		shifted := shiftedCA(caCerts[0], cas)
+		if shifted == nil {
+			continue
+		}
+		cluster.CertificateAuthorityData, err = certs.ReplaceCertificate(cluster.CertificateAuthorityData, shifted)
+		if err != nil {
+			return false, err
+		}
+		changed = true
+	}
+	return changed, nil
+}
+
+// shiftBootstrapTokens moves backwards by offset the expiration of all the bootstrap tokens; expired
+// tokens are deleted afterwards by the token cleaner, like if the clock had moved forward
+func shiftBootstrapTokens(c *status.Cluster, offset time.Duration) error {
+	client, err := kubeClient(c)
+	if err != nil {
+		return err
+	}
+	secrets, err := client.CoreV1().Secrets(metav1.NamespaceSystem).List(metav1.ListOptions{
+		FieldSelector: fmt.Sprintf("type=%s", corev1.SecretTypeBootstrapToken),
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to list bootstrap tokens")
+	}
+
+	for i := range secrets.Items {
+		secret := &secrets.Items[i]
+		expiration, ok := secret.Data["expiration"]
+		if !ok {
+			// tokens without TTL are not affected by the clock
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, string(expiration))
+		if err != nil {
+			return errors.Wrapf(err, "failed to parse the expiration of bootstrap token %s", secret.Name)
+		}
+		shifted := t.Add(-offset).Format(time.RFC3339)
+		secret.Data["expiration"] = []byte(shifted)
+		if _, err := client.CoreV1().Secrets(metav1.NamespaceSystem).Update(secret); err != nil {
+			return errors.Wrapf(err, "failed to update bootstrap token %s", secret.Name)
+		}
+		fmt.Printf("Bootstrap token %s shifted, expiration: %s\n", secret.Name, shifted)
+	}
+	return nil
+}
+
+// shiftClusterInfo replaces the cluster CA certificate in the cluster-info ConfigMap with the shifted CA certificate;
+// the JWS signatures for bootstrap tokens are updated afterwards by the bootstrap signer
+func shiftClusterInfo(c *status.Cluster, cas []certificateAuthority) error {
+	client, err := kubeClient(c)
+	if err != nil {
+		return err
+	}
+	cm, err := client.CoreV1().ConfigMaps(metav1.NamespacePublic).Get("cluster-info", metav1.GetOptions{})
+	if err != nil {
+		return errors.Wrap(err, "failed to get the cluster-info ConfigMap")
+	}
+	config, err := clientcmd.Load([]byte(cm.Data["kubeconfig"]))
+	if err != nil {
+		return errors.Wrap(err, "failed to parse the kubeconfig in the cluster-info ConfigMap")
+	}
+
+	changed, err := shiftKubeConfigCAs(config, cas)
+	if err != nil {
+		return errors.Wrap(err, "failed to shift the CA certificate in the cluster-info ConfigMap")
+	}
+	if !changed {
+		return nil
+	}
+
+	data, err := clientcmd.Write(*config)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode the kubeconfig in the cluster-info ConfigMap")
+	}
+	cm.Data["kubeconfig"] = string(data)
+	if _, err := client.CoreV1().ConfigMaps(metav1.NamespacePublic).Update(cm); err != nil {
+		return errors.Wrap(err, "failed to update the cluster-info ConfigMap")
+	}
+	fmt.Println("ConfigMap cluster-info shifted")
+	return nil
+}
+
+// shiftCertificate signs again a certificate with the issuing CA moving its validity backwards by offset;
+// for cluster CA certificates, the shifted CA certificate is returned, while nil is returned for other
+// CA certificates or for certificates not issued by one of the given CAs
+func shiftCertificate(cert *x509.Certificate, cas []certificateAuthority, offset time.Duration) (*x509.Certificate, error) {
+	if cert.IsCA {
+		return shiftedCA(cert, cas), nil
+	}
+
+	for _, ca := range cas {
+		if certs.IsSignedBy(cert, ca.cert) {
+			return certs.ShiftCertificate(cert, ca.cert, ca.key, offset)
+		}
+	}
+
+	log.Debugf("certificate %s ignored: issuer %s is not a cluster CA", cert.Subject.CommonName, cert.Issuer)
+	return nil, nil
+}
+
+// shiftedCA returns the shifted CA certificate corresponding to a cluster CA certificate,
+// or nil if the certificate is not one of the given CAs
+func shiftedCA(cert *x509.Certificate, cas []certificateAuthority) *x509.Certificate {
+	for _, ca := range cas {
+		if bytes.Equal(cert.Raw, ca.cert.Raw) {
+			return ca.shifted
+		}
+	}
+	return nil
+}
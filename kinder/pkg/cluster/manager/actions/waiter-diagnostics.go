@@ -0,0 +1,225 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+)
+
+const (
+	// diagnosticsEvents defines the number of recent events printed when a wait times out
+	diagnosticsEvents = 20
+
+	// diagnosticsJournalLines defines the number of kubelet journal lines printed when a wait times out
+	diagnosticsJournalLines = 50
+)
+
+// printNodeDiagnostics prints information useful for investigating why a wait on a node timed out, that is
+// the node status, the status of the pods hosted on the node, the recent events related to the node and to its pods,
+// and the recent kubelet journal
+func printNodeDiagnostics(c *status.Cluster, n *status.Node) {
+	if !n.IsControlPlane() && !n.IsWorker() {
+		return
+	}
+	fmt.Printf("\nDiagnostics for node %s\n", n.Name())
+
+	client, err := kubeClient(c)
+	if err != nil {
+		fmt.Printf("failed to create a client for reading the cluster status: %v\n", err)
+	} else {
+		pods := printNodeStatus(client, n)
+		printRecentEvents(client, func(o corev1.ObjectReference) bool {
+			return (o.Kind == "Node" && o.Name == n.Name()) || (o.Kind == "Pod" && pods[o.Namespace+"/"+o.Name])
+		})
+	}
+
+	fmt.Printf("\nkubelet journal (last %d lines):\n", diagnosticsJournalLines)
+	lines, err := n.Command(
+		"journalctl", "-u", "kubelet", "--no-pager", "-n", fmt.Sprintf("%d", diagnosticsJournalLines),
+	).Silent().RunAndCapture()
+	if err != nil {
+		fmt.Printf("failed to read the kubelet journal: %v\n", err)
+	}
+	for _, l := range lines {
+		fmt.Println(l)
+	}
+}
+
+// printWorkloadsDiagnostics prints information useful for investigating why a wait on workloads timed out,
+// that is the replicas of each workload and the recent events related to the workloads
+func printWorkloadsDiagnostics(c *status.Cluster, workloads []workload) {
+	fmt.Println("\nDiagnostics for workloads")
+
+	client, err := kubeClient(c)
+	if err != nil {
+		fmt.Printf("failed to create a client for reading the cluster status: %v\n", err)
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "WORKLOAD\tDESIRED\tREADY")
+	names := map[string]bool{}
+	for _, wl := range workloads {
+		names[wl.Kind+"/"+wl.Namespace+"/"+wl.Name] = true
+		get, _, err := workloadClient(client, wl)
+		if err != nil {
+			fmt.Fprintf(w, "%s\t%v\t\n", wl, err)
+			continue
+		}
+		obj, err := get()
+		if err != nil {
+			fmt.Fprintf(w, "%s\t%v\t\n", wl, err)
+			continue
+		}
+		desired, ready, _ := workloadReplicas(obj)
+		fmt.Fprintf(w, "%s\t%d\t%d\n", wl, desired, ready)
+	}
+	w.Flush()
+
+	printRecentEvents(client, func(o corev1.ObjectReference) bool {
+		return names[o.Kind+"/"+o.Namespace+"/"+o.Name]
+	})
+}
+
+// printNodeStatus prints the node conditions and the status of the pods hosted on the node;
+// the pods hosted on the node are returned, using namespace/name as a key
+func printNodeStatus(client kubernetes.Interface, n *status.Node) map[string]bool {
+	node, err := client.CoreV1().Nodes().Get(n.Name(), metav1.GetOptions{})
+	if err != nil {
+		fmt.Printf("failed to get node %s: %v\n", n.Name(), err)
+	} else {
+		fmt.Println("\nNode conditions:")
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(w, "TYPE\tSTATUS\tREASON\tLAST TRANSITION\tMESSAGE")
+		for _, c := range node.Status.Conditions {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", c.Type, c.Status, c.Reason, c.LastTransitionTime.Format(time.RFC3339), c.Message)
+		}
+		w.Flush()
+	}
+
+	pods := map[string]bool{}
+	list, err := client.CoreV1().Pods(metav1.NamespaceAll).List(metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", n.Name()).String(),
+	})
+	if err != nil {
+		fmt.Printf("failed to list pods on node %s: %v\n", n.Name(), err)
+		return pods
+	}
+
+	fmt.Println("\nPods:")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "NAMESPACE\tNAME\tPHASE\tREADY\tRESTARTS\tPROBLEM")
+	for _, p := range list.Items {
+		pods[p.Namespace+"/"+p.Name] = true
+		ready, restarts := 0, int32(0)
+		for _, s := range p.Status.ContainerStatuses {
+			if s.Ready {
+				ready++
+			}
+			restarts += s.RestartCount
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d/%d\t%d\t%s\n", p.Namespace, p.Name, p.Status.Phase, ready, len(p.Spec.Containers), restarts, podProblem(&p))
+	}
+	w.Flush()
+	return pods
+}
+
+// printRecentEvents prints the most recent events involving the objects selected by the match function
+func printRecentEvents(client kubernetes.Interface, match func(corev1.ObjectReference) bool) {
+	list, err := client.CoreV1().Events(metav1.NamespaceAll).List(metav1.ListOptions{})
+	if err != nil {
+		fmt.Printf("failed to list events: %v\n", err)
+		return
+	}
+
+	events := recentEvents(list.Items, match, diagnosticsEvents)
+	fmt.Printf("\nRecent events (last %d):\n", diagnosticsEvents)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "LAST SEEN\tTYPE\tREASON\tOBJECT\tMESSAGE")
+	for _, e := range events {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s/%s\t%s\n", eventTime(e).Format(time.RFC3339), e.Type, e.Reason, strings.ToLower(e.InvolvedObject.Kind), e.InvolvedObject.Name, strings.TrimSpace(e.Message))
+	}
+	w.Flush()
+}
+
+// recentEvents returns the most recent events involving the objects selected by the match function,
+// sorted from the oldest to the newest
+func recentEvents(events []corev1.Event, match func(corev1.ObjectReference) bool, limit int) []corev1.Event {
+	selected := []corev1.Event{}
+	for _, e := range events {
+		if match(e.InvolvedObject) {
+			selected = append(selected, e)
+		}
+	}
+	sort.SliceStable(selected, func(i, j int) bool {
+		return eventTime(selected[i]).Before(eventTime(selected[j]))
+	})
+	if len(selected) > limit {
+		selected = selected[len(selected)-limit:]
+	}
+	return selected
+}
+
+// eventTime returns the last time an event was observed
+func eventTime(e corev1.Event) time.Time {
+	if !e.LastTimestamp.IsZero() {
+		return e.LastTimestamp.Time
+	}
+	if !e.EventTime.IsZero() {
+		return e.EventTime.Time
+	}
+	return e.CreationTimestamp.Time
+}
+
+// podProblem returns a short description of the reason why a pod is not ready, if any
+func podProblem(p *corev1.Pod) string {
+	for _, s := range p.Status.ContainerStatuses {
+		if s.Ready {
+			continue
+		}
+		switch {
+		case s.State.Waiting != nil:
+			return fmt.Sprintf("%s: %s %s", s.Name, s.State.Waiting.Reason, firstLine(s.State.Waiting.Message))
+		case s.State.Terminated != nil:
+			return fmt.Sprintf("%s: %s (exit code %d)", s.Name, s.State.Terminated.Reason, s.State.Terminated.ExitCode)
+		case s.LastTerminationState.Terminated != nil:
+			return fmt.Sprintf("%s: last terminated with %s (exit code %d)", s.Name, s.LastTerminationState.Terminated.Reason, s.LastTerminationState.Terminated.ExitCode)
+		default:
+			return fmt.Sprintf("%s: not ready", s.Name)
+		}
+	}
+	if p.Status.Reason != "" {
+		return fmt.Sprintf("%s %s", p.Status.Reason, firstLine(p.Status.Message))
+	}
+	return ""
+}
+
+// firstLine returns the first line of a message
+func firstLine(message string) string {
+	return strings.TrimSpace(strings.SplitN(message, "\n", 2)[0])
+}
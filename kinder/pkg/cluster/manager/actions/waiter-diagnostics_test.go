@@ -0,0 +1,151 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestRecentEvents(t *testing.T) {
+	base := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	event := func(name, kind, object string, offset time.Duration) corev1.Event {
+		return corev1.Event{
+			ObjectMeta:     metav1.ObjectMeta{Name: name},
+			InvolvedObject: corev1.ObjectReference{Kind: kind, Name: object},
+			LastTimestamp:  metav1.NewTime(base.Add(offset)),
+		}
+	}
+	events := []corev1.Event{
+		event("e1", "Node", "kinder-worker", 3*time.Second),
+		event("e2", "Pod", "kube-proxy-abc", 1*time.Second),
+		event("e3", "Node", "kinder-control-plane-1", 2*time.Second),
+		event("e4", "Node", "kinder-worker", 2*time.Second),
+		event("e5", "Node", "kinder-worker", 1*time.Second),
+	}
+	match := func(o corev1.ObjectReference) bool {
+		return o.Kind == "Node" && o.Name == "kinder-worker"
+	}
+
+	tests := []struct {
+		name     string
+		limit    int
+		expected []string
+	}{
+		{name: "all the matching events, sorted", limit: 10, expected: []string{"e5", "e4", "e1"}},
+		{name: "most recent events only", limit: 2, expected: []string{"e4", "e1"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			names := []string{}
+			for _, e := range recentEvents(events, match, test.limit) {
+				names = append(names, e.Name)
+			}
+			if !reflect.DeepEqual(names, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, names)
+			}
+		})
+	}
+}
+
+func TestPodProblem(t *testing.T) {
+	tests := []struct {
+		name     string
+		pod      corev1.Pod
+		expected string
+	}{
+		{
+			name: "ready pod",
+			pod: corev1.Pod{Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{
+				{Name: "etcd", Ready: true},
+			}}},
+			expected: "",
+		},
+		{
+			name: "waiting container",
+			pod: corev1.Pod{Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{
+				{Name: "etcd", Ready: true},
+				{Name: "kube-apiserver", State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff", Message: "back-off 10s\nrestarting"}}},
+			}}},
+			expected: "kube-apiserver: CrashLoopBackOff back-off 10s",
+		},
+		{
+			name: "terminated container",
+			pod: corev1.Pod{Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{
+				{Name: "kube-scheduler", State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: "Error", ExitCode: 1}}},
+			}}},
+			expected: "kube-scheduler: Error (exit code 1)",
+		},
+		{
+			name:     "pending pod",
+			pod:      corev1.Pod{Status: corev1.PodStatus{Reason: "Evicted", Message: "The node was low on resource"}},
+			expected: "Evicted The node was low on resource",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if problem := podProblem(&test.pod); problem != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, problem)
+			}
+		})
+	}
+}
+
+func TestWorkloadReplicas(t *testing.T) {
+	three := int32(3)
+	tests := []struct {
+		name            string
+		obj             runtime.Object
+		expectedDesired int32
+		expectedReady   int32
+		expectedOk      bool
+	}{
+		{
+			name:            "deployment",
+			obj:             &appsv1.Deployment{Spec: appsv1.DeploymentSpec{Replicas: &three}, Status: appsv1.DeploymentStatus{AvailableReplicas: 2}},
+			expectedDesired: 3, expectedReady: 2, expectedOk: true,
+		},
+		{
+			name:            "statefulset with default replicas",
+			obj:             &appsv1.StatefulSet{Status: appsv1.StatefulSetStatus{ReadyReplicas: 1}},
+			expectedDesired: 1, expectedReady: 1, expectedOk: true,
+		},
+		{
+			name:            "daemonset",
+			obj:             &appsv1.DaemonSet{Status: appsv1.DaemonSetStatus{DesiredNumberScheduled: 4, NumberReady: 4}},
+			expectedDesired: 4, expectedReady: 4, expectedOk: true,
+		},
+		{
+			name: "unsupported kind",
+			obj:  &corev1.Pod{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			desired, ready, ok := workloadReplicas(test.obj)
+			if desired != test.expectedDesired || ready != test.expectedReady || ok != test.expectedOk {
+				t.Errorf("expected %d, %d, %t, got %d, %d, %t", test.expectedDesired, test.expectedReady, test.expectedOk, desired, ready, ok)
+			}
+		})
+	}
+}
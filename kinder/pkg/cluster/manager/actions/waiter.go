@@ -24,30 +24,36 @@ import (
 	"time"
 
 	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
 
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	K8sVersion "k8s.io/apimachinery/pkg/util/version"
 	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/cni"
+	"k8s.io/kubeadm/kinder/pkg/cri"
+	"k8s.io/kubeadm/kinder/pkg/loadbalancer"
 )
 
 // waitNewControlPlaneNodeReady waits for a new control plane node reaching the target state after init/join
 func waitNewControlPlaneNodeReady(c *status.Cluster, n *status.Node, wait time.Duration) error {
-	n.Infof("waiting for Node and control-plane Pods to become Ready (timeout %s)", wait)
-	if pass := waitFor(c, n, wait,
+	conditions := []try{
 		nodeIsReady,
 		staticPodIsReady("kube-apiserver"),
 		staticPodIsReady("kube-controller-manager"),
 		staticPodIsReady("kube-scheduler"),
-	); !pass {
-		return errors.New("timeout: Node and control-plane did not reach target state")
 	}
-	fmt.Println()
-	return nil
-}
+	if c.ExternalEtcd() == nil {
+		conditions = append(conditions, etcdMemberIsHealthy)
+	}
+	if loadBalancerHasStats(c) {
+		conditions = append(conditions, loadBalancerBackendIsUp)
+	}
 
-func waitForPodsRunning(c *status.Cluster, n *status.Node, wait time.Duration, label string, replicas int) error {
-	if pass := waitFor(c, n, wait,
-		podsAreRunning(n, label, replicas),
-	); !pass {
+	n.Infof("waiting for Node and control-plane Pods to become Ready (timeout %s)", wait)
+	if pass := waitFor(c, n, wait, conditions...); !pass {
 		return errors.New("timeout: Node and control-plane did not reach target state")
 	}
 	fmt.Println()
@@ -66,6 +72,46 @@ func waitForNodePort(c *status.Cluster, n *status.Node, wait time.Duration, node
 	return nil
 }
 
+// workload defines a Kubernetes workload, that is a Deployment, a StatefulSet or a DaemonSet
+type workload struct {
+	Kind      string
+	Namespace string
+	Name      string
+}
+
+// workloadKinds defines the kinds of workloads supported by waiters
+var workloadKinds = map[string]bool{
+	"Deployment":  true,
+	"StatefulSet": true,
+	"DaemonSet":   true,
+}
+
+func (w workload) String() string {
+	return fmt.Sprintf("%s %s/%s", w.Kind, w.Namespace, w.Name)
+}
+
+// waitWorkloadsReady waits for a list of workloads to have all the desired replicas ready
+func waitWorkloadsReady(c *status.Cluster, n *status.Node, workloads []workload, wait time.Duration) error {
+	if len(workloads) == 0 {
+		return nil
+	}
+
+	names := []string{}
+	conditions := []try{}
+	for _, w := range workloads {
+		names = append(names, w.String())
+		conditions = append(conditions, workloadIsReady(w))
+	}
+
+	n.Infof("waiting for %s to become ready (timeout %s)", strings.Join(names, ", "), wait)
+	if pass := waitFor(c, n, wait, conditions...); !pass {
+		printWorkloadsDiagnostics(c, workloads)
+		return errors.New("timeout: workloads did not reach target state")
+	}
+	fmt.Println()
+	return nil
+}
+
 // waitNewWorkerNodeReady waits for a new control plane node reaching the target state after join
 func waitNewWorkerNodeReady(c *status.Cluster, n *status.Node, wait time.Duration) error {
 	n.Infof("waiting for Node to become Ready (timeout %s)", wait)
@@ -82,8 +128,37 @@ func waitNewWorkerNodeReady(c *status.Cluster, n *status.Node, wait time.Duratio
 func waitControlPlaneUpgraded(c *status.Cluster, n *status.Node, upgradeVersion *K8sVersion.Version, wait time.Duration) error {
 	version := kubernetesVersionToImageTag(upgradeVersion.String())
 
+	conditions := []try{
+		staticPodHasVersion("kube-apiserver", version),
+		staticPodHasVersion("kube-controller-manager", version),
+		staticPodHasVersion("kube-scheduler", version),
+	}
+	if c.ExternalEtcd() == nil {
+		conditions = append(conditions, etcdMemberIsHealthy)
+	}
+	if loadBalancerHasStats(c) {
+		conditions = append(conditions, loadBalancerBackendIsUp)
+	}
+
 	n.Infof("waiting for control-plane Pods to restart with the new version (timeout %s)", wait)
+	if pass := waitFor(c, n, wait, conditions...); !pass {
+		return errors.New("timeout: control-plane did not reach target state")
+	}
+	fmt.Println()
+	return nil
+}
+
+// waitControlPlaneRolledBack waits for a control plane node reaching the target state after a failed upgrade,
+// that is control-plane Pods are ready and running with the original version
+func waitControlPlaneRolledBack(c *status.Cluster, n *status.Node, currentVersion *K8sVersion.Version, wait time.Duration) error {
+	version := kubernetesVersionToImageTag(currentVersion.String())
+
+	n.Infof("waiting for control-plane Pods to be ready with the original version (timeout %s)", wait)
 	if pass := waitFor(c, n, wait,
+		nodeIsReady,
+		staticPodIsReady("kube-apiserver"),
+		staticPodIsReady("kube-controller-manager"),
+		staticPodIsReady("kube-scheduler"),
 		staticPodHasVersion("kube-apiserver", version),
 		staticPodHasVersion("kube-controller-manager", version),
 		staticPodHasVersion("kube-scheduler", version),
@@ -108,6 +183,41 @@ func waitKubeletUpgraded(c *status.Cluster, n *status.Node, upgradeVersion *K8sV
 	return nil
 }
 
+// waitNodeUpgraded waits for a node reaching the target state after a full upgrade workflow, that is
+// the node has the new kubeadm binary, it reports the new kubelet version and, in case of control-plane nodes,
+// control-plane Pods are running with the new version
+func waitNodeUpgraded(c *status.Cluster, n *status.Node, upgradeVersion *K8sVersion.Version, wait time.Duration) error {
+	if err := verifyKubeadmVersion(n, upgradeVersion.String()); err != nil {
+		return err
+	}
+
+	conditions := []try{
+		nodeIsReady,
+		nodeHasKubernetesVersion(upgradeVersion.String()),
+	}
+	if n.IsControlPlane() {
+		version := kubernetesVersionToImageTag(upgradeVersion.String())
+		conditions = append(conditions,
+			staticPodHasVersion("kube-apiserver", version),
+			staticPodHasVersion("kube-controller-manager", version),
+			staticPodHasVersion("kube-scheduler", version),
+		)
+		if c.ExternalEtcd() == nil {
+			conditions = append(conditions, etcdMemberIsHealthy)
+		}
+		if loadBalancerHasStats(c) {
+			conditions = append(conditions, loadBalancerBackendIsUp)
+		}
+	}
+
+	n.Infof("waiting for node and control-plane Pods to report the new version (timeout %s)", wait)
+	if pass := waitFor(c, n, wait, conditions...); !pass {
+		return errors.New("timeout: node did not reach target state")
+	}
+	fmt.Println()
+	return nil
+}
+
 // waitKubeletHasRBAC waits for the kubelet to have access to the expected config map
 // please note that this is a temporary workaround for a problem we are observing on upgrades while
 // executing node upgrades immediately after control-plane upgrade.
@@ -122,11 +232,109 @@ func waitKubeletHasRBAC(c *status.Cluster, n *status.Node, upgradeVersion *K8sVe
 	return nil
 }
 
+// waitEtcdMemberRemoved waits for the etcd member hosted on a node to be removed from the etcd cluster;
+// the etcd member list is read using the etcd static pod hosted on the observer node
+func waitEtcdMemberRemoved(c *status.Cluster, observer, n *status.Node, wait time.Duration) error {
+	observer.Infof("waiting for etcd member %s to be removed from the etcd cluster (timeout %s)", n.Name(), wait)
+	if pass := waitFor(c, observer, wait,
+		etcdMemberIsRemoved(n.Name()),
+	); !pass {
+		return errors.New("timeout: etcd member was not removed")
+	}
+	fmt.Println()
+	return nil
+}
+
+// waitStaticPodStopped waits for the containers of a static pod to be stopped on a node
+func waitStaticPodStopped(c *status.Cluster, n *status.Node, pod string, wait time.Duration) error {
+	n.Infof("waiting for %s to be stopped (timeout %s)", pod, wait)
+	if pass := waitFor(c, n, wait,
+		staticPodIsStopped(pod),
+	); !pass {
+		return errors.Errorf("timeout: %s was not stopped", pod)
+	}
+	fmt.Println()
+	return nil
+}
+
+// waitStaticPodsReady waits for a list of static pods to become Ready on a node
+func waitStaticPodsReady(c *status.Cluster, n *status.Node, pods []string, wait time.Duration) error {
+	if len(pods) == 0 {
+		return nil
+	}
+
+	n.Infof("waiting for %s to become Ready (timeout %s)", strings.Join(pods, ", "), wait)
+	conditions := []try{}
+	for _, p := range pods {
+		conditions = append(conditions, staticPodIsReady(p))
+	}
+	if pass := waitFor(c, n, wait, conditions...); !pass {
+		return errors.New("timeout: static Pods did not reach target state")
+	}
+	fmt.Println()
+	return nil
+}
+
+// waitBinariesReplaced waits for a node reaching the target state after replacing binaries and images,
+// that is the node is ready and, in case of updated static pod manifests, static pods are running with the new images
+func waitBinariesReplaced(c *status.Cluster, n *status.Node, images map[string]string, wait time.Duration) error {
+	conditions := []try{nodeIsReady}
+	for _, p := range staticPodComponents {
+		if image, ok := images[p]; ok {
+			conditions = append(conditions,
+				staticPodIsReady(p),
+				staticPodHasVersion(p, image),
+			)
+		}
+	}
+
+	n.Infof("waiting for node to be ready with the new binaries and images (timeout %s)", wait)
+	if pass := waitFor(c, n, wait, conditions...); !pass {
+		return errors.New("timeout: node did not reach target state")
+	}
+	fmt.Println()
+	return nil
+}
+
+// waitBootstrapTokenDeleted waits for the token cleaner to delete an expired bootstrap token
+func waitBootstrapTokenDeleted(c *status.Cluster, n *status.Node, token string, wait time.Duration) error {
+	n.Infof("waiting for the bootstrap token to expire and to be deleted (timeout %s)", wait)
+	if pass := waitFor(c, n, wait,
+		bootstrapTokenIsDeleted(bootstrapTokenID(token)),
+	); !pass {
+		return errors.New("timeout: bootstrap token not deleted")
+	}
+	fmt.Println()
+	return nil
+}
+
+// waitBootstrapTokenSigned waits for the bootstrap signer to sign the cluster-info ConfigMap with a new bootstrap token
+func waitBootstrapTokenSigned(c *status.Cluster, n *status.Node, token string, wait time.Duration) error {
+	n.Infof("waiting for the bootstrap token to be used for signing cluster-info (timeout %s)", wait)
+	if pass := waitFor(c, n, wait,
+		bootstrapTokenIsSigned(bootstrapTokenID(token)),
+	); !pass {
+		return errors.New("timeout: bootstrap token not used for signing cluster-info")
+	}
+	fmt.Println()
+	return nil
+}
+
+// waitChaosFault waits for the cluster reaching the expected state while a chaos fault is active
+func waitChaosFault(c *status.Cluster, n *status.Node, wait time.Duration, conditions ...try) error {
+	n.Infof("waiting for the cluster to react to the fault (timeout %s)", wait)
+	if pass := waitFor(c, n, wait, conditions...); !pass {
+		return errors.New("timeout: cluster did not reach the expected state while the fault was active")
+	}
+	fmt.Println()
+	return nil
+}
+
 // try defines a function that test a condition to be waited for
 type try func(*status.Cluster, *status.Node) bool
 
 // waitFor implements the waiter core logic that is responsible for testing all the given contitions
-// until are satisfied or a timeout are reached
+// until are satisfied or a timeout are reached; in case of timeout, diagnostics for the node are printed
 func waitFor(c *status.Cluster, n *status.Node, timeout time.Duration, conditions ...try) bool {
 	// if timeout is 0 or no conditions are defined, exit fast
 	if timeout == time.Duration(0) {
@@ -170,99 +378,210 @@ func waitFor(c *status.Cluster, n *status.Node, timeout time.Duration, condition
 				return true
 			}
 		case <-timer.C:
+			fmt.Printf("\n%d of %d conditions were not satisfied before timeout\n", len(conditions)-passed, len(conditions))
+			printNodeDiagnostics(c, n)
 			return false
 		}
 	}
 }
 
-// nodeIsReady implement a function that test when a node is ready
+// nodeIsReady implement a function that test when a node is ready.
+// If the cluster does not have a CNI plugin, nodes are not expected to become ready and the test is skipped
 func nodeIsReady(c *status.Cluster, n *status.Node) bool {
-	output := kubectlOutput(c.BootstrapControlPlane(),
-		"get",
-		"nodes",
-		"--kubeconfig=/etc/kubernetes/admin.conf",
-		// check for the selected node
-		fmt.Sprintf("-l=kubernetes.io/hostname=%s", n.Name()),
-		// check for status.conditions type:Ready
-		"-o=jsonpath='{.items..status.conditions[?(@.type == \"Ready\")].status}'",
-	)
-	if strings.Contains(output, "True") {
+	if c.Settings != nil && c.Settings.CNI == cni.None {
+		fmt.Printf("Node %s is not expected to be ready without a CNI plugin, skipping\n", n.Name())
+		return true
+	}
+
+	if watchNode(c, n.Name(), func(node *corev1.Node) bool {
+		return nodeReadyStatus(node) == corev1.ConditionTrue
+	}) {
 		fmt.Printf("Node %s is ready\n", n.Name())
 		return true
 	}
 	return false
 }
 
-// nodeHasKubernetesVersion implement a function that if a node is has the given Kubernetes version
-func nodeHasKubernetesVersion(version string) func(c *status.Cluster, n *status.Node) bool {
-	return func(c *status.Cluster, n *status.Node) bool {
-		output := kubectlOutput(c.BootstrapControlPlane(),
-			"get",
-			"nodes",
-			"--kubeconfig=/etc/kubernetes/admin.conf",
-			// check for the selected node
-			fmt.Sprintf("-l=kubernetes.io/hostname=%s", n.Name()),
-			// check for the kubelet version
-			"-o=jsonpath='{.items..status.nodeInfo.kubeletVersion}'",
-		)
-		if strings.Contains(output, version) {
-			fmt.Printf("Node %s has Kubernetes version %s\n", n.Name(), version)
+// loadBalancerBackendIsUp implement a function that test when the external load balancer considers a control plane node healthy.
+// If the cluster does not have an external load balancer, the load balancer does not support reporting backend status
+// or stats are not available, the test is skipped
+func loadBalancerBackendIsUp(c *status.Cluster, n *status.Node) bool {
+	if c.ExternalLoadBalancer() == nil {
+		return true
+	}
+	if impl, err := loadbalancer.Get(c.Settings.LoadBalancer); err != nil || !impl.SupportsBackendStatus() {
+		fmt.Printf("The load balancer does not report the status of %s, skipping\n", n.Name())
+		return true
+	}
+
+	backends, err := LoadBalancerBackends(c)
+	if err != nil {
+		fmt.Printf("Load balancer stats are not available (%v), skipping\n", err)
+		return true
+	}
+	for _, b := range backends {
+		if b.Name == n.Name() && b.IsUp() {
+			fmt.Printf("Load balancer backend %s is up\n", n.Name())
 			return true
 		}
-		return false
 	}
+	return false
 }
 
-// staticPodIsReady implement a function that test when a static pod is ready
-func staticPodIsReady(pod string) func(c *status.Cluster, n *status.Node) bool {
-	return func(c *status.Cluster, n *status.Node) bool {
-		output := kubectlOutput(c.BootstrapControlPlane(),
-			"get",
-			"pods",
-			"--kubeconfig=/etc/kubernetes/admin.conf",
-			"-n=kube-system",
-			// check for static pods existing on the selected node
-			fmt.Sprintf("%s-%s", pod, n.Name()),
-			// check for status.conditions type:Ready
-			"-o=jsonpath='{.status.conditions[?(@.type == \"Ready\")].status}'",
-		)
-		if strings.Contains(output, "True") {
-			fmt.Printf("Pod %s-%s is ready\n", pod, n.Name())
+// loadBalancerBackendIsDown implement a function that test when the external load balancer considers a control plane node
+// not healthy. If the cluster does not have an external load balancer, the load balancer does not support reporting
+// backend status or stats are not available, the test is skipped
+func loadBalancerBackendIsDown(c *status.Cluster, n *status.Node) bool {
+	if c.ExternalLoadBalancer() == nil {
+		return true
+	}
+	if impl, err := loadbalancer.Get(c.Settings.LoadBalancer); err != nil || !impl.SupportsBackendStatus() {
+		fmt.Printf("The load balancer does not report the status of %s, skipping\n", n.Name())
+		return true
+	}
+
+	backends, err := LoadBalancerBackends(c)
+	if err != nil {
+		fmt.Printf("Load balancer stats are not available (%v), skipping\n", err)
+		return true
+	}
+	for _, b := range backends {
+		if b.Name == n.Name() && !b.IsUp() {
+			fmt.Printf("Load balancer backend %s is %s\n", n.Name(), b.Status)
 			return true
 		}
+	}
+	return false
+}
+
+// nodeIsUnreachable implement a function that test when the node controller marks a node as unreachable,
+// that is the Ready condition is Unknown because the kubelet stopped posting the node status
+func nodeIsUnreachable(c *status.Cluster, n *status.Node) bool {
+	if watchNode(c, n.Name(), func(node *corev1.Node) bool {
+		return nodeReadyStatus(node) == corev1.ConditionUnknown
+	}) {
+		fmt.Printf("Node %s is NotReady\n", n.Name())
+		return true
+	}
+	return false
+}
+
+// apiServerIsReachable implement a function that test when the API server is reachable through the control plane
+// endpoint, that is the external load balancer or the control plane VIP, if any
+func apiServerIsReachable(c *status.Cluster, n *status.Node) bool {
+	client, err := kubeClient(c)
+	if err != nil {
+		log.Debugf("API server is not reachable: %v", err)
+		return false
+	}
+	output, err := client.Discovery().RESTClient().Get().AbsPath("/healthz").DoRaw()
+	if err != nil {
+		log.Debugf("API server is not reachable: %v", err)
 		return false
 	}
+	if string(output) == "ok" {
+		fmt.Println("API server is reachable via the control plane endpoint")
+		return true
+	}
+	return false
 }
 
-func podsAreRunning(n *status.Node, label string, replicas int) func(c *status.Cluster, n *status.Node) bool {
+// etcdQuorumIsKept implement a function that test when the etcd cluster has quorum, that is the etcd member hosted
+// on the observer node can commit a proposal. If the cluster uses external etcd, the test is skipped
+func etcdQuorumIsKept(observer *status.Node) func(c *status.Cluster, n *status.Node) bool {
 	return func(c *status.Cluster, n *status.Node) bool {
-		output := kubectlOutput(n,
-			"get",
-			"pods",
-			"--kubeconfig=/etc/kubernetes/admin.conf",
-			"-l", fmt.Sprintf("app=%s", label), "-o", "jsonpath='{.items[*].status.phase}'",
-		)
+		if c.ExternalEtcd() != nil {
+			return true
+		}
 
-		statuses := strings.Split(strings.Trim(output, "'"), " ")
+		e, err := newEtcdctl(c, observer)
+		if err != nil {
+			return false
+		}
+		if len(e.endpointHealth(nil)) > 0 {
+			fmt.Println("etcd cluster has quorum")
+			return true
+		}
+		return false
+	}
+}
 
-		// if pod number not yet converged, wait
-		if len(statuses) != replicas {
+// etcdLeaderChanged implement a function that test when the etcd member hosted on the observer node reports
+// a leader different from the given one
+func etcdLeaderChanged(observer *status.Node, leader uint64) func(c *status.Cluster, n *status.Node) bool {
+	return func(c *status.Cluster, n *status.Node) bool {
+		e, err := newEtcdctl(c, observer)
+		if err != nil {
+			return false
+		}
+		statuses, err := e.endpointsStatus(nil)
+		if err != nil || len(statuses) == 0 {
 			return false
 		}
+		if newLeader := statuses[0].Status.Leader; newLeader != 0 && newLeader != leader {
+			fmt.Printf("etcd elected a new leader %x\n", newLeader)
+			return true
+		}
+		return false
+	}
+}
 
-		// check for pods status
-		running := true
-		for j := 0; j < replicas; j++ {
-			if statuses[j] != "Running" {
-				running = false
-			}
+// workloadIsReady implement a function that test when all the desired replicas of a workload are ready;
+// DaemonSets are considered ready only if they have at least one Pod scheduled
+func workloadIsReady(w workload) func(c *status.Cluster, n *status.Node) bool {
+	return func(c *status.Cluster, n *status.Node) bool {
+		if watchWorkload(c, w, func(obj runtime.Object) bool {
+			desired, ready, ok := workloadReplicas(obj)
+			return ok && desired == ready && !(w.Kind == "DaemonSet" && desired == 0)
+		}) {
+			fmt.Printf("%s is ready\n", w)
+			return true
 		}
+		return false
+	}
+}
 
-		if running {
-			fmt.Printf("%d pods running!", replicas)
+// nodeHasKubernetesVersion implement a function that if a node is has the given Kubernetes version
+func nodeHasKubernetesVersion(version string) func(c *status.Cluster, n *status.Node) bool {
+	return func(c *status.Cluster, n *status.Node) bool {
+		if watchNode(c, n.Name(), func(node *corev1.Node) bool {
+			return strings.Contains(node.Status.NodeInfo.KubeletVersion, version)
+		}) {
+			fmt.Printf("Node %s has Kubernetes version %s\n", n.Name(), version)
 			return true
 		}
+		return false
+	}
+}
+
+// verifyKubeadmVersion checks that the kubeadm binary on a node has the given version; the kubeadm binary
+// is not part of the cluster state, so it is checked once before waiting for the cluster to reach the target state
+func verifyKubeadmVersion(n *status.Node, version string) error {
+	lines, err := n.Command(
+		"kubeadm", "version", "-o=short",
+	).Silent().RunAndCapture()
+	if err != nil {
+		return errors.Wrapf(err, "failed to get the kubeadm version on node %s", n.Name())
+	}
+	if n.IsDryRun() {
+		return nil
+	}
+	if len(lines) != 1 || strings.TrimPrefix(lines[0], "v") != strings.TrimPrefix(version, "v") {
+		return errors.Errorf("node %s has kubeadm version %s, expected %s", n.Name(), strings.Join(lines, " "), version)
+	}
+	fmt.Printf("Node %s has kubeadm version %s\n", n.Name(), version)
+	return nil
+}
 
+// staticPodIsReady implement a function that test when a static pod is ready
+func staticPodIsReady(pod string) func(c *status.Cluster, n *status.Node) bool {
+	return func(c *status.Cluster, n *status.Node) bool {
+		if watchPod(c, metav1.NamespaceSystem, fmt.Sprintf("%s-%s", pod, n.Name()), func(p *corev1.Pod) bool {
+			return podReadyStatus(p) == corev1.ConditionTrue
+		}) {
+			fmt.Printf("Pod %s-%s is ready\n", pod, n.Name())
+			return true
+		}
 		return false
 	}
 }
@@ -293,22 +612,42 @@ func nodePortIsReady(n *status.Node, port string) func(c *status.Cluster, n *sta
 	}
 }
 
-// staticPodHasVersion implement a function that if a static pod is has the given Kubernetes version
+// staticPodIsStopped implement a function that test when a static pod is stopped, that is when the pod
+// containers are not running anymore on the node; the check is executed on the node, because the API server
+// could be one of the stopped pods
+func staticPodIsStopped(pod string) func(c *status.Cluster, n *status.Node) bool {
+	return func(c *status.Cluster, n *status.Node) bool {
+		nodeCRI, err := n.CRI()
+		if err != nil {
+			return false
+		}
+
+		actionHelper, err := cri.NewActionHelper(nodeCRI)
+		if err != nil {
+			return false
+		}
+
+		containers, err := actionHelper.GetRunningContainers(n, pod)
+		if err != nil {
+			return false
+		}
+
+		if len(containers) == 0 {
+			fmt.Printf("Pod %s-%s is stopped\n", pod, n.Name())
+			return true
+		}
+		return false
+	}
+}
+
+// staticPodHasVersion implement a function that test when a static pod is running the given version
 func staticPodHasVersion(pod, version string) func(c *status.Cluster, n *status.Node) bool {
 	return func(c *status.Cluster, n *status.Node) bool {
-		output := kubectlOutput(c.BootstrapControlPlane(),
-			"get",
-			"pods",
-			"--kubeconfig=/etc/kubernetes/admin.conf",
-			"-n=kube-system",
-			// check for static pods existing on the selected node
-			fmt.Sprintf("%s-%s", pod, n.Name()),
-			// check for the node image
+		if watchPod(c, metav1.NamespaceSystem, fmt.Sprintf("%s-%s", pod, n.Name()), func(p *corev1.Pod) bool {
 			// NB. this assumes the Pod has only one container only
 			// which is true for the control plane pods
-			"-o=jsonpath='{.spec.containers[0].image}'",
-		)
-		if strings.Contains(output, version) {
+			return len(p.Spec.Containers) > 0 && strings.Contains(p.Spec.Containers[0].Image, version)
+		}) {
 			fmt.Printf("Pod %s-%s has Kubernetes version %s\n", pod, n.Name(), version)
 			return true
 		}
@@ -316,6 +655,106 @@ func staticPodHasVersion(pod, version string) func(c *status.Cluster, n *status.
 	}
 }
 
+// bootstrapTokenIsSigned implements a function that tests if the cluster-info ConfigMap is signed with a bootstrap token
+func bootstrapTokenIsSigned(tokenID string) func(c *status.Cluster, n *status.Node) bool {
+	return func(c *status.Cluster, n *status.Node) bool {
+		if signed, err := clusterInfoIsSignedWith(c, tokenID); err != nil || !signed {
+			return false
+		}
+		fmt.Printf("cluster-info is signed with bootstrap token %s\n", tokenID)
+		return true
+	}
+}
+
+// bootstrapTokenIsDeleted implements a function that tests if the Secret storing a bootstrap token does not exist anymore
+// and if the cluster-info ConfigMap is not signed with the token anymore
+func bootstrapTokenIsDeleted(tokenID string) func(c *status.Cluster, n *status.Node) bool {
+	return func(c *status.Cluster, n *status.Node) bool {
+		client, err := kubeClient(c)
+		if err != nil {
+			return false
+		}
+		_, err = client.CoreV1().Secrets(metav1.NamespaceSystem).Get(fmt.Sprintf("bootstrap-token-%s", tokenID), metav1.GetOptions{})
+		if !apierrors.IsNotFound(err) {
+			return false
+		}
+		if signed, err := clusterInfoIsSignedWith(c, tokenID); err != nil || signed {
+			return false
+		}
+		fmt.Printf("Bootstrap token %s is deleted\n", tokenID)
+		return true
+	}
+}
+
+// clusterInfoIsSignedWith returns true if the cluster-info ConfigMap contains a signature for the given bootstrap token
+func clusterInfoIsSignedWith(c *status.Cluster, tokenID string) (bool, error) {
+	client, err := kubeClient(c)
+	if err != nil {
+		return false, err
+	}
+	cm, err := client.CoreV1().ConfigMaps(metav1.NamespacePublic).Get("cluster-info", metav1.GetOptions{})
+	if err != nil {
+		return false, err
+	}
+	_, ok := cm.Data[fmt.Sprintf("jws-kubeconfig-%s", tokenID)]
+	return ok, nil
+}
+
+// etcdMemberIsRemoved implements a function that tests if an etcd member is not listed anymore in the
+// etcd cluster member list
+func etcdMemberIsRemoved(name string) func(c *status.Cluster, n *status.Node) bool {
+	return func(c *status.Cluster, n *status.Node) bool {
+		e, err := newEtcdctl(c, n)
+		if err != nil {
+			return false
+		}
+		members, err := e.members()
+		if err != nil || len(members) == 0 {
+			return false
+		}
+
+		for _, m := range members {
+			if m.Name == name {
+				return false
+			}
+		}
+
+		fmt.Printf("etcd member %s is not listed in the etcd cluster member list\n", name)
+		return true
+	}
+}
+
+// etcdMemberIsHealthy implements a function that tests if the etcd member hosted on a node is a started, voting
+// member of the etcd cluster and if its endpoint is healthy; the etcd cluster is observed from the bootstrap control-plane
+func etcdMemberIsHealthy(c *status.Cluster, n *status.Node) bool {
+	observer := c.BootstrapControlPlane()
+	e, err := newEtcdctl(c, observer)
+	if err != nil {
+		return false
+	}
+	members, err := e.members()
+	if err != nil {
+		return false
+	}
+
+	for _, m := range members {
+		if m.Name != n.Name() || m.IsLearner {
+			continue
+		}
+
+		healthy := e.endpointHealth(m.ClientURLs)
+		for _, u := range m.ClientURLs {
+			if !healthy[u] {
+				return false
+			}
+		}
+
+		fmt.Printf("etcd member %s is healthy\n", n.Name())
+		return true
+	}
+	return false
+}
+
 // kubeletHasRBAC is a test checking that kubelet has reliable access to kubelet-config-x.y and kube-proxy,
 // where reliable = it have access for 5 seconds in a row.
 //
@@ -329,27 +768,11 @@ func staticPodHasVersion(pod, version string) func(c *status.Cluster, n *status.
 func kubeletHasRBAC(major, minor uint) func(c *status.Cluster, n *status.Node) bool {
 	return func(c *status.Cluster, n *status.Node) bool {
 		for i := 0; i < 5; i++ {
-			output1 := kubectlOutput(n,
-				"auth",
-				"can-i",
-				"get",
-				"--kubeconfig=/etc/kubernetes/kubelet.conf",
-				"--namespace=kube-system",
-				fmt.Sprintf("configmaps/kubelet-config-%d.%d", major, minor),
-			)
-			output2 := kubectlOutput(n,
-				"auth",
-				"can-i",
-				"get",
-				"--kubeconfig=/etc/kubernetes/kubelet.conf",
-				"--namespace=kube-system",
-				"configmaps/kube-proxy",
-			)
-			if output1 == "yes" && output2 == "yes" {
-				time.Sleep(1 * time.Second)
-				continue
+			if !nodeCanGetConfigMap(n, fmt.Sprintf("kubelet-config-%d.%d", major, minor)) ||
+				!nodeCanGetConfigMap(n, "kube-proxy") {
+				return false
 			}
-			return false
+			time.Sleep(1 * time.Second)
 		}
 
 		fmt.Println("kubelet has access to expected config maps")
@@ -357,21 +780,20 @@ func kubeletHasRBAC(major, minor uint) func(c *status.Cluster, n *status.Node) b
 	}
 }
 
-// kubectlOutput implements a utility function that runs a kubectl command on the given node
-// and captures the command output
-func kubectlOutput(n *status.Node, args ...string) string {
+// nodeCanGetConfigMap returns true if the kubelet credentials of the node, that is the kubelet.conf file,
+// allow to get the given ConfigMap in the kube-system namespace; the check is executed on the node
+func nodeCanGetConfigMap(n *status.Node, name string) bool {
 	lines, err := n.Command(
-		"kubectl",
-		args...,
+		"kubectl", "auth", "can-i", "get",
+		"--kubeconfig=/etc/kubernetes/kubelet.conf",
+		"--namespace=kube-system",
+		fmt.Sprintf("configmaps/%s", name),
 	).Silent().RunAndCapture()
-	if err != nil {
-		return ""
-	}
-	if len(lines) != 1 {
-		return ""
+	if err != nil || len(lines) != 1 {
+		log.Debugf("failed to check access to configmap %s for node %s: %v", name, n.Name(), err)
+		return false
 	}
-
-	return lines[0]
+	return strings.TrimSpace(lines[0]) == "yes"
 }
 
 // kubernetesVersionToImageTag is helper function that replaces all
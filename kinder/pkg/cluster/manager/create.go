@@ -27,6 +27,7 @@ import (
 	"k8s.io/kubeadm/kinder/pkg/constants"
 	"k8s.io/kubeadm/kinder/pkg/cri"
 	"k8s.io/kubeadm/kinder/pkg/exec"
+	"k8s.io/kubeadm/kinder/pkg/loadbalancer"
 	kinddocker "sigs.k8s.io/kind/pkg/container/docker"
 )
 
@@ -37,8 +38,13 @@ type CreateOptions struct {
 	image                string
 	externalLoadBalancer bool
 	externalEtcd         bool
+	externalEtcdMembers  int
 	retain               bool
 	volumes              []string
+	cni                  string
+	loadBalancer         string
+	loadBalancerVerifyCA bool
+	controlPlaneVIP      bool
 }
 
 // CreateOption is a configuration option supplied to Create
@@ -72,6 +78,13 @@ func ExternalEtcd(externalEtcd bool) CreateOption {
 	}
 }
 
+// ExternalEtcdMembers instruct create to add a TLS secured external etcd cluster with the given number of members
+func ExternalEtcdMembers(members int) CreateOption {
+	return func(c *CreateOptions) {
+		c.externalEtcdMembers = members
+	}
+}
+
 // ExternalLoadBalancer instruct create to add an external loadbalancer to the cluster.
 // NB. this happens automatically when there are more than two control plane instances, but with this flag
 // it is possible to override the default behaviour
@@ -95,6 +108,37 @@ func Volumes(volumes []string) CreateOption {
 	}
 }
 
+// CNI option instructs create cluster about the CNI plugin to be installed after kubeadm init
+func CNI(cni string) CreateOption {
+	return func(c *CreateOptions) {
+		c.cni = cni
+	}
+}
+
+// LoadBalancer option instructs create cluster about the load balancer implementation to be used
+// for the external load balancer
+func LoadBalancer(loadBalancer string) CreateOption {
+	return func(c *CreateOptions) {
+		c.loadBalancer = loadBalancer
+	}
+}
+
+// LoadBalancerVerifyCA option instructs create cluster to setup the external load balancer for verifying
+// control plane nodes against the cluster CA when executing health checks
+func LoadBalancerVerifyCA(verifyCA bool) CreateOption {
+	return func(c *CreateOptions) {
+		c.loadBalancerVerifyCA = verifyCA
+	}
+}
+
+// ControlPlaneVIP option instructs create cluster to use a VIP managed by keepalived static pods on control plane nodes
+// as a control plane endpoint, instead of an external load balancer
+func ControlPlaneVIP(controlPlaneVIP bool) CreateOption {
+	return func(c *CreateOptions) {
+		c.controlPlaneVIP = controlPlaneVIP
+	}
+}
+
 // CreateCluster creates a new kinder cluster
 func CreateCluster(clusterName string, options ...CreateOption) error {
 	flags := &CreateOptions{}
@@ -102,6 +146,22 @@ func CreateCluster(clusterName string, options ...CreateOption) error {
 		o(flags)
 	}
 
+	if flags.externalEtcd && flags.externalEtcdMembers > 0 {
+		return errors.New("external etcd and external etcd members are mutually exclusive")
+	}
+
+	if flags.controlPlaneVIP && flags.externalLoadBalancer {
+		return errors.New("control plane VIP and external load balancer are mutually exclusive")
+	}
+
+	lb, err := loadbalancer.Get(flags.loadBalancer)
+	if err != nil {
+		return err
+	}
+	if flags.loadBalancerVerifyCA && !lb.SupportsCAVerification() {
+		return errors.Errorf("the %s load balancer does not support CA-verified health checks", lb.Name)
+	}
+
 	// Check if the cluster name already exists
 	known, err := status.IsKnown(clusterName)
 	if err != nil {
@@ -162,6 +222,7 @@ func createNodes(clusterName string, flags *CreateOptions) error {
 	if flags.externalEtcd {
 		numberOfNodes++
 	}
+	numberOfNodes += flags.externalEtcdMembers
 	fmt.Printf("Preparing nodes %s\n", strings.Repeat("📦", numberOfNodes))
 
 	// detect CRI runtime installed into images before actually creating nodes
@@ -178,6 +239,30 @@ func createNodes(clusterName string, flags *CreateOptions) error {
 		return err
 	}
 
+	lb, err := loadbalancer.Get(flags.loadBalancer)
+	if err != nil {
+		return err
+	}
+
+	// if requested, selects a VIP on the docker network to be used as a control plane endpoint
+	var vip string
+	if flags.controlPlaneVIP {
+		vip, err = selectControlPlaneVIP(nodesNetwork)
+		if err != nil {
+			return err
+		}
+		log.Infof("Using %s as a control plane VIP", vip)
+	}
+
+	// cluster settings that will be re-used by kinder during the cluster lifecycle are stored in a label of the nodes
+	settings := &status.ClusterSettings{
+		IPFamily:             status.IPv4Family, // support for ipv6 is still WIP
+		CNI:                  flags.cni,
+		LoadBalancer:         lb.Name,
+		LoadBalancerVerifyCA: flags.loadBalancerVerifyCA,
+		ControlPlaneVIP:      vip,
+	}
+
 	// create all of the node containers, concurrently
 	fns := []func() error{}
 	for _, desiredNode := range desiredNodes {
@@ -185,9 +270,9 @@ func createNodes(clusterName string, flags *CreateOptions) error {
 		fns = append(fns, func() error {
 			switch desiredNode.Role {
 			case constants.ExternalLoadBalancerNodeRoleValue:
-				return createHelper.CreateExternalLoadBalancer(clusterName, desiredNode.Name)
+				return createHelper.CreateExternalLoadBalancer(clusterName, desiredNode.Name, lb.Image)
 			case constants.ControlPlaneNodeRoleValue, constants.WorkerNodeRoleValue:
-				return createHelper.CreateNode(clusterName, desiredNode.Name, flags.image, desiredNode.Role, flags.volumes)
+				return createHelper.CreateNode(clusterName, desiredNode.Name, flags.image, desiredNode.Role, flags.volumes, settings)
 			default:
 				return nil
 			}
@@ -200,7 +285,7 @@ func createNodes(clusterName string, flags *CreateOptions) error {
 	}
 
 	// add an external etcd if explicitly requested
-	if flags.externalEtcd {
+	if flags.externalEtcd || flags.externalEtcdMembers > 0 {
 		log.Info("Getting required etcd image...")
 		c, err := status.FromDocker(clusterName)
 		if err != nil {
@@ -216,9 +301,28 @@ func createNodes(clusterName string, flags *CreateOptions) error {
 		// we don't care if this errors, we'll still try to run which also pulls
 		_, _ = kinddocker.PullIfNotPresent(etcdImage, 4)
 
-		log.Info("Creating external etcd...")
-		if err := createHelper.CreateExternalEtcd(clusterName, fmt.Sprintf("%s-etcd", clusterName), etcdImage); err != nil {
-			return err
+		if flags.externalEtcd {
+			log.Info("Creating external etcd...")
+			if err := createHelper.CreateExternalEtcd(clusterName, fmt.Sprintf("%s-etcd", clusterName), etcdImage); err != nil {
+				return err
+			}
+		}
+
+		if flags.externalEtcdMembers > 0 {
+			log.Info("Creating external etcd cluster...")
+			for i := 1; i <= flags.externalEtcdMembers; i++ {
+				if err := createHelper.CreateExternalEtcdMember(clusterName, fmt.Sprintf("%s-etcd%d", clusterName, i), flags.image); err != nil {
+					return err
+				}
+			}
+
+			c, err := status.FromDocker(clusterName)
+			if err != nil {
+				return err
+			}
+			if err := setupExternalEtcdCluster(c, etcdImage); err != nil {
+				return err
+			}
 		}
 	}
 
@@ -228,14 +332,6 @@ func createNodes(clusterName string, flags *CreateOptions) error {
 		return err
 	}
 
-	// writes to the nodes the cluster settings that will be re-used by kinder during the cluster lifecycle.
-	c.Settings = &status.ClusterSettings{
-		IPFamily: status.IPv4Family, // support for ipv6 is still WIP
-	}
-	if err := c.WriteSettings(); err != nil {
-		return err
-	}
-
 	// writes to the nodes the node settings
 	for _, n := range c.K8sNodes() {
 		if err := n.WriteNodeSettings(&status.NodeSettings{}); err != nil {
@@ -253,6 +349,9 @@ type nodeSpec struct {
 	Role string
 }
 
+// nodesNetwork defines the docker network node containers are attached to, that is the default docker network
+const nodesNetwork = "bridge"
+
 // nodesToCreate return the list of nodes to create for the cluster
 func nodesToCreate(clusterName string, flags *CreateOptions) []nodeSpec {
 	var desiredNodes []nodeSpec
@@ -276,7 +375,7 @@ func nodesToCreate(clusterName string, flags *CreateOptions) []nodeSpec {
 	}
 
 	// add an external load balancer if explicitly requested or if there are multiple control planes
-	if flags.externalLoadBalancer || flags.controlPlanes > 1 {
+	if (flags.externalLoadBalancer || flags.controlPlanes > 1) && !flags.controlPlaneVIP {
 		role := constants.ExternalLoadBalancerNodeRoleValue
 		desiredNodes = append(desiredNodes, nodeSpec{
 			Name: fmt.Sprintf("%s-lb", clusterName),
@@ -315,3 +414,66 @@ func untilError(funcs []func() error) error {
 	}
 	return nil
 }
+
+// selectControlPlaneVIP returns a free address on the given docker network, excluding the control plane VIPs
+// of other kinder clusters recorded in the node labels.
+// NB. VIPs can't be reserved outside of the IPAM pool of the docker default bridge network, so addresses
+// are selected from the end of the subnet, that docker allocates last
+func selectControlPlaneVIP(network string) (string, error) {
+	// gets the subnet and the addresses already in use
+	lines, err := exec.NewHostCmd("docker", "network", "inspect", "-f", "{{range .IPAM.Config}}{{.Subnet}} {{end}}", network).RunAndCapture()
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to get subnets for docker network %s", network)
+	}
+	var subnet string
+	for _, s := range strings.Fields(strings.Join(lines, " ")) {
+		if !strings.Contains(s, ":") {
+			subnet = s
+			break
+		}
+	}
+	if subnet == "" {
+		return "", errors.Errorf("docker network %s does not have an IPv4 subnet", network)
+	}
+
+	lines, err = exec.NewHostCmd("docker", "network", "inspect", "-f", "{{range .Containers}}{{.IPv4Address}} {{end}}", network).RunAndCapture()
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to get addresses in use for docker network %s", network)
+	}
+	var used []string
+	for _, l := range lines {
+		used = append(used, strings.Fields(l)...)
+	}
+
+	vips, err := controlPlaneVIPsInUse()
+	if err != nil {
+		return "", err
+	}
+
+	return loadbalancer.SelectVIP(subnet, used, vips)
+}
+
+// controlPlaneVIPsInUse returns the control plane VIPs of the existing kinder clusters, including stopped ones
+func controlPlaneVIPsInUse() ([]string, error) {
+	lines, err := exec.NewHostCmd(
+		"docker", "ps", "-a",
+		"--filter", fmt.Sprintf("label=%s", constants.ClusterSettingsLabelKey),
+		"--format", fmt.Sprintf("{{.Label %q}}", constants.ClusterSettingsLabelKey),
+	).RunAndCapture()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the settings of existing kinder clusters")
+	}
+
+	var vips []string
+	for _, l := range lines {
+		settings, err := status.ClusterSettingsFromLabel(strings.TrimSpace(l))
+		if err != nil {
+			log.Warnf("skipping invalid cluster settings %q: %v", l, err)
+			continue
+		}
+		if settings.ControlPlaneVIP != "" {
+			vips = append(vips, settings.ControlPlaneVIP)
+		}
+	}
+	return vips, nil
+}
@@ -0,0 +1,290 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package manager
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"k8s.io/kubeadm/kinder/pkg/certs"
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/constants"
+	"k8s.io/kubeadm/kinder/pkg/cri/util"
+	"k8s.io/kubeadm/kinder/pkg/exec"
+)
+
+// externalEtcdConfigTemplate defines the config file for TLS secured external etcd members
+const externalEtcdConfigTemplate = `name: {{ .Name }}
+data-dir: /var/lib/etcd
+listen-client-urls: https://0.0.0.0:2379
+advertise-client-urls: {{ .ClientURL }}
+listen-peer-urls: https://0.0.0.0:2380
+initial-advertise-peer-urls: {{ .PeerURL }}
+initial-cluster: {{ .InitialCluster }}
+initial-cluster-state: new
+initial-cluster-token: {{ .ClusterName }}-etcd
+client-transport-security:
+  cert-file: {{ .PKIDir }}/server.crt
+  key-file: {{ .PKIDir }}/server.key
+  trusted-ca-file: {{ .PKIDir }}/ca.crt
+  client-cert-auth: true
+peer-transport-security:
+  cert-file: {{ .PKIDir }}/peer.crt
+  key-file: {{ .PKIDir }}/peer.key
+  trusted-ca-file: {{ .PKIDir }}/ca.crt
+  client-cert-auth: true
+`
+
+// externalEtcdConfigData defines the data used for generating the config file for external etcd members
+type externalEtcdConfigData struct {
+	Name           string
+	ClientURL      string
+	PeerURL        string
+	InitialCluster string
+	ClusterName    string
+	PKIDir         string
+}
+
+// setupExternalEtcdCluster generates certificates and config files for the members of a TLS secured
+// external etcd cluster, thus allowing the etcd members to start; then, it copies to the control-plane
+// nodes the certificates required for connecting to the external etcd cluster.
+// Please note that certificates are generated on the host, and the etcd CA key is not stored anywhere.
+func setupExternalEtcdCluster(c *status.Cluster, etcdImage string) error {
+	log.Info("Configuring external etcd cluster...")
+
+	if err := copyExternalEtcdBinaries(c, etcdImage); err != nil {
+		return err
+	}
+
+	etcdCA, etcdCAKey, err := certs.NewCA("etcd-ca", nil, nil)
+	if err != nil {
+		return err
+	}
+
+	// gets the IPs of all the etcd members; the IPv4 address is used in etcd URLs if available,
+	// while both the IPv4 and the IPv6 addresses are added to the certificate SANs
+	addresses := map[string]string{}
+	ips := map[string][]net.IP{}
+	initialCluster := []string{}
+	for _, n := range c.ExternalEtcds() {
+		ipv4, ipv6, err := n.IP()
+		if err != nil {
+			return errors.Wrapf(err, "failed to get IP for node: %s", n.Name())
+		}
+		for _, ip := range []string{ipv4, ipv6} {
+			if ip != "" {
+				ips[n.Name()] = append(ips[n.Name()], net.ParseIP(ip))
+			}
+		}
+		if len(ips[n.Name()]) == 0 {
+			return errors.Errorf("node %s does not have an IP address", n.Name())
+		}
+		addresses[n.Name()] = ips[n.Name()][0].String()
+		initialCluster = append(initialCluster, fmt.Sprintf("%s=%s", n.Name(), externalEtcdURL(addresses[n.Name()], 2380)))
+	}
+
+	// generates a client certificate to be used by the API server and by etcd health checks
+	clientCert, clientKey, err := certs.NewSignedCertificate(certs.CertificateConfig{
+		CommonName:   "kube-apiserver-etcd-client",
+		Organization: []string{"system:masters"},
+		Usages:       []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, etcdCA, etcdCAKey)
+	if err != nil {
+		return err
+	}
+	clientCertData, clientKeyData, err := encodeCertificateAndKey(clientCert, clientKey)
+	if err != nil {
+		return err
+	}
+
+	for _, n := range c.ExternalEtcds() {
+		files := map[string][]byte{
+			"ca.crt":                 certs.EncodeCertificates(etcdCA),
+			"healthcheck-client.crt": clientCertData,
+			"healthcheck-client.key": clientKeyData,
+		}
+		for _, name := range []string{"server", "peer"} {
+			cert, key, err := certs.NewSignedCertificate(certs.CertificateConfig{
+				CommonName:  n.Name(),
+				DNSNames:    []string{n.Name(), "localhost"},
+				IPAddresses: append([]net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback}, ips[n.Name()]...),
+				Usages:      []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+			}, etcdCA, etcdCAKey)
+			if err != nil {
+				return err
+			}
+			certData, keyData, err := encodeCertificateAndKey(cert, key)
+			if err != nil {
+				return err
+			}
+			files[name+".crt"] = certData
+			files[name+".key"] = keyData
+		}
+
+		if err := n.Command("mkdir", "-p", constants.ExternalEtcdPKIDir).Silent().Run(); err != nil {
+			return errors.Wrapf(err, "failed to create %s on node %s", constants.ExternalEtcdPKIDir, n.Name())
+		}
+		for name, data := range files {
+			if err := n.WriteFile(filepath.Join(constants.ExternalEtcdPKIDir, name), data); err != nil {
+				return err
+			}
+		}
+
+		config, err := externalEtcdConfig(externalEtcdConfigData{
+			Name:           n.Name(),
+			ClientURL:      externalEtcdURL(addresses[n.Name()], 2379),
+			PeerURL:        externalEtcdURL(addresses[n.Name()], 2380),
+			InitialCluster: strings.Join(initialCluster, ","),
+			ClusterName:    c.Name(),
+			PKIDir:         constants.ExternalEtcdPKIDir,
+		})
+		if err != nil {
+			return err
+		}
+
+		// writes the config file in a temporary location, and then moves it to the final destination;
+		// this ensures the etcd member starts only when the config file is complete
+		if err := n.WriteFile(constants.ExternalEtcdConfig+".tmp", config); err != nil {
+			return err
+		}
+		if err := n.Command(
+			"mv", constants.ExternalEtcdConfig+".tmp", constants.ExternalEtcdConfig,
+		).Silent().Run(); err != nil {
+			return errors.Wrapf(err, "failed to write %s on node %s", constants.ExternalEtcdConfig, n.Name())
+		}
+	}
+
+	if err := waitExternalEtcdClusterHealthy(c); err != nil {
+		return err
+	}
+
+	// copies the certificates for connecting to the external etcd cluster to all the control-plane nodes
+	for _, n := range c.ControlPlanes() {
+		if err := n.Command("mkdir", "-p", filepath.Dir(constants.ExternalEtcdCAFile)).Silent().Run(); err != nil {
+			return errors.Wrapf(err, "failed to create pki folder on node %s", n.Name())
+		}
+		if err := n.WriteFile(constants.ExternalEtcdCAFile, certs.EncodeCertificates(etcdCA)); err != nil {
+			return err
+		}
+		if err := n.WriteFile(constants.ExternalEtcdCertFile, clientCertData); err != nil {
+			return err
+		}
+		if err := n.WriteFile(constants.ExternalEtcdKeyFile, clientKeyData); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// waitExternalEtcdClusterHealthy waits for all the members of the external etcd cluster to be healthy
+func waitExternalEtcdClusterHealthy(c *status.Cluster) error {
+	for _, n := range c.ExternalEtcds() {
+		log.Infof("Waiting for etcd member %s to be healthy...", n.Name())
+		healthy := util.TryUntil(time.Now().Add(2*time.Minute), func() bool {
+			err := n.Command(
+				filepath.Join(constants.ExternalEtcdBinDir, "etcdctl"),
+				"--endpoints=https://127.0.0.1:2379",
+				fmt.Sprintf("--cacert=%s/ca.crt", constants.ExternalEtcdPKIDir),
+				fmt.Sprintf("--cert=%s/healthcheck-client.crt", constants.ExternalEtcdPKIDir),
+				fmt.Sprintf("--key=%s/healthcheck-client.key", constants.ExternalEtcdPKIDir),
+				"endpoint", "health",
+			).Silent().Run()
+			if err != nil {
+				time.Sleep(1 * time.Second)
+				return false
+			}
+			return true
+		})
+		if !healthy {
+			return errors.Errorf("timeout: etcd member %s is not healthy", n.Name())
+		}
+	}
+	return nil
+}
+
+// copyExternalEtcdBinaries copies the etcd binaries from the etcd image to all the external etcd members;
+// binaries are extracted from a container created, but never started, from the etcd image
+func copyExternalEtcdBinaries(c *status.Cluster, etcdImage string) error {
+	tmpDir, err := ioutil.TempDir("", "kinder-etcd-")
+	if err != nil {
+		return errors.Wrap(err, "failed to create a temporary folder for etcd binaries")
+	}
+	defer os.RemoveAll(tmpDir)
+
+	container := fmt.Sprintf("%s-etcd-binaries", c.Name())
+	if err := exec.NewHostCmd("docker", "create", "--name", container, etcdImage, "etcd").Run(); err != nil {
+		return errors.Wrapf(err, "failed to create a container from image %s", etcdImage)
+	}
+	defer func() {
+		_ = exec.NewHostCmd("docker", "rm", "-f", container).Run()
+	}()
+
+	for _, binary := range []string{"etcd", "etcdctl"} {
+		src := filepath.Join(tmpDir, binary)
+		if err := exec.NewHostCmd("docker", "cp", fmt.Sprintf("%s:/usr/local/bin/%s", container, binary), src).Run(); err != nil {
+			return errors.Wrapf(err, "failed to copy %s from image %s", binary, etcdImage)
+		}
+		for _, n := range c.ExternalEtcds() {
+			if err := n.CopyTo(src, filepath.Join(constants.ExternalEtcdBinDir, binary)); err != nil {
+				return errors.Wrapf(err, "failed to copy %s to node %s", binary, n.Name())
+			}
+		}
+	}
+	return nil
+}
+
+// externalEtcdURL returns the URL for an external etcd member listening on the given address and port
+func externalEtcdURL(address string, port int) string {
+	return fmt.Sprintf("https://%s", net.JoinHostPort(address, strconv.Itoa(port)))
+}
+
+// externalEtcdConfig returns the config file for an external etcd member
+func externalEtcdConfig(data externalEtcdConfigData) ([]byte, error) {
+	t, err := template.New("external-etcd-config").Parse(externalEtcdConfigTemplate)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse external etcd config template")
+	}
+
+	var buff bytes.Buffer
+	if err := t.Execute(&buff, data); err != nil {
+		return nil, errors.Wrap(err, "failed to execute external etcd config template")
+	}
+	return buff.Bytes(), nil
+}
+
+// encodeCertificateAndKey returns the PEM encoding of a certificate and of the corresponding key
+func encodeCertificateAndKey(cert *x509.Certificate, key crypto.Signer) ([]byte, []byte, error) {
+	keyData, err := certs.EncodePrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return certs.EncodeCertificates(cert), keyData, nil
+}
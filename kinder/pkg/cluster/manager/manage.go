@@ -50,13 +50,14 @@ func NewClusterManager(clusterName string) (c *ClusterManager, err error) {
 		return nil, err
 	}
 
-	// Validate the cluster has a consistent set of nodes
-	if err := x.Validate(); err != nil {
+	// Read the cluster setting saved by kinder at creation time
+	// nb. settings are read before validating the cluster, because the expected set of nodes depends on settings
+	if err := x.ReadSettings(); err != nil {
 		return nil, err
 	}
 
-	// Read the cluster setting saved by kinder at creation time
-	if err := x.ReadSettings(); err != nil {
+	// Validate the cluster has a consistent set of nodes
+	if err := x.Validate(); err != nil {
 		return nil, err
 	}
 
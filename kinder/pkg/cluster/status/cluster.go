@@ -17,6 +17,7 @@ limitations under the License.
 package status
 
 import (
+	"encoding/json"
 	"fmt"
 	"path/filepath"
 	"strings"
@@ -38,7 +39,7 @@ type Cluster struct {
 	k8sNodes             NodeList
 	controlPlanes        NodeList
 	workers              NodeList
-	externalEtcd         *Node
+	externalEtcds        NodeList
 	externalLoadBalancer *Node
 }
 
@@ -52,6 +53,47 @@ type ClusterSettings struct {
 	// kind configuration settings that are used to configure the cluster when
 	// generating the kubeadm config file.
 	IPFamily ClusterIPFamily `json:"ipFamily,omitempty"`
+
+	// CNI defines the CNI plugin to be installed after kubeadm init;
+	// if empty, the default CNI plugin is used.
+	CNI string `json:"cni,omitempty"`
+
+	// LoadBalancer defines the load balancer implementation used by the external load balancer;
+	// if empty, the default load balancer implementation is used.
+	LoadBalancer string `json:"loadBalancer,omitempty"`
+
+	// LoadBalancerVerifyCA instructs the external load balancer to verify the API server certificate
+	// of control plane nodes against the cluster CA when executing health checks.
+	LoadBalancerVerifyCA bool `json:"loadBalancerVerifyCA,omitempty"`
+
+	// ControlPlaneVIP defines a virtual IP on the cluster docker network used as a control plane endpoint;
+	// the VIP is managed by keepalived static pods on control plane nodes instead of an external load balancer.
+	ControlPlaneVIP string `json:"controlPlaneVIP,omitempty"`
+}
+
+// Label returns the cluster settings encoded as a value of a container label
+func (s *ClusterSettings) Label() (string, error) {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to encode cluster settings")
+	}
+	return string(b), nil
+}
+
+// ClusterSettingsFromLabel returns the cluster settings encoded in the value of a container label;
+// if the value is empty, default settings are returned
+func ClusterSettingsFromLabel(value string) (*ClusterSettings, error) {
+	settings := ClusterSettings{
+		IPFamily: IPv4Family,
+	}
+	if value == "" || value == "<no value>" {
+		return &settings, nil
+	}
+
+	if err := json.Unmarshal([]byte(value), &settings); err != nil {
+		return nil, errors.Wrap(err, "failed to decode cluster settings")
+	}
+	return &settings, nil
 }
 
 // ClusterIPFamily defines cluster network IP family
@@ -137,6 +179,7 @@ func FromDocker(name string) (c *Cluster, err error) {
 	c.k8sNodes.Sort()
 	c.controlPlanes.Sort()
 	c.workers.Sort()
+	c.externalEtcds.Sort()
 
 	return c, nil
 }
@@ -182,8 +225,9 @@ func (c *Cluster) Validate() error {
 	if c.BootstrapControlPlane() == nil {
 		return errors.Errorf("please add at least one node with role %q", constants.ControlPlaneNodeRoleValue)
 	}
-	// There should be one load balancer if more than one control plane exists in the cluster
-	if len(c.ControlPlanes()) > 1 && c.ExternalLoadBalancer() == nil {
+	// There should be one load balancer if more than one control plane exists in the cluster,
+	// unless control plane nodes share a VIP
+	if len(c.ControlPlanes()) > 1 && c.ExternalLoadBalancer() == nil && (c.Settings == nil || c.Settings.ControlPlaneVIP == "") {
 		return errors.Errorf("please add a node with role %s because in the cluster there are more than one node with role %s",
 			constants.ExternalLoadBalancerNodeRoleValue, constants.ControlPlaneNodeRoleValue)
 	}
@@ -194,6 +238,9 @@ func (c *Cluster) Validate() error {
 // ReadSettings read cluster settings from a control-plane node
 func (c *Cluster) ReadSettings() (err error) {
 	log.Debug("Reading cluster settings...")
+	if c.BootstrapControlPlane() == nil {
+		return errors.Errorf("please add at least one node with role %q", constants.ControlPlaneNodeRoleValue)
+	}
 	c.Settings, err = c.BootstrapControlPlane().ReadClusterSettings()
 	if err != nil {
 		return errors.Wrapf(err, "failed to read cluster settings from node %s", c.BootstrapControlPlane().name)
@@ -201,17 +248,6 @@ func (c *Cluster) ReadSettings() (err error) {
 	return nil
 }
 
-// WriteSettings writes cluster settings nodes
-func (c *Cluster) WriteSettings() error {
-	log.Debug("Writings cluster settings...")
-	for _, n := range c.K8sNodes() {
-		if err := n.WriteClusterSettings(c.Settings); err != nil {
-			return errors.Wrapf(err, "failed to write cluster settings to node %s", n.name)
-		}
-	}
-	return nil
-}
-
 // add a Node to the Cluster, filling the derived list of Node by role
 func (c *Cluster) add(node *Node) error {
 	c.allNodes = append(c.allNodes, node)
@@ -229,10 +265,7 @@ func (c *Cluster) add(node *Node) error {
 	}
 
 	if node.IsExternalEtcd() {
-		if c.externalEtcd != nil {
-			return errors.Errorf("unable to add the node to the cluster. A cluster can not have more than one node with role %q", constants.ExternalEtcdNodeRoleValue)
-		}
-		c.externalEtcd = node
+		c.externalEtcds = append(c.externalEtcds, node)
 	}
 
 	if node.IsExternalLoadBalancer() {
@@ -283,9 +316,17 @@ func (c *Cluster) Workers() NodeList {
 	return c.workers
 }
 
-// ExternalEtcd returns the node with external-etcd role, if defined
+// ExternalEtcd returns the first node with external-etcd role, if defined
 func (c *Cluster) ExternalEtcd() *Node {
-	return c.externalEtcd
+	if len(c.externalEtcds) == 0 {
+		return nil
+	}
+	return c.externalEtcds[0]
+}
+
+// ExternalEtcds returns all the nodes with external-etcd role, if any
+func (c *Cluster) ExternalEtcds() NodeList {
+	return c.externalEtcds
 }
 
 // ExternalLoadBalancer returns the node with external-load-balancer role, if defined
@@ -334,7 +375,7 @@ func (c *Cluster) SelectNodes(nodeSelector string) (nodes NodeList, err error) {
 		case "@lb":
 			return toNodeList(c.ExternalLoadBalancer()), nil
 		case "@etcd":
-			return toNodeList(c.ExternalEtcd()), nil
+			return c.ExternalEtcds(), nil
 		default:
 			return nil, errors.Errorf("Invalid node selector %q. Use one of [@all, @cp*, @cp1, @cpn, @w*, @lb, @etcd]", nodeSelector)
 		}
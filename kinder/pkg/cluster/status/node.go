@@ -53,6 +53,7 @@ type Node struct {
 	cri             ContainerRuntime
 	etcdImage       string
 	skip            bool
+	dryRun          bool
 	commandMutators []commandMutator
 }
 
@@ -117,6 +118,18 @@ func (n *Node) IsExternalLoadBalancer() bool {
 	return n.Role() == constants.ExternalLoadBalancerNodeRoleValue
 }
 
+// IsSecureExternalEtcd returns true if the node hosts a member of a TLS secured external etcd cluster
+func (n *Node) IsSecureExternalEtcd() (bool, error) {
+	lines, err := kinddocker.Inspect(n.name, fmt.Sprintf("{{index .Config.Labels %q}}", constants.ExternalEtcdSecureLabelKey))
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to get %q label", constants.ExternalEtcdSecureLabelKey)
+	}
+	if len(lines) != 1 {
+		return false, errors.Errorf("%q label should only be one line, got %d lines", constants.ExternalEtcdSecureLabelKey, len(lines))
+	}
+	return strings.Trim(lines[0], "'") == "true", nil
+}
+
 // ProvisioningOrder returns the provisioning order for nodes, that
 // should be defined according to the assigned Role; is used to get consistent
 // and repeatable ordering in the list of nodes
@@ -161,6 +174,8 @@ func (n *Node) SkipActions() {
 // DryRun differs from SkipRun, because in case of DryRun kinder prints all the details for running
 // the command manually.
 func (n *Node) DryRun() {
+	n.dryRun = true
+
 	if n.commandMutators == nil {
 		n.commandMutators = []commandMutator{}
 	}
@@ -172,6 +187,11 @@ func (n *Node) DryRun() {
 	)
 }
 
+// IsDryRun returns true if the node is dry running all the commands.
+func (n *Node) IsDryRun() bool {
+	return n.dryRun
+}
+
 // Infof print an information message in the same format of commands on the node;
 // the message is print after the prompt containing the kind (er) node name.
 func (n *Node) Infof(message string, args ...interface{}) {
@@ -234,57 +254,71 @@ func (n *Node) EtcdImage() (string, error) {
 	return n.etcdImage, nil
 }
 
-//const clusterSettingsPath = "/kinder/cluster-settings.yaml"
-
-// WriteClusterSettings stores in the node a set of cluster-wide settings that will be re-used
-// by kinder during the cluster lifecycle (after create)
-func (n *Node) WriteClusterSettings(settings *ClusterSettings) error {
-	/*
-		Temporarily disabled because we are observing flakes related to this operation
-
-			s, err := ksigsyaml.Marshal(*settings)
-			if err != nil {
-				return errors.Wrapf(err, "failed to encode %s", clusterSettingsPath)
-			}
-
-			err = n.Command(
-				"mkdir", "-p", filepath.Dir(clusterSettingsPath),
-			).Silent().Run()
-			if err != nil {
-				return errors.Wrapf(err, "failed to write %s", clusterSettingsPath)
-			}
+// ReadClusterSettings reads from the node container labels a set of cluster-wide settings that
+// are going to be re-used by kinder during the cluster lifecycle (after create).
+// Settings are stored in a label set at node creation time instead of a file in the node, because
+// reading/writing files in the node was flaky (https://github.com/kubernetes/kubeadm/issues/1918);
+// if the node does not have the label, e.g. because it was created by an older version of kinder,
+// settings are read from the legacy settings file.
+func (n *Node) ReadClusterSettings() (*ClusterSettings, error) {
+	lines, err := kinddocker.Inspect(n.name, fmt.Sprintf("{{index .Config.Labels %q}}", constants.ClusterSettingsLabelKey))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get %q label", constants.ClusterSettingsLabelKey)
+	}
 
-			if err := n.WriteFile(clusterSettingsPath, s); err != nil {
-				return err
-			}
-	*/
-	return nil
+	value := strings.Trim(strings.Join(lines, ""), "'")
+	if value == "" || value == "<no value>" {
+		log.Debugf("%q label does not exist, reading legacy cluster settings", constants.ClusterSettingsLabelKey)
+		return n.readLegacyClusterSettings()
+	}
+	return ClusterSettingsFromLabel(value)
 }
 
-// ReadClusterSettings reads from the node a set of cluster-wide settings that
-// are going to be re-used by kinder during the cluster lifecycle (after create)
-func (n *Node) ReadClusterSettings() (*ClusterSettings, error) {
-	/*
-		Temporarily disabled because we are observing flakes related to this operation
-
-			lines, err := n.Command(
-				"cat", clusterSettingsPath,
-			).Silent().RunAndCapture()
-			if err != nil {
-				return nil, errors.Wrapf(err, "failed to read %s", clusterSettingsPath)
-			}
+// legacyClusterSettingsPath defines the file where cluster settings were stored by older versions of kinder
+const legacyClusterSettingsPath = "/kinder/cluster-settings.yaml"
 
-			var settings ClusterSettings
-			err = ksigsyaml.Unmarshal([]byte(strings.Join(lines, "\n")), &settings)
-			if err != nil {
-				return nil, errors.Wrapf(err, "failed to decode %s", clusterSettingsPath)
+// readLegacyClusterSettings reads cluster settings from the file used by older versions of kinder;
+// if the file does not exist, e.g. because the node was created before kinder stored cluster settings,
+// default settings are returned, while other errors are reported in order to not use wrong settings
+func (n *Node) readLegacyClusterSettings() (*ClusterSettings, error) {
+	var lines []string
+	var lastError error
+	attempts := 0
+	// Retry the operation to avoid flakes:
+	// https://github.com/kubernetes/kubeadm/issues/1918
+	err := wait.PollImmediate(time.Second*1, time.Second*20, func() (bool, error) {
+		attempts++
+		log.Debugf("Reading cluster settings at %s (attempt %d)...", legacyClusterSettingsPath, attempts)
+		var err error
+		lines, err = n.Command(
+			"cat", legacyClusterSettingsPath,
+		).Silent().RunAndCapture()
+		if err != nil {
+			if strings.Contains(strings.Join(lines, "\n"), "No such file or directory") {
+				lines = nil
+				return true, nil
 			}
+			lastError = errors.Wrapf(err, "failed to read %s", legacyClusterSettingsPath)
+			return false, nil
+		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, lastError
+	}
 
-			return &settings, nil
-	*/
-	return &ClusterSettings{
+	settings := ClusterSettings{
 		IPFamily: IPv4Family,
-	}, nil
+	}
+	if lines == nil {
+		log.Debugf("%s does not exist, using default cluster settings", legacyClusterSettingsPath)
+		return &settings, nil
+	}
+
+	if err := ksigsyaml.Unmarshal([]byte(strings.Join(lines, "\n")), &settings); err != nil {
+		return nil, errors.Wrapf(err, "failed to decode %s", legacyClusterSettingsPath)
+	}
+	return &settings, nil
 }
 
 const nodeSettingsPath = "/kinder/node-settings.yaml"
@@ -457,6 +491,17 @@ func (n *Node) WriteFile(containerPath string, contents []byte) error {
 	return nil
 }
 
+// ReadFile reads a text file from the node container
+func (n *Node) ReadFile(containerPath string) ([]byte, error) {
+	lines, err := n.Command(
+		"cat", containerPath,
+	).Silent().RunAndCapture()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %s", containerPath)
+	}
+	return []byte(strings.Join(lines, "\n")), nil
+}
+
 // KubeVersion returns the Kubernetes version installed on the node
 func (n *Node) KubeVersion() (version string, err error) {
 	// grab kubernetes version from the node image
@@ -0,0 +1,179 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cni
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	K8sVersion "k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/kubeadm/kinder/pkg/data"
+)
+
+const (
+	// Calico installs the Calico CNI plugin
+	Calico = "calico"
+	// Kindnet installs the kindnet CNI plugin, the same used by kind
+	Kindnet = "kindnet"
+	// Flannel installs the flannel CNI plugin
+	Flannel = "flannel"
+	// None does not install any CNI plugin; please note that in this case nodes are not expected to become Ready
+	None = "none"
+
+	// filePrefix defines the prefix for the CNI plugins installed from a manifest file on the host
+	filePrefix = "file:"
+
+	calicoPodSubnet  = "192.168.0.0/16"
+	defaultPodSubnet = "10.244.0.0/16"
+)
+
+// Plugin defines a release of a CNI plugin with an embedded manifest
+type Plugin struct {
+	// Name of the CNI plugin, e.g. calico
+	Name string
+
+	// Version of the CNI plugin release
+	Version string
+
+	// Manifest for installing the CNI plugin release
+	Manifest string
+
+	// DaemonSet defines the name of the DaemonSet in the kube-system namespace that runs the CNI plugin on nodes
+	DaemonSet string
+
+	// MinKubernetesVersion defines the first Kubernetes version supported by the CNI plugin release
+	MinKubernetesVersion *K8sVersion.Version
+
+	// MaxKubernetesVersion defines the first Kubernetes version not supported anymore by the CNI plugin release;
+	// nil means that there are no known incompatibilities with newer Kubernetes versions
+	MaxKubernetesVersion *K8sVersion.Version
+}
+
+// plugins defines the CNI plugin releases embedded in kinder; releases for the same plugin should be
+// ordered from the newest to the oldest
+var plugins = []Plugin{
+	{
+		Name:                 Calico,
+		Version:              "v3.8.2",
+		Manifest:             data.CalicoCNI3_8_2,
+		DaemonSet:            "calico-node",
+		MinKubernetesVersion: K8sVersion.MustParseSemantic("v1.13.0-0"),
+		// Calico v3.8 uses apiextensions.k8s.io/v1beta1 CRDs, that are not served anymore since Kubernetes v1.22
+		MaxKubernetesVersion: K8sVersion.MustParseSemantic("v1.22.0-0"),
+	},
+	{
+		Name:                 Kindnet,
+		Version:              "v20200725-4d6bea59",
+		Manifest:             data.KindnetCNI20200725,
+		DaemonSet:            "kindnet",
+		MinKubernetesVersion: K8sVersion.MustParseSemantic("v1.13.0-0"),
+	},
+	{
+		Name:                 Flannel,
+		Version:              "v0.15.1",
+		Manifest:             data.FlannelCNI0_15_1,
+		DaemonSet:            "kube-flannel-ds",
+		MinKubernetesVersion: K8sVersion.MustParseSemantic("v1.16.0-0"),
+	},
+}
+
+// KnownCNIs returns the list of CNI plugins supported by kinder
+func KnownCNIs() []string {
+	return []string{Calico, Kindnet, Flannel, None, fmt.Sprintf("%sPATH", filePrefix)}
+}
+
+// Validate checks if the given CNI plugin is supported by kinder
+func Validate(cni string) error {
+	switch cni {
+	case "", Calico, Kindnet, Flannel, None:
+		return nil
+	}
+	if path, ok := ManifestFile(cni); ok {
+		if path == "" {
+			return errors.Errorf("CNI plugin %q should include the path of a manifest file", cni)
+		}
+		return nil
+	}
+	return errors.Errorf("unknown CNI plugin %q. Use one of %s", cni, KnownCNIs())
+}
+
+// ManifestFile returns the path of the manifest file on the host for CNI plugins installed
+// from a manifest file, e.g. file:/path/to/cni.yaml
+func ManifestFile(cni string) (string, bool) {
+	if !strings.HasPrefix(cni, filePrefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(cni, filePrefix), true
+}
+
+// FromFile returns the CNI plugin for installing a manifest file from the host
+func FromFile(path string) string {
+	return filePrefix + path
+}
+
+// Default returns the CNI plugin installed when no CNI plugin is selected: Calico, if kinder embeds a Calico release
+// compatible with the given Kubernetes version, otherwise kindnet
+func Default(kubeVersion *K8sVersion.Version) string {
+	if _, err := SelectPlugin(Calico, kubeVersion); err == nil {
+		return Calico
+	}
+	return Kindnet
+}
+
+// Resolve returns the given CNI plugin, or the default CNI plugin for the given Kubernetes version
+// if no CNI plugin is selected
+func Resolve(cni string, kubeVersion *K8sVersion.Version) string {
+	if cni == "" {
+		return Default(kubeVersion)
+	}
+	return cni
+}
+
+// PodSubnet returns the pod subnet to be used for the given CNI plugin; Calico and CNI plugins
+// installed from a manifest file or not installed at all use the Calico default pod subnet
+func PodSubnet(cni string) string {
+	switch cni {
+	case Kindnet, Flannel:
+		return defaultPodSubnet
+	}
+	return calicoPodSubnet
+}
+
+// SelectPlugin returns the newest release of the given CNI plugin compatible with the given Kubernetes version
+func SelectPlugin(cni string, kubeVersion *K8sVersion.Version) (*Plugin, error) {
+	found := false
+	for i := range plugins {
+		p := plugins[i]
+		if p.Name != cni {
+			continue
+		}
+		found = true
+		if kubeVersion.LessThan(p.MinKubernetesVersion) {
+			continue
+		}
+		if p.MaxKubernetesVersion != nil && !kubeVersion.LessThan(p.MaxKubernetesVersion) {
+			continue
+		}
+		return &p, nil
+	}
+	if !found {
+		return nil, errors.Errorf("CNI plugin %q does not have an embedded manifest", cni)
+	}
+	return nil, errors.Errorf("CNI plugin %q does not have an embedded manifest compatible with Kubernetes %s. Use %s<manifest> instead", cni, kubeVersion, filePrefix)
+}
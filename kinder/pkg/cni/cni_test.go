@@ -0,0 +1,141 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cni
+
+import (
+	"testing"
+
+	K8sVersion "k8s.io/apimachinery/pkg/util/version"
+)
+
+func TestSelectPlugin(t *testing.T) {
+	tests := []struct {
+		name            string
+		cni             string
+		kubeVersion     string
+		expectedVersion string
+		expectedError   bool
+	}{
+		{
+			name:            "calico for a supported version",
+			cni:             Calico,
+			kubeVersion:     "v1.19.1",
+			expectedVersion: "v3.8.2",
+		},
+		{
+			name:            "calico for a ci version",
+			cni:             Calico,
+			kubeVersion:     "v1.21.0-alpha.0.1+2a1b3c4d5e6f78",
+			expectedVersion: "v3.8.2",
+		},
+		{
+			name:          "calico for a version serving only apiextensions.k8s.io/v1",
+			cni:           Calico,
+			kubeVersion:   "v1.22.0",
+			expectedError: true,
+		},
+		{
+			name:          "flannel for an old version",
+			cni:           Flannel,
+			kubeVersion:   "v1.15.3",
+			expectedError: true,
+		},
+		{
+			name:            "kindnet for a recent version",
+			cni:             Kindnet,
+			kubeVersion:     "v1.25.0",
+			expectedVersion: "v20200725-4d6bea59",
+		},
+		{
+			name:          "none does not have a manifest",
+			cni:           None,
+			kubeVersion:   "v1.19.1",
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			plugin, err := SelectPlugin(test.cni, K8sVersion.MustParseSemantic(test.kubeVersion))
+			if (err != nil) != test.expectedError {
+				t.Fatalf("expected error %t, got %v", test.expectedError, err)
+			}
+			if test.expectedError {
+				return
+			}
+			if plugin.Version != test.expectedVersion {
+				t.Errorf("expected version %s, got %s", test.expectedVersion, plugin.Version)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		cni           string
+		expectedError bool
+	}{
+		{cni: ""},
+		{cni: Calico},
+		{cni: None},
+		{cni: "file:/tmp/cni.yaml"},
+		{cni: "file:", expectedError: true},
+		{cni: "weave", expectedError: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.cni, func(t *testing.T) {
+			if err := Validate(test.cni); (err != nil) != test.expectedError {
+				t.Errorf("expected error %t, got %v", test.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		name        string
+		cni         string
+		kubeVersion string
+		expectedCNI string
+	}{
+		{
+			name:        "default for a version supported by calico",
+			kubeVersion: "v1.21.3",
+			expectedCNI: Calico,
+		},
+		{
+			name:        "default for a version not supported by calico",
+			kubeVersion: "v1.22.0-alpha.1",
+			expectedCNI: Kindnet,
+		},
+		{
+			name:        "selected plugin",
+			cni:         Flannel,
+			kubeVersion: "v1.22.0",
+			expectedCNI: Flannel,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if cni := Resolve(test.cni, K8sVersion.MustParseSemantic(test.kubeVersion)); cni != test.expectedCNI {
+				t.Errorf("expected %s, got %s", test.expectedCNI, cni)
+			}
+		})
+	}
+}
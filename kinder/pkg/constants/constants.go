@@ -90,6 +90,27 @@ const (
 
 	// ConfigPath defines the path to the config file in the load balancer node
 	LoadBalancerConfigPath = "/usr/local/etc/haproxy/haproxy.cfg"
+
+	// LoadBalancerCAPath defines the path to the cluster CA in the load balancer node, used for verifying control plane nodes
+	LoadBalancerCAPath = "/usr/local/etc/haproxy/ca.crt"
+
+	// LoadBalancerStatsPort defines the port where the load balancer exposes stats, including backend status
+	LoadBalancerStatsPort = 8404
+
+	// NginxLoadBalancerImage defines the image:tag for the nginx loadbalancer
+	NginxLoadBalancerImage = "nginx:1.19.6-alpine"
+
+	// NginxLoadBalancerConfigPath defines the path to the config file in the nginx load balancer node
+	NginxLoadBalancerConfigPath = "/etc/nginx/nginx.conf"
+
+	// KeepalivedImage defines the image:tag for the keepalived static pod managing the control plane VIP
+	KeepalivedImage = "osixia/keepalived:2.0.20"
+
+	// KeepalivedConfigPath defines the path to the keepalived config file on control plane nodes
+	KeepalivedConfigPath = "/etc/keepalived/keepalived.conf"
+
+	// KeepalivedManifestPath defines the path to the keepalived static pod manifest on control plane nodes
+	KeepalivedManifestPath = "/etc/kubernetes/manifests/keepalived.yaml"
 )
 
 // constants used by the ClusterManager / inside actions
@@ -103,6 +124,38 @@ const (
 
 	// PatchesDir defines the path to patches stored on node
 	PatchesDir = "/kinder/patches"
+
+	// CACertPath defines the path to the cluster CA certificate stored on control-plane nodes
+	CACertPath = "/etc/kubernetes/pki/ca.crt"
+
+	// ClusterSettingsLabelKey is applied to each Kubernetes "node" docker container for storing
+	// cluster settings that are re-used by kinder during the cluster lifecycle
+	ClusterSettingsLabelKey = "io.k8s.sigs.kinder.settings"
+
+	// ExternalEtcdSecureLabelKey is applied to containers hosting members of a TLS secured external etcd cluster
+	ExternalEtcdSecureLabelKey = "io.k8s.sigs.kinder.etcd-tls"
+
+	// ExternalEtcdConfig defines the path to the config file for TLS secured external etcd members;
+	// external etcd members wait for this file to exist before starting
+	ExternalEtcdConfig = "/etc/etcd/etcd.yaml"
+
+	// ExternalEtcdBinDir defines the path where etcd binaries are copied on TLS secured external etcd members
+	ExternalEtcdBinDir = "/usr/local/bin"
+
+	// ExternalEtcdPKIDir defines the path to certificates stored on TLS secured external etcd members
+	ExternalEtcdPKIDir = "/etc/etcd/pki"
+
+	// ExternalEtcdCAFile defines the path to the CA certificate used by control-plane nodes
+	// for connecting to TLS secured external etcd
+	ExternalEtcdCAFile = "/etc/kubernetes/pki/etcd/ca.crt"
+
+	// ExternalEtcdCertFile defines the path to the client certificate used by control-plane nodes
+	// for connecting to TLS secured external etcd
+	ExternalEtcdCertFile = "/etc/kubernetes/pki/apiserver-etcd-client.crt"
+
+	// ExternalEtcdKeyFile defines the path to the client key used by control-plane nodes
+	// for connecting to TLS secured external etcd
+	ExternalEtcdKeyFile = "/etc/kubernetes/pki/apiserver-etcd-client.key"
 )
 
 // kubernetes releases, used for branching code according to K8s release or kubeadm release version
@@ -115,6 +168,9 @@ var (
 
 	// V1.19 minor version
 	V1_19 = K8sVersion.MustParseSemantic("v1.19.0-0")
+
+	// V1.20 minor version
+	V1_20 = K8sVersion.MustParseSemantic("v1.20.0-0")
 )
 
 // other constants
@@ -57,3 +57,59 @@ func (h *ActionHelper) GetImages(n *status.Node) ([]string, error) {
 	}
 	return nil, errors.Errorf("unknown cri: %s", h.cri)
 }
+
+// GetRunningContainers returns the IDs of the containers running in the node;
+// if a name filter is provided, only containers with a matching name are returned
+func (h *ActionHelper) GetRunningContainers(n *status.Node, nameFilter string) ([]string, error) {
+	switch h.cri {
+	case status.ContainerdRuntime:
+		return containerd.GetRunningContainers(n, nameFilter)
+	case status.DockerRuntime:
+		return docker.GetRunningContainers(n, nameFilter)
+	}
+	return nil, errors.Errorf("unknown cri: %s", h.cri)
+}
+
+// ListContainers returns a human readable list of all the containers in the node, including exited ones
+func (h *ActionHelper) ListContainers(n *status.Node) ([]string, error) {
+	switch h.cri {
+	case status.ContainerdRuntime:
+		return containerd.ListContainers(n)
+	case status.DockerRuntime:
+		return docker.ListContainers(n)
+	}
+	return nil, errors.Errorf("unknown cri: %s", h.cri)
+}
+
+// GetAllContainers returns the IDs of all the containers in the node, including exited ones
+func (h *ActionHelper) GetAllContainers(n *status.Node) ([]string, error) {
+	switch h.cri {
+	case status.ContainerdRuntime:
+		return containerd.GetAllContainers(n)
+	case status.DockerRuntime:
+		return docker.GetAllContainers(n)
+	}
+	return nil, errors.Errorf("unknown cri: %s", h.cri)
+}
+
+// GetContainerLogs returns the logs of the container with the given ID
+func (h *ActionHelper) GetContainerLogs(n *status.Node, container string) ([]string, error) {
+	switch h.cri {
+	case status.ContainerdRuntime:
+		return containerd.GetContainerLogs(n, container)
+	case status.DockerRuntime:
+		return docker.GetContainerLogs(n, container)
+	}
+	return nil, errors.Errorf("unknown cri: %s", h.cri)
+}
+
+// KillContainers kills the containers with the given IDs running in the node
+func (h *ActionHelper) KillContainers(n *status.Node, containers ...string) error {
+	switch h.cri {
+	case status.ContainerdRuntime:
+		return containerd.KillContainers(n, containers...)
+	case status.DockerRuntime:
+		return docker.KillContainers(n, containers...)
+	}
+	return errors.Errorf("unknown cri: %s", h.cri)
+}
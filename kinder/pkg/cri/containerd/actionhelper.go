@@ -43,3 +43,71 @@ func GetImages(n *status.Node) ([]string, error) {
 
 	return current, nil
 }
+
+// GetRunningContainers returns the IDs of the containers running in the node
+func GetRunningContainers(n *status.Node, nameFilter string) ([]string, error) {
+	args := []string{"ps", "-q"}
+	if nameFilter != "" {
+		args = append(args, "--name", nameFilter)
+	}
+
+	containers, err := n.Command(
+		"crictl", args...,
+	).Silent().RunAndCapture()
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read running containers from %s", n.Name())
+	}
+
+	return containers, nil
+}
+
+// ListContainers returns a human readable list of all the containers in the node, including exited ones
+func ListContainers(n *status.Node) ([]string, error) {
+	containers, err := n.Command(
+		"crictl", "ps", "-a",
+	).Silent().RunAndCapture()
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list containers from %s", n.Name())
+	}
+
+	return containers, nil
+}
+
+// GetAllContainers returns the IDs of all the containers in the node, including exited ones
+func GetAllContainers(n *status.Node) ([]string, error) {
+	containers, err := n.Command(
+		"crictl", "ps", "-a", "-q",
+	).Silent().RunAndCapture()
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read containers from %s", n.Name())
+	}
+
+	return containers, nil
+}
+
+// GetContainerLogs returns the logs of the container with the given ID
+func GetContainerLogs(n *status.Node, container string) ([]string, error) {
+	logs, err := n.Command(
+		"crictl", "logs", container,
+	).Silent().RunAndCapture()
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read logs for container %s from %s", container, n.Name())
+	}
+
+	return logs, nil
+}
+
+// KillContainers kills the containers with the given IDs running in the node
+func KillContainers(n *status.Node, containers ...string) error {
+	args := append([]string{"stop", "--timeout", "0"}, containers...)
+	if err := n.Command(
+		"crictl", args...,
+	).Run(); err != nil {
+		return errors.Wrapf(err, "failed to kill containers on %s", n.Name())
+	}
+	return nil
+}
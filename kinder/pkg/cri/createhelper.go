@@ -39,13 +39,19 @@ func NewCreateHelper(cri status.ContainerRuntime) (*CreateHelper, error) {
 	}, nil
 }
 
-// CreateNode creates a container that internally hosts the selected cri runtime
-func (h *CreateHelper) CreateNode(cluster, name, image, role string, volumes []string) error {
+// CreateNode creates a container that internally hosts the selected cri runtime;
+// cluster settings are stored in a label of the container
+func (h *CreateHelper) CreateNode(cluster, name, image, role string, volumes []string, settings *status.ClusterSettings) error {
+	label, err := settings.Label()
+	if err != nil {
+		return err
+	}
+
 	switch h.cri {
 	case status.ContainerdRuntime:
-		return containerd.CreateNode(cluster, name, image, role, volumes)
+		return containerd.CreateNode(cluster, name, image, role, volumes, label)
 	case status.DockerRuntime:
-		return docker.CreateNode(cluster, name, image, role, volumes)
+		return docker.CreateNode(cluster, name, image, role, volumes, label)
 	}
 	return errors.Errorf("unknown cri: %s", h.cri)
 }
@@ -70,8 +76,30 @@ func (h *CreateHelper) CreateExternalEtcd(cluster, name, image string) error {
 	return exec.NewHostCmd("docker", args...).Run()
 }
 
+// CreateExternalEtcdMember creates a container hosting a member of a TLS secured, external etcd cluster;
+// please note that the container is based on the node image, and the etcd member starts only after
+// the etcd binaries and the etcd config file are written into the container
+func (h *CreateHelper) CreateExternalEtcdMember(cluster, name, image string) error {
+	args, err := util.CommonArgs(cluster, name, constants.ExternalEtcdNodeRoleValue)
+	if err != nil {
+		return err
+	}
+
+	// Add etcd member run args
+	args = util.RunArgsForExternalEtcdMember(args)
+
+	// Specify the image to run
+	args = append(args, image)
+
+	// Add container args for starting an etcd member as soon as the config file exists
+	args = util.ContainerArgsForExternalEtcdMember(args)
+
+	// creates the container
+	return exec.NewHostCmd("docker", args...).Run()
+}
+
 // CreateExternalLoadBalancer creates a container hosting an external load balancer
-func (h *CreateHelper) CreateExternalLoadBalancer(cluster, name string) error {
+func (h *CreateHelper) CreateExternalLoadBalancer(cluster, name, image string) error {
 	args, err := util.CommonArgs(cluster, name, constants.ExternalLoadBalancerNodeRoleValue)
 	if err != nil {
 		return err
@@ -84,7 +112,7 @@ func (h *CreateHelper) CreateExternalLoadBalancer(cluster, name string) error {
 	}
 
 	// Specify the image to run
-	args = append(args, constants.LoadBalancerImage)
+	args = append(args, image)
 
 	// creates the container
 	return exec.NewHostCmd("docker", args...).Run()
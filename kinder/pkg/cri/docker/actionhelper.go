@@ -17,6 +17,7 @@ limitations under the License.
 package docker
 
 import (
+	"fmt"
 	"strconv"
 
 	"github.com/pkg/errors"
@@ -48,3 +49,72 @@ func GetImages(n *status.Node) ([]string, error) {
 
 	return current, nil
 }
+
+// GetRunningContainers returns the IDs of the containers running in the node
+func GetRunningContainers(n *status.Node, nameFilter string) ([]string, error) {
+	args := []string{"ps", "-q"}
+	if nameFilter != "" {
+		// NB. kubelet names docker containers as k8s_{container name}_{pod name}_...
+		args = append(args, "--filter", fmt.Sprintf("name=k8s_%s", nameFilter))
+	}
+
+	containers, err := n.Command(
+		"docker", args...,
+	).Silent().RunAndCapture()
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read running containers from %s", n.Name())
+	}
+
+	return containers, nil
+}
+
+// ListContainers returns a human readable list of all the containers in the node, including exited ones
+func ListContainers(n *status.Node) ([]string, error) {
+	containers, err := n.Command(
+		"docker", "ps", "-a",
+	).Silent().RunAndCapture()
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list containers from %s", n.Name())
+	}
+
+	return containers, nil
+}
+
+// GetAllContainers returns the IDs of all the containers in the node, including exited ones
+func GetAllContainers(n *status.Node) ([]string, error) {
+	containers, err := n.Command(
+		"docker", "ps", "-a", "-q",
+	).Silent().RunAndCapture()
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read containers from %s", n.Name())
+	}
+
+	return containers, nil
+}
+
+// GetContainerLogs returns the logs of the container with the given ID
+func GetContainerLogs(n *status.Node, container string) ([]string, error) {
+	logs, err := n.Command(
+		"docker", "logs", container,
+	).Silent().RunAndCapture()
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read logs for container %s from %s", container, n.Name())
+	}
+
+	return logs, nil
+}
+
+// KillContainers kills the containers with the given IDs running in the node
+func KillContainers(n *status.Node, containers ...string) error {
+	args := append([]string{"kill"}, containers...)
+	if err := n.Command(
+		"docker", args...,
+	).Run(); err != nil {
+		return errors.Wrapf(err, "failed to kill containers on %s", n.Name())
+	}
+	return nil
+}
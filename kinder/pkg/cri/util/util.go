@@ -134,8 +134,13 @@ func usernsRemap() bool {
 	return false
 }
 
-// RunArgsForNode computes docker run arguments that apply to containers that should host K8s nodes
-func RunArgsForNode(role string, volumes []string, args []string) ([]string, error) {
+// RunArgsForNode computes docker run arguments that apply to containers that should host K8s nodes;
+// settings, if not empty, are stored in a label of the container
+func RunArgsForNode(role string, volumes []string, settings string, args []string) ([]string, error) {
+	if settings != "" {
+		args = append(args, "--label", fmt.Sprintf("%s=%s", constants.ClusterSettingsLabelKey, settings))
+	}
+
 	args = append(args,
 		// running containers in a container requires privileged
 		// NOTE: we could try to replicate this with --cap-add, and use less
@@ -198,6 +203,24 @@ func RunArgsForExternalLoadBalancer(args []string) ([]string, error) {
 
 // RunArgsForExternalEtcd computes docker run arguments that apply to containers that should host external etcd members
 func RunArgsForExternalEtcd(args []string) []string {
+	// etcd images do not provide a shell, so the etcdctl API version is set at container level
+	args = append(args, "--env", "ETCDCTL_API=3")
+
+	return args
+}
+
+// RunArgsForExternalEtcdMember computes docker run arguments that apply to containers that should host members
+// of a TLS secured external etcd cluster; such containers are based on the node image, because the etcd configuration
+// depends on the IP assigned to the containers, and waiting for it requires a shell, that is not available in etcd images
+func RunArgsForExternalEtcdMember(args []string) []string {
+	args = RunArgsForExternalEtcd(args)
+	args = append(args,
+		// label the node as a TLS secured external etcd member
+		"--label", fmt.Sprintf("%s=true", constants.ExternalEtcdSecureLabelKey),
+		// override the node image entrypoint
+		"--entrypoint", "/bin/sh",
+	)
+
 	return args
 }
 
@@ -214,6 +237,18 @@ func ContainerArgsForExternalEtcd(name string, args []string) []string {
 	return args
 }
 
+// ContainerArgsForExternalEtcdMember computes arguments to pass to the entry point of a container hosting
+// a member of a TLS secured external etcd cluster; given that the etcd configuration depends on the IP
+// assigned to the containers, etcd waits for the config file to be written before starting
+func ContainerArgsForExternalEtcdMember(args []string) []string {
+	args = append(args,
+		"-c",
+		fmt.Sprintf("while [ ! -f %[1]s ]; do sleep 1; done; exec %[2]s/etcd --config-file=%[1]s", constants.ExternalEtcdConfig, constants.ExternalEtcdBinDir),
+	)
+
+	return args
+}
+
 // TryUntil implements an helper that calls `try()`` in a loop until the deadline `until`
 // has passed or `try()`returns true, returns whether try ever returned true
 func TryUntil(until time.Time, try func() bool) bool {
@@ -51,6 +51,9 @@ const (
 	kubeadmBinary = "kubeadm"
 	kubeletBinary = "kubelet"
 	kubectlBinary = "kubectl"
+
+	// KubernetesTestTarball defines the name of the tarball with test binaries included in a K8s release
+	KubernetesTestTarball = "kubernetes-test-linux-amd64.tar.gz"
 )
 
 var (
@@ -146,6 +149,17 @@ func OnlyKubernetesImages(onlyImages bool) Option {
 	}
 }
 
+// OnlyKubernetesTestTarball option instructs the Extractor for retrieving the Kubernetes test tarball only
+func OnlyKubernetesTestTarball(onlyTestTarball bool) Option {
+	return func(b *Extractor) {
+		if onlyTestTarball {
+			b.files = []string{KubernetesTestTarball}
+			// disable addVersionFileToDst when we are reading only a subset of files
+			b.addVersionFileToDst = false
+		}
+	}
+}
+
 // WithNamePrefix option instructs the Extractor to adds a prefix to the name of each file before saving to destination
 func WithNamePrefix(namePrefix string) Option {
 	return func(b *Extractor) {
@@ -313,14 +327,19 @@ func extractFromHTTP(src string, files []string, dst string, m fileNameMutator,
 	}
 
 	// in case the source is a Kubernetes build, add bin/OS/ARCH to the src uri
+	// nb. the test tarball is hosted at the root of the Kubernetes build
+	binSrc := src
 	if strings.HasPrefix(src, releaseBuildURepository) || strings.HasPrefix(src, ciBuildRepository) {
-		src = fmt.Sprintf("%s/bin/linux/amd64", src)
+		binSrc = fmt.Sprintf("%s/bin/linux/amd64", src)
 	}
 
 	// Download the files.
 	paths = map[string]string{}
 	for _, f := range files {
-		srcFilePath := fmt.Sprintf("%s/%s", src, f)
+		srcFilePath := fmt.Sprintf("%s/%s", binSrc, f)
+		if f == KubernetesTestTarball {
+			srcFilePath = fmt.Sprintf("%s/%s", src, f)
+		}
 		log.Infof("Downloading %s\n", srcFilePath)
 		dstFilePath := path.Join(dst, m.Mutate(f))
 		if err := copyFromURI(srcFilePath, dstFilePath); err != nil {
@@ -66,6 +66,16 @@ func Config(kubeadmVersion *K8sVersion.Version, data ConfigData) (config string,
 	return buff.String(), nil
 }
 
+// GetConfigAPIVersion returns the kubeadm config apiVersion, e.g. kubeadm.k8s.io/v1beta2, corresponding
+// to a Kubernetes kubeadmVersion
+func GetConfigAPIVersion(kubeadmVersion *K8sVersion.Version) (string, error) {
+	kubeadmConfigVersion, err := getKubeadmConfigVersion(kubeadmVersion)
+	if err != nil {
+		return "", err
+	}
+	return "kubeadm.k8s.io/" + kubeadmConfigVersion, nil
+}
+
 // getKubeadmConfigVersion returns the kubeadm config version corresponding to a Kubernetes kubeadmVersion
 func getKubeadmConfigVersion(kubeadmVersion *K8sVersion.Version) (string, error) {
 	// returns the corresponding config version
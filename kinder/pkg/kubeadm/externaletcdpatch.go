@@ -17,17 +17,20 @@ limitations under the License.
 package kubeadm
 
 import (
-	"fmt"
+	"bytes"
+	"text/template"
 
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 
 	K8sVersion "k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/kubeadm/kinder/pkg/constants"
 )
 
 // GetExternalEtcdPatch returns the kubeadm config patch that will instruct kubeadm
-// to use external etcd.
-func GetExternalEtcdPatch(kubeadmVersion *K8sVersion.Version, etcdIP string) (string, error) {
+// to use external etcd; if the external etcd is secure, the patch includes the paths
+// of the certificates to be used for connecting to etcd.
+func GetExternalEtcdPatch(kubeadmVersion *K8sVersion.Version, endpoints []string, secure bool) (string, error) {
 	// gets the config version corresponding to a kubeadm version
 	kubeadmConfigVersion, err := getKubeadmConfigVersion(kubeadmVersion)
 	if err != nil {
@@ -37,33 +40,52 @@ func GetExternalEtcdPatch(kubeadmVersion *K8sVersion.Version, etcdIP string) (st
 	// select the patches for the kubeadm config version
 	log.Debugf("Preparing externalEtcdPatch for kubeadm config %s (kubeadm version %s)", kubeadmConfigVersion, kubeadmVersion)
 
-	var externalEtcdPatch string
 	switch kubeadmConfigVersion {
-	case "v1beta2":
-		externalEtcdPatch = externalEtcdPatchv1beta2
-	case "v1beta1":
-		externalEtcdPatch = externalEtcdPatchv1beta1
+	case "v1beta2", "v1beta1":
 	default:
 		return "", errors.Errorf("unknown kubeadm config version: %s", kubeadmConfigVersion)
 	}
 
-	return fmt.Sprintf(externalEtcdPatch, etcdIP), nil
-}
+	t, err := template.New("external-etcd-patch").Parse(externalEtcdPatch)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to parse externalEtcdPatch template")
+	}
 
-const externalEtcdPatchv1beta2 = `apiVersion: kubeadm.k8s.io/v1beta2
-kind: ClusterConfiguration
-metadata:
-  name: config
-etcd:
-  external:
-    endpoints:
-    - http://%s:2379`
+	var buff bytes.Buffer
+	err = t.Execute(&buff, struct {
+		KubeadmConfigVersion string
+		Endpoints            []string
+		Secure               bool
+		CAFile               string
+		CertFile             string
+		KeyFile              string
+	}{
+		KubeadmConfigVersion: kubeadmConfigVersion,
+		Endpoints:            endpoints,
+		Secure:               secure,
+		CAFile:               constants.ExternalEtcdCAFile,
+		CertFile:             constants.ExternalEtcdCertFile,
+		KeyFile:              constants.ExternalEtcdKeyFile,
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "failed to execute externalEtcdPatch template")
+	}
+
+	return buff.String(), nil
+}
 
-const externalEtcdPatchv1beta1 = `apiVersion: kubeadm.k8s.io/v1beta1
+const externalEtcdPatch = `apiVersion: kubeadm.k8s.io/{{ .KubeadmConfigVersion }}
 kind: ClusterConfiguration
 metadata:
   name: config
 etcd:
   external:
     endpoints:
-    - http://%s:2379`
+{{- range .Endpoints }}
+    - {{ . }}
+{{- end }}
+{{- if .Secure }}
+    caFile: {{ .CAFile }}
+    certFile: {{ .CertFile }}
+    keyFile: {{ .KeyFile }}
+{{- end }}`
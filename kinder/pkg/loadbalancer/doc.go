@@ -22,6 +22,8 @@ in kinder all the actions for setting up a working cluster can happen
 at different time while in kind everything - from create to a working K8s cluster -
 happens within an atomic operation, create.
 
-The current implementation is a fork from "sigs.k8s.io/kind/pkg/cluster/internal/loadbalancer".
+Kinder supports different load balancer implementations, selectable at create time; the HAProxy
+implementation is a fork from "sigs.k8s.io/kind/pkg/cluster/internal/loadbalancer", while the nginx
+implementation uses a nginx stream proxy.
 */
 package loadbalancer
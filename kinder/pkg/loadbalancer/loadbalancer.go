@@ -18,9 +18,22 @@ package loadbalancer
 
 import (
 	"bytes"
+	"fmt"
 	"text/template"
 
 	"github.com/pkg/errors"
+
+	"k8s.io/kubeadm/kinder/pkg/constants"
+)
+
+const (
+	// HAProxy implements the load balancer using HAProxy, the same used by kind
+	HAProxy = "haproxy"
+	// Nginx implements the load balancer using a nginx stream proxy
+	Nginx = "nginx"
+
+	// Default defines the load balancer implementation used when no implementation is selected
+	Default = HAProxy
 )
 
 // ConfigData is supplied to the loadbalancer config template
@@ -28,9 +41,115 @@ type ConfigData struct {
 	ControlPlanePort int
 	BackendServers   map[string]string
 	IPv6             bool
+	// CAFile defines the path to the cluster CA in the load balancer node;
+	// if set, health checks against control plane nodes verify the API server certificate.
+	CAFile string
+	// StatsPort defines the port where the load balancer exposes stats; 0 means stats are not exposed.
+	StatsPort int
 }
 
-// DefaultConfigTemplate is the loadbalancer config template
+// Implementation defines a load balancer implementation
+type Implementation struct {
+	// Name of the load balancer implementation, e.g. haproxy
+	Name string
+
+	// Image used for creating the load balancer node
+	Image string
+
+	// ConfigPath defines the path to the config file in the load balancer node
+	ConfigPath string
+
+	// CAPath defines the path to the cluster CA in the load balancer node;
+	// empty means that the implementation does not support CA-verified health checks
+	CAPath string
+
+	// StatsPort defines the port where the load balancer exposes backend status;
+	// 0 means that the implementation does not support reporting backend status
+	StatsPort int
+
+	// configTemplate is the load balancer config template
+	configTemplate string
+}
+
+// implementations defines the load balancer implementations supported by kinder
+var implementations = []Implementation{
+	{
+		Name:           HAProxy,
+		Image:          constants.LoadBalancerImage,
+		ConfigPath:     constants.LoadBalancerConfigPath,
+		CAPath:         constants.LoadBalancerCAPath,
+		StatsPort:      constants.LoadBalancerStatsPort,
+		configTemplate: DefaultConfigTemplate,
+	},
+	{
+		Name:       Nginx,
+		Image:      constants.NginxLoadBalancerImage,
+		ConfigPath: constants.NginxLoadBalancerConfigPath,
+		// nb. nginx open source supports only passive health checks, so the CA can't be used for verifying control plane nodes
+		// and the status of backends can't be reported
+		configTemplate: NginxConfigTemplate,
+	},
+}
+
+// KnownImplementations returns the list of load balancer implementations supported by kinder
+func KnownImplementations() []string {
+	var names []string
+	for _, i := range implementations {
+		names = append(names, i.Name)
+	}
+	return names
+}
+
+// Get returns the load balancer implementation with the given name; if name is empty
+// the default implementation is returned
+func Get(name string) (*Implementation, error) {
+	if name == "" {
+		name = Default
+	}
+	for _, i := range implementations {
+		if i.Name == name {
+			i := i
+			return &i, nil
+		}
+	}
+	return nil, errors.Errorf("unknown load balancer %q. Use one of %v", name, KnownImplementations())
+}
+
+// SupportsCAVerification returns true if the load balancer implementation supports CA-verified health checks
+func (i *Implementation) SupportsCAVerification() bool {
+	return i.CAPath != ""
+}
+
+// SupportsBackendStatus returns true if the load balancer implementation supports reporting backend status
+func (i *Implementation) SupportsBackendStatus() bool {
+	return i.StatsPort != 0
+}
+
+// StatsURL returns the URL for reading backend status in CSV format from the load balancer node
+func (i *Implementation) StatsURL() string {
+	return fmt.Sprintf("http://127.0.0.1:%d/stats;csv", i.StatsPort)
+}
+
+// Config returns the load balancer config generated from config data
+func (i *Implementation) Config(data *ConfigData) (config string, err error) {
+	if data.CAFile != "" && !i.SupportsCAVerification() {
+		return "", errors.Errorf("the %s load balancer does not support CA-verified health checks", i.Name)
+	}
+
+	t, err := template.New(fmt.Sprintf("%s-config", i.Name)).Parse(i.configTemplate)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to parse config template")
+	}
+	// execute the template
+	var buff bytes.Buffer
+	err = t.Execute(&buff, data)
+	if err != nil {
+		return "", errors.Wrap(err, "error executing config template")
+	}
+	return buff.String(), nil
+}
+
+// DefaultConfigTemplate is the HAProxy loadbalancer config template
 const DefaultConfigTemplate = `# generated by kind
 global
   log /dev/log local0
@@ -55,24 +174,47 @@ frontend control-plane
 
 backend kube-apiservers
   option httpchk GET /healthz
+  {{- if not .CAFile }}
   # TODO: we should be verifying (!)
+  {{- end }}
   {{range $server, $address := .BackendServers}}
-  server {{ $server }} {{ $address }} check check-ssl verify none
+  server {{ $server }} {{ $address }} check check-ssl {{ if $.CAFile }}verify required ca-file {{ $.CAFile }} verifyhost kubernetes{{ else }}verify none{{ end }}
   {{- end}}
+{{- if .StatsPort }}
+
+frontend stats
+  bind *:{{ .StatsPort }}
+  mode http
+  stats enable
+  stats uri /stats
+  stats refresh 10s
+{{- end }}
 `
 
-// Config returns a kubeadm config generated from config data, in particular
-// the kubernetes version
-func Config(data *ConfigData) (config string, err error) {
-	t, err := template.New("loadbalancer-config").Parse(DefaultConfigTemplate)
-	if err != nil {
-		return "", errors.Wrap(err, "failed to parse config template")
-	}
-	// execute the template
-	var buff bytes.Buffer
-	err = t.Execute(&buff, data)
-	if err != nil {
-		return "", errors.Wrap(err, "error executing config template")
-	}
-	return buff.String(), nil
+// NginxConfigTemplate is the nginx loadbalancer config template
+const NginxConfigTemplate = `# generated by kinder
+worker_processes auto;
+
+events {
+  worker_connections 1024;
 }
+
+stream {
+  upstream kube-apiservers {
+    {{- range $server, $address := .BackendServers}}
+    # {{ $server }}
+    server {{ $address }} max_fails=1 fail_timeout=5s;
+    {{- end}}
+  }
+
+  server {
+    listen {{ .ControlPlanePort }};
+    {{- if .IPv6 }}
+    listen [::]:{{ .ControlPlanePort }};
+    {{- end }}
+    proxy_pass kube-apiservers;
+    proxy_connect_timeout 5s;
+    proxy_timeout 50s;
+  }
+}
+`
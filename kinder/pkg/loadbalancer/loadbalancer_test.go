@@ -0,0 +1,144 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loadbalancer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConfig(t *testing.T) {
+	backends := map[string]string{
+		"kinder-control-plane-1": "172.17.0.3:6443",
+		"kinder-control-plane-2": "172.17.0.4:6443",
+	}
+
+	cases := []struct {
+		name        string
+		lb          string
+		caFile      string
+		statsPort   int
+		expected    []string
+		notExpected []string
+		expectError bool
+	}{
+		{
+			name: "haproxy",
+			lb:   HAProxy,
+			expected: []string{
+				"server kinder-control-plane-1 172.17.0.3:6443 check check-ssl verify none",
+				"server kinder-control-plane-2 172.17.0.4:6443 check check-ssl verify none",
+			},
+			notExpected: []string{"verify required", "frontend stats"},
+		},
+		{
+			name:   "haproxy with CA-verified health checks",
+			lb:     HAProxy,
+			caFile: "/usr/local/etc/haproxy/ca.crt",
+			expected: []string{
+				"server kinder-control-plane-1 172.17.0.3:6443 check check-ssl verify required ca-file /usr/local/etc/haproxy/ca.crt verifyhost kubernetes",
+				"server kinder-control-plane-2 172.17.0.4:6443 check check-ssl verify required ca-file /usr/local/etc/haproxy/ca.crt verifyhost kubernetes",
+			},
+			notExpected: []string{"verify none"},
+		},
+		{
+			name:      "haproxy with stats",
+			lb:        HAProxy,
+			statsPort: 8404,
+			expected: []string{
+				"frontend stats",
+				"bind *:8404",
+				"stats uri /stats",
+			},
+		},
+		{
+			name: "default",
+			lb:   "",
+			expected: []string{
+				"backend kube-apiservers",
+			},
+		},
+		{
+			name: "nginx",
+			lb:   Nginx,
+			expected: []string{
+				"upstream kube-apiservers",
+				"server 172.17.0.3:6443 max_fails=1 fail_timeout=5s;",
+				"server 172.17.0.4:6443 max_fails=1 fail_timeout=5s;",
+				"listen 6443;",
+				"proxy_pass kube-apiservers;",
+			},
+		},
+		{
+			name:        "nginx with CA-verified health checks",
+			lb:          Nginx,
+			caFile:      "/etc/nginx/ca.crt",
+			expectError: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			impl, err := Get(c.lb)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			config, err := impl.Config(&ConfigData{
+				ControlPlanePort: 6443,
+				BackendServers:   backends,
+				CAFile:           c.caFile,
+				StatsPort:        c.statsPort,
+			})
+			if err != nil {
+				if !c.expectError {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if c.expectError {
+				t.Fatalf("expected error, got nil")
+			}
+
+			for _, e := range c.expected {
+				if !strings.Contains(config, e) {
+					t.Errorf("expected config to contain %q, got\n%s", e, config)
+				}
+			}
+			for _, e := range c.notExpected {
+				if strings.Contains(config, e) {
+					t.Errorf("expected config not to contain %q, got\n%s", e, config)
+				}
+			}
+		})
+	}
+}
+
+func TestGet(t *testing.T) {
+	if _, err := Get("envoy"); err == nil {
+		t.Errorf("expected error for unknown load balancer, got nil")
+	}
+	for _, name := range KnownImplementations() {
+		impl, err := Get(name)
+		if err != nil {
+			t.Fatalf("unexpected error for %s: %v", name, err)
+		}
+		if impl.Image == "" || impl.ConfigPath == "" {
+			t.Errorf("expected image and config path for %s", name)
+		}
+	}
+}
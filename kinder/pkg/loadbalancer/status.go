@@ -0,0 +1,112 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loadbalancer
+
+import (
+	"encoding/csv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// BackendsName defines the name of the load balancer backend for control plane nodes
+const BackendsName = "kube-apiservers"
+
+// BackendStatus defines the status of a control plane node as seen by the load balancer
+type BackendStatus struct {
+	// Name of the backend, that is the name of the control plane node
+	Name string `json:"name"`
+	// Address of the backend
+	Address string `json:"address,omitempty"`
+	// Status of the backend, e.g. UP, DOWN, MAINT, no check
+	Status string `json:"status"`
+	// CheckStatus is the status of the last health check, e.g. L7OK, L4CON
+	CheckStatus string `json:"checkStatus,omitempty"`
+	// CheckCode is the HTTP code returned by the last health check, if any
+	CheckCode string `json:"checkCode,omitempty"`
+	// LastChange is the number of seconds since the last status change
+	LastChange string `json:"lastChange,omitempty"`
+	// Downtime is the total number of seconds the backend was down
+	Downtime string `json:"downtime,omitempty"`
+}
+
+// IsUp returns true if the load balancer considers the backend healthy
+func (b *BackendStatus) IsUp() bool {
+	// nb. during transitions HAProxy reports e.g. "UP 1/2" or "DOWN 1/2"
+	return b.Status == "UP" || strings.HasPrefix(b.Status, "UP ")
+}
+
+// ParseHAProxyStats parses the HAProxy stats in CSV format, and returns the status of control plane backends
+func ParseHAProxyStats(lines []string) ([]BackendStatus, error) {
+	// skips lines before the CSV header, if any (e.g. warnings)
+	start := -1
+	for i, l := range lines {
+		if strings.HasPrefix(l, "# pxname,") {
+			start = i
+			break
+		}
+	}
+	if start == -1 {
+		return nil, errors.New("invalid HAProxy stats: header not found")
+	}
+
+	r := csv.NewReader(strings.NewReader(strings.TrimPrefix(strings.Join(lines[start:], "\n"), "# ")))
+	r.FieldsPerRecord = -1
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid HAProxy stats")
+	}
+
+	columns := map[string]int{}
+	for i, c := range records[0] {
+		columns[c] = i
+	}
+	for _, c := range []string{"pxname", "svname", "status"} {
+		if _, ok := columns[c]; !ok {
+			return nil, errors.Errorf("invalid HAProxy stats: column %s not found", c)
+		}
+	}
+	field := func(record []string, column string) string {
+		i, ok := columns[column]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return record[i]
+	}
+
+	backends := []BackendStatus{}
+	for _, record := range records[1:] {
+		if field(record, "pxname") != BackendsName {
+			continue
+		}
+		// skips aggregated stats for the backend
+		name := field(record, "svname")
+		if name == "FRONTEND" || name == "BACKEND" {
+			continue
+		}
+		backends = append(backends, BackendStatus{
+			Name:        name,
+			Address:     field(record, "addr"),
+			Status:      field(record, "status"),
+			CheckStatus: field(record, "check_status"),
+			CheckCode:   field(record, "check_code"),
+			LastChange:  field(record, "lastchg"),
+			Downtime:    field(record, "downtime"),
+		})
+	}
+	return backends, nil
+}
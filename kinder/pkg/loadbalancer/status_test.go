@@ -0,0 +1,102 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loadbalancer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseHAProxyStats(t *testing.T) {
+	cases := []struct {
+		name        string
+		lines       []string
+		expected    []BackendStatus
+		expectError bool
+	}{
+		{
+			name: "control plane backends",
+			lines: []string{
+				"# pxname,svname,status,lastchg,downtime,check_status,check_code,addr,",
+				"stats,FRONTEND,OPEN,,,,,,",
+				"control-plane,FRONTEND,OPEN,,,,,,",
+				"kube-apiservers,kinder-control-plane-1,UP,120,0,L6OK,,172.17.0.3:6443,",
+				"kube-apiservers,kinder-control-plane-2,DOWN,5,5,L4CON,,172.17.0.4:6443,",
+				"kube-apiservers,BACKEND,UP,120,0,,,,",
+			},
+			expected: []BackendStatus{
+				{Name: "kinder-control-plane-1", Address: "172.17.0.3:6443", Status: "UP", CheckStatus: "L6OK", LastChange: "120", Downtime: "0"},
+				{Name: "kinder-control-plane-2", Address: "172.17.0.4:6443", Status: "DOWN", CheckStatus: "L4CON", LastChange: "5", Downtime: "5"},
+			},
+		},
+		{
+			name: "lines before the header",
+			lines: []string{
+				"[WARNING] something",
+				"# pxname,svname,status",
+				"kube-apiservers,kinder-control-plane-1,UP 1/2",
+			},
+			expected: []BackendStatus{
+				{Name: "kinder-control-plane-1", Status: "UP 1/2"},
+			},
+		},
+		{
+			name:        "missing header",
+			lines:       []string{"kube-apiservers,kinder-control-plane-1,UP"},
+			expectError: true,
+		},
+		{
+			name:        "missing status column",
+			lines:       []string{"# pxname,svname", "kube-apiservers,kinder-control-plane-1"},
+			expectError: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			backends, err := ParseHAProxyStats(c.lines)
+			if err != nil {
+				if !c.expectError {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if c.expectError {
+				t.Fatalf("expected error, got nil")
+			}
+			if !reflect.DeepEqual(backends, c.expected) {
+				t.Errorf("expected %v, got %v", c.expected, backends)
+			}
+		})
+	}
+}
+
+func TestBackendStatusIsUp(t *testing.T) {
+	for status, expected := range map[string]bool{
+		"UP":       true,
+		"UP 1/2":   true,
+		"DOWN":     false,
+		"DOWN 1/2": false,
+		"MAINT":    false,
+		"no check": false,
+	} {
+		b := BackendStatus{Status: status}
+		if b.IsUp() != expected {
+			t.Errorf("expected IsUp() to be %t for %q", expected, status)
+		}
+	}
+}
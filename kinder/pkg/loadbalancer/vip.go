@@ -0,0 +1,219 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loadbalancer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"text/template"
+
+	"github.com/pkg/errors"
+)
+
+// VIPConfigData is supplied to the keepalived config and static pod manifest templates
+type VIPConfigData struct {
+	// NodeName is the name of the control plane node hosting the keepalived instance
+	NodeName string
+	// Bootstrap is true for the bootstrap control plane node, that is the initial owner of the VIP
+	Bootstrap bool
+	// VIP is the virtual IP address shared by control plane nodes
+	VIP string
+	// Interface is the node network interface where the VIP is configured
+	Interface string
+	// NodeAddress is the address of the control plane node hosting the keepalived instance
+	NodeAddress string
+	// Peers are the addresses of the other control plane nodes
+	Peers []string
+	// APIServerPort is the port where the API server is listening on control plane nodes
+	APIServerPort int
+	// Image is the keepalived image
+	Image string
+	// ConfigPath is the path of the keepalived config file on the node
+	ConfigPath string
+}
+
+// RouterID returns the VRRP router id for the VIP; the last byte of the VIP is used in order to avoid
+// different clusters on the same docker network to use the same router id
+func (d *VIPConfigData) RouterID() int {
+	ip := net.ParseIP(d.VIP).To4()
+	if ip == nil || ip[3] == 0 {
+		return 51
+	}
+	return int(ip[3])
+}
+
+// Priority returns the VRRP priority of the keepalived instance; the bootstrap control plane node
+// has the higher priority so it owns the VIP when the cluster is created
+func (d *VIPConfigData) Priority() int {
+	if d.Bootstrap {
+		return 150
+	}
+	return 100
+}
+
+// KeepalivedConfigTemplate is the keepalived config template.
+// VRRP advertisements are sent in unicast to the other control plane nodes, so many clusters can share the same docker network.
+// When the API server on a node is not healthy the node priority is lowered, but the node keeps the VIP
+// if there are no other candidates; this allows kubeadm init to reach the API server via the VIP while bootstrapping.
+const KeepalivedConfigTemplate = `# generated by kinder
+global_defs {
+  router_id {{ .NodeName }}
+  enable_script_security
+  script_user root
+}
+
+vrrp_script check_apiserver {
+  script "/usr/bin/nc -z 127.0.0.1 {{ .APIServerPort }}"
+  interval 3
+  fall 3
+  rise 2
+  weight -60
+}
+
+vrrp_instance kube-apiservers {
+  state {{ if .Bootstrap }}MASTER{{ else }}BACKUP{{ end }}
+  interface {{ .Interface }}
+  virtual_router_id {{ .RouterID }}
+  priority {{ .Priority }}
+  advert_int 1
+  unicast_src_ip {{ .NodeAddress }}
+  unicast_peer {
+    {{- range .Peers }}
+    {{ . }}
+    {{- end }}
+  }
+  authentication {
+    auth_type PASS
+    auth_pass kinder
+  }
+  virtual_ipaddress {
+    {{ .VIP }}
+  }
+  track_script {
+    check_apiserver
+  }
+}
+`
+
+// KeepalivedManifestTemplate is the template for the keepalived static pod manifest
+const KeepalivedManifestTemplate = `# generated by kinder
+apiVersion: v1
+kind: Pod
+metadata:
+  name: keepalived
+  namespace: kube-system
+  labels:
+    component: keepalived
+    tier: control-plane
+spec:
+  hostNetwork: true
+  containers:
+  - name: keepalived
+    image: {{ .Image }}
+    command:
+    - keepalived
+    - --dont-fork
+    - --log-console
+    - --vrrp
+    - --use-file=/etc/keepalived/keepalived.conf
+    securityContext:
+      capabilities:
+        add:
+        - NET_ADMIN
+        - NET_BROADCAST
+        - NET_RAW
+    volumeMounts:
+    - name: config
+      mountPath: /etc/keepalived/keepalived.conf
+      readOnly: true
+  volumes:
+  - name: config
+    hostPath:
+      path: {{ .ConfigPath }}
+      type: File
+`
+
+// KeepalivedConfig returns the keepalived config generated from config data
+func KeepalivedConfig(data *VIPConfigData) (string, error) {
+	return executeTemplate("keepalived-config", KeepalivedConfigTemplate, data)
+}
+
+// KeepalivedManifest returns the keepalived static pod manifest generated from config data
+func KeepalivedManifest(data *VIPConfigData) (string, error) {
+	return executeTemplate("keepalived-manifest", KeepalivedManifestTemplate, data)
+}
+
+func executeTemplate(name, text string, data interface{}) (string, error) {
+	t, err := template.New(name).Parse(text)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to parse config template")
+	}
+	// execute the template
+	var buff bytes.Buffer
+	err = t.Execute(&buff, data)
+	if err != nil {
+		return "", errors.Wrap(err, "error executing config template")
+	}
+	return buff.String(), nil
+}
+
+// SelectVIP returns a free IPv4 address in the given subnet to be used as a VIP; addresses are selected
+// starting from the end of the subnet, because docker allocates addresses to containers starting from the beginning.
+// Addresses used by containers and the VIPs of other clusters are excluded, as well as addresses that would
+// lead to the same VRRP router id of the VIPs of other clusters.
+func SelectVIP(subnet string, used, vips []string) (string, error) {
+	_, ipNet, err := net.ParseCIDR(subnet)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid subnet %s", subnet)
+	}
+	network := ipNet.IP.To4()
+	if network == nil {
+		return "", errors.Errorf("subnet %s is not an IPv4 subnet", subnet)
+	}
+
+	usedIPs := map[string]bool{}
+	for _, u := range used {
+		if ip, _, err := net.ParseCIDR(u); err == nil {
+			u = ip.String()
+		}
+		usedIPs[u] = true
+	}
+
+	usedRouterIDs := map[int]bool{}
+	for _, v := range vips {
+		usedIPs[v] = true
+		usedRouterIDs[(&VIPConfigData{VIP: v}).RouterID()] = true
+	}
+
+	ones, bits := ipNet.Mask.Size()
+	if bits-ones < 2 {
+		return "", errors.Errorf("subnet %s is too small", subnet)
+	}
+	size := uint32(1) << uint(bits-ones)
+	first := binary.BigEndian.Uint32(network)
+	// skips the broadcast address and the network address
+	for i := size - 2; i > 0; i-- {
+		ip := make(net.IP, 4)
+		binary.BigEndian.PutUint32(ip, first+i)
+		if !usedIPs[ip.String()] && !usedRouterIDs[(&VIPConfigData{VIP: ip.String()}).RouterID()] {
+			return ip.String(), nil
+		}
+	}
+
+	return "", errors.Errorf("no free addresses in subnet %s", subnet)
+}
@@ -0,0 +1,134 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loadbalancer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSelectVIP(t *testing.T) {
+	cases := []struct {
+		name        string
+		subnet      string
+		used        []string
+		vips        []string
+		expected    string
+		expectError bool
+	}{
+		{
+			name:     "docker default bridge",
+			subnet:   "172.17.0.0/16",
+			used:     []string{"172.17.0.2/16", "172.17.0.3/16"},
+			expected: "172.17.255.254",
+		},
+		{
+			name:     "last address in use",
+			subnet:   "172.18.0.0/16",
+			used:     []string{"172.18.255.254/16", "172.18.255.253"},
+			expected: "172.18.255.252",
+		},
+		{
+			name:     "VIPs of other clusters",
+			subnet:   "172.17.0.0/16",
+			used:     []string{"172.17.0.2/16"},
+			vips:     []string{"172.17.255.254", "172.18.255.253"},
+			expected: "172.17.255.252",
+		},
+		{
+			name:     "small subnet",
+			subnet:   "10.0.0.0/30",
+			used:     []string{"10.0.0.2/30"},
+			expected: "10.0.0.1",
+		},
+		{
+			name:        "no free addresses",
+			subnet:      "10.0.0.0/30",
+			used:        []string{"10.0.0.1/30", "10.0.0.2/30"},
+			expectError: true,
+		},
+		{
+			name:        "IPv6 subnet",
+			subnet:      "fc00:f853:ccd:e793::/64",
+			expectError: true,
+		},
+		{
+			name:        "invalid subnet",
+			subnet:      "172.17.0.0",
+			expectError: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			vip, err := SelectVIP(c.subnet, c.used, c.vips)
+			if err != nil {
+				if !c.expectError {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if c.expectError {
+				t.Fatalf("expected error, got %s", vip)
+			}
+			if vip != c.expected {
+				t.Errorf("expected %s, got %s", c.expected, vip)
+			}
+		})
+	}
+}
+
+func TestKeepalivedConfig(t *testing.T) {
+	data := &VIPConfigData{
+		NodeName:      "kinder-control-plane-1",
+		Bootstrap:     true,
+		VIP:           "172.17.255.254",
+		Interface:     "eth0",
+		NodeAddress:   "172.17.0.2",
+		Peers:         []string{"172.17.0.3", "172.17.0.4"},
+		APIServerPort: 6443,
+	}
+
+	config, err := KeepalivedConfig(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, e := range []string{
+		"state MASTER",
+		"virtual_router_id 254",
+		"priority 150",
+		"unicast_src_ip 172.17.0.2",
+		"    172.17.0.3\n    172.17.0.4\n",
+		"    172.17.255.254\n",
+		"nc -z 127.0.0.1 6443",
+	} {
+		if !strings.Contains(config, e) {
+			t.Errorf("expected config to contain %q, got\n%s", e, config)
+		}
+	}
+
+	data.Bootstrap = false
+	config, err = KeepalivedConfig(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, e := range []string{"state BACKUP", "priority 100"} {
+		if !strings.Contains(config, e) {
+			t.Errorf("expected config to contain %q, got\n%s", e, config)
+		}
+	}
+}
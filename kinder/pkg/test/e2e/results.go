@@ -0,0 +1,179 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package e2e
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"k8s.io/kubeadm/kinder/pkg/test/junit"
+)
+
+// collectResults parses the junit files written in the report dir after the given time,
+// prints a summary of the test results and returns all the test cases merged in a single test suite
+// together with the list of parsed junit files
+func collectResults(reportDir string, since time.Time) (*junit.TestSuite, []string) {
+	results := &junit.TestSuite{}
+
+	files, err := junitFiles(reportDir, since)
+	if err != nil {
+		log.Warnf("failed to read junit files in %s: %v", reportDir, err)
+		return results, nil
+	}
+	if len(files) == 0 {
+		log.Warnf("no junit files found in %s", reportDir)
+		return results, nil
+	}
+
+	for _, f := range files {
+		suites, err := junit.ParseFile(f)
+		if err != nil {
+			log.Warnf("skipping junit file %s: %v", f, err)
+			continue
+		}
+		for i := range suites {
+			results.Merge(&suites[i])
+		}
+	}
+
+	printSummary(results)
+	return results, files
+}
+
+// markFlakes rewrites the given junit files from previous test runs, marking as skipped the failed
+// test cases that passed when re-run; this prevents tools reading all the junit files in the
+// artifacts folder from reporting flakes as failures
+func markFlakes(files []string, rerun *junit.TestSuite) {
+	passed := map[string]bool{}
+	for _, tc := range rerun.Cases {
+		if tc.Failure == "" && tc.Skipped == "" {
+			passed[tc.ClassName+"/"+tc.Name] = true
+		}
+	}
+
+	for _, f := range files {
+		suites, err := junit.ParseFile(f)
+		if err != nil {
+			log.Warnf("skipping junit file %s: %v", f, err)
+			continue
+		}
+
+		marked := 0
+		suite := &junit.TestSuite{}
+		for _, s := range suites {
+			if suite.Name == "" {
+				suite.Name = s.Name
+			}
+			suite.Time += s.Time
+			for _, tc := range s.Cases {
+				options := []junit.TestCaseOption{
+					junit.WithDuration(time.Duration(tc.Time * float64(time.Second))),
+				}
+				switch {
+				case tc.Failure != "" && passed[tc.ClassName+"/"+tc.Name]:
+					options = append(options, junit.WithSkipped(fmt.Sprintf("flake: passed when re-run; original failure: %s", firstLine(tc.Failure))))
+					marked++
+				case tc.Failure != "":
+					options = append(options, junit.WithFailure(tc.Failure))
+				case tc.Skipped != "":
+					options = append(options, junit.WithSkipped(tc.Skipped))
+				}
+				suite.AddTestCase(tc.ClassName, tc.Name, options...)
+			}
+		}
+		if marked == 0 {
+			continue
+		}
+
+		if err := suite.WriteFile(f); err != nil {
+			log.Warnf("failed to mark flakes in junit file %s: %v", f, err)
+			continue
+		}
+		log.Infof("marked %d flakes as skipped in junit file %s", marked, f)
+	}
+}
+
+// junitFiles returns the junit files in a folder, modified after the given time
+func junitFiles(dir string, since time.Time) ([]string, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, i := range infos {
+		if i.IsDir() || !strings.HasPrefix(i.Name(), "junit") || filepath.Ext(i.Name()) != ".xml" {
+			continue
+		}
+		// skip junit files from previous test runs
+		if i.ModTime().Before(since) {
+			continue
+		}
+		files = append(files, filepath.Join(dir, i.Name()))
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// printSummary prints a summary of test results, including the list of failed specs
+func printSummary(results *junit.TestSuite) {
+	skipped := results.SkippedTestCases()
+	run := results.Tests - skipped
+	fmt.Printf("\nRan %d of %d specs: %d Passed | %d Failed | %d Skipped\n", run, results.Tests, run-results.Failures, results.Failures, skipped)
+
+	failed := results.FailedTestCases()
+	if len(failed) == 0 {
+		return
+	}
+
+	fmt.Printf("\nSummarizing %d failures:\n", len(failed))
+	for _, tc := range failed {
+		fmt.Printf("- %s\n", tc.Name)
+		if msg := firstLine(tc.Failure); msg != "" {
+			fmt.Printf("  %s\n", msg)
+		}
+	}
+	fmt.Println()
+}
+
+// firstLine returns the first non empty line of a text
+func firstLine(text string) string {
+	for _, l := range strings.Split(text, "\n") {
+		if l = strings.TrimSpace(l); l != "" {
+			return l
+		}
+	}
+	return ""
+}
+
+// failedSpecsRegex returns a regex for instructing ginkgo to focus on the given failed specs only
+func failedSpecsRegex(failed []junit.TestCase) string {
+	var names []string
+	for _, tc := range failed {
+		names = append(names, regexp.QuoteMeta(tc.Name))
+	}
+	// nb. the regex is not anchored because ginkgo matches focus against the full spec text,
+	// that can include a prefix not reported in junit test case names
+	return strings.Join(names, "|")
+}
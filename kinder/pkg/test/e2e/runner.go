@@ -17,13 +17,18 @@ limitations under the License.
 /*
 Package e2e implements support for running kubeadm e2e tests or kubernetes e2e test.
 
-It takes care of building upstream test suites if necessary, and provides "sane" defaults
-for simplifying test invocation.
+It takes care of building upstream test suites if necessary, or of fetching prebuilt test suites
+from the kubernetes-test artifacts, and provides "sane" defaults for simplifying test invocation.
 */
 package e2e
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
@@ -40,6 +45,22 @@ func KubeRoot(kubeRoot string) Option {
 	}
 }
 
+// TestBinaries option sets the source of prebuilt test binaries; it can be a Kubernetes version or label,
+// an http/https repository, a local kubernetes-test tarball or a local folder containing the test binaries.
+// When set, a Kubernetes checkout is not required
+func TestBinaries(src string) Option {
+	return func(r *Runner) {
+		r.testBinaries = src
+	}
+}
+
+// RerunFailed option sets the number of times failed specs should be re-run for detecting flakes
+func RerunFailed(attempts int) Option {
+	return func(r *Runner) {
+		r.rerunFailed = attempts
+	}
+}
+
 // WithGinkgoFlags option sets flags for ginkgo
 func WithGinkgoFlags(ginkgoFlags GinkgoFlags) Option {
 	return func(r *Runner) {
@@ -59,6 +80,8 @@ type Runner struct {
 	testBinary     string
 	makeBinaryGoal string
 	kubeRoot       string
+	testBinaries   string
+	rerunFailed    int
 	ginkgoFlags    GinkgoFlags
 	suiteFlags     SuiteFlags
 }
@@ -85,8 +108,12 @@ func newTestRunner(testBinary, makeBinaryGoal string, options ...Option) (runner
 		option(runner)
 	}
 
-	// sets kubeRoot if not provided by the user
-	if runner.kubeRoot == "" {
+	if runner.kubeRoot != "" && runner.testBinaries != "" {
+		return nil, errors.New("kube-root and test-binaries are mutually exclusive")
+	}
+
+	// sets kubeRoot if not provided by the user and if not using prebuilt test binaries
+	if runner.kubeRoot == "" && runner.testBinaries == "" {
 		runner.kubeRoot, err = findKubeRoot()
 		if err != nil {
 			return nil, errors.Wrap(err, "")
@@ -99,27 +126,114 @@ func newTestRunner(testBinary, makeBinaryGoal string, options ...Option) (runner
 
 // Run executes tests as defined by the selected runner options.
 // it takes care of building ginkgo and upstream test suites if necessary,
+// or of fetching prebuilt binaries when using test binaries.
+// After the test run, junit results are parsed and a summary of failed specs is printed;
+// if requested, failed specs are re-run in order to detect flakes
 func (r *Runner) Run() error {
-	// find a ginkgo binary or build it if it not exists
-	ginkgoBinary, err := getOrBuildBinary(r.kubeRoot, "ginkgo", "vendor/github.com/onsi/ginkgo/ginkgo")
+	ginkgoBinary, testBinary, cleanup, err := r.getBinaries()
 	if err != nil {
 		return err
 	}
+	defer cleanup()
 
-	// find the binary with the test suites to be executes or build it if it not exists
-	testBinary, err := getOrBuildBinary(r.kubeRoot, r.testBinary, r.makeBinaryGoal)
+	// sets the folder where the test suite writes junit results
+	reportDir, err := r.setupReportDir()
 	if err != nil {
 		return err
 	}
 
+	start := time.Now()
+	err = runGinkgo(ginkgoBinary, testBinary, r.ginkgoFlags, r.suiteFlags)
+	results, files := collectResults(reportDir, start)
+	if err == nil {
+		return nil
+	}
+
+	// if requested, re-run failed specs for detecting flakes
+	for i := 1; i <= r.rerunFailed; i++ {
+		failed := results.FailedTestCases()
+		if len(failed) == 0 {
+			break
+		}
+
+		log.Infof("Re-running %d failed specs (attempt %d of %d)", len(failed), i, r.rerunFailed)
+
+		ginkgoFlags := GinkgoFlags{}
+		for k, v := range r.ginkgoFlags {
+			ginkgoFlags[k] = v
+		}
+		ginkgoFlags["focus"] = failedSpecsRegex(failed)
+
+		suiteFlags := SuiteFlags{}
+		for k, v := range r.suiteFlags {
+			suiteFlags[k] = v
+		}
+		suiteFlags["report-dir"] = filepath.Join(reportDir, fmt.Sprintf("rerun-%d", i))
+		if err := os.MkdirAll(suiteFlags["report-dir"], 0755); err != nil {
+			return errors.Wrapf(err, "failed to create %s dir", suiteFlags["report-dir"])
+		}
+
+		start = time.Now()
+		rerunErr := runGinkgo(ginkgoBinary, testBinary, ginkgoFlags, suiteFlags)
+		var rerunFiles []string
+		results, rerunFiles = collectResults(suiteFlags["report-dir"], start)
+
+		// junit files from previous runs are kept in the report dir, so flakes are marked as skipped there
+		markFlakes(files, results)
+		files = append(files, rerunFiles...)
+
+		if rerunErr == nil {
+			log.Warnf("All the failed specs passed when re-run, they are considered flakes:")
+			for _, tc := range failed {
+				log.Warnf("- %s", tc.Name)
+			}
+			return nil
+		}
+	}
+
+	return err
+}
+
+// setupReportDir returns the folder where the test suite writes junit results.
+// If the report dir is not set in the suite flags, it defaults to a folder in $ARTIFACTS named like
+// the test suite, or to a temporary folder if $ARTIFACTS is not set
+func (r *Runner) setupReportDir() (string, error) {
+	if r.suiteFlags == nil {
+		r.suiteFlags = SuiteFlags{}
+	}
+	if dir, ok := r.suiteFlags["report-dir"]; ok {
+		return dir, nil
+	}
+
+	var dir string
+	if artifacts := os.Getenv("ARTIFACTS"); artifacts != "" {
+		dir = filepath.Join(artifacts, strings.TrimSuffix(r.testBinary, ".test"))
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return "", errors.Wrapf(err, "failed to create %s dir", dir)
+		}
+	} else {
+		var err error
+		dir, err = ioutil.TempDir("", "kinder-e2e-report")
+		if err != nil {
+			return "", errors.Wrap(err, "failed to create a temporary report dir")
+		}
+	}
+
+	log.Infof("using %s as a report dir", dir)
+	r.suiteFlags["report-dir"] = dir
+	return dir, nil
+}
+
+// runGinkgo runs ginkgo with the given flags and test suite
+func runGinkgo(ginkgoBinary, testBinary string, ginkgoFlags GinkgoFlags, suiteFlags SuiteFlags) error {
 	// prepare args to be passed to ginkgo test runner:
 	// ginkgo [ginkgo-flags] [test-suite-binary] -- [test-suite-flags]
 	var args []string
-	for k, v := range r.ginkgoFlags {
+	for k, v := range ginkgoFlags {
 		args = append(args, fmt.Sprintf("--%s=%s", k, v))
 	}
 	args = append(args, testBinary, "--")
-	for k, v := range r.suiteFlags {
+	for k, v := range suiteFlags {
 		args = append(args, fmt.Sprintf("--%s=%s", k, v))
 	}
 
@@ -128,7 +242,7 @@ func (r *Runner) Run() error {
 	// executes the command.
 	// TODO: switch to an executor that supports timeout/cancellation
 	cmd := exec.NewHostCmd(ginkgoBinary, args...)
-	if err = cmd.RunWithEcho(); err != nil {
+	if err := cmd.RunWithEcho(); err != nil {
 		return errors.Wrap(err, "error running test")
 	}
 
@@ -136,3 +250,30 @@ func (r *Runner) Run() error {
 
 	return nil
 }
+
+// getBinaries returns the ginkgo binary and the binary with the test suites to be executed, and a func
+// for removing temporary files created while getting them
+func (r *Runner) getBinaries() (ginkgoBinary, testBinary string, cleanup func(), err error) {
+	// if using prebuilt test binaries, gets them from the given source
+	if r.testBinaries != "" {
+		dir, cleanup, err := getTestBinaries(r.testBinaries, "ginkgo", r.testBinary)
+		if err != nil {
+			return "", "", nil, err
+		}
+		return filepath.Join(dir, "ginkgo"), filepath.Join(dir, r.testBinary), cleanup, nil
+	}
+
+	// find a ginkgo binary or build it if it not exists
+	ginkgoBinary, err = getOrBuildBinary(r.kubeRoot, "ginkgo", "vendor/github.com/onsi/ginkgo/ginkgo")
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	// find the binary with the test suites to be executes or build it if it not exists
+	testBinary, err = getOrBuildBinary(r.kubeRoot, r.testBinary, r.makeBinaryGoal)
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	return ginkgoBinary, testBinary, func() {}, nil
+}
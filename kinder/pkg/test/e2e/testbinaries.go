@@ -0,0 +1,262 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package e2e
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	K8sVersion "k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/kubeadm/kinder/pkg/extract"
+)
+
+// testBinariesTarballFolder defines the folder in the kubernetes-test tarball where test binaries are stored
+const testBinariesTarballFolder = "kubernetes/test/bin"
+
+// getTestBinaries returns a folder with the prebuilt ginkgo and test binaries from the given source.
+// src can be a local folder already containing the binaries, or any source supported by the extract package
+// (Kubernetes version, release/ci label, http/https repository, local folder or kubernetes-test tarball);
+// test binaries extracted from the kubernetes-test tarball of Kubernetes versions, labels or http/https repositories
+// are cached locally, so subsequent runs don't require to download them again, while test binaries from
+// local sources are extracted into a temporary folder, to be removed by invoking the returned cleanup func.
+func getTestBinaries(src string, binaries ...string) (dir string, cleanup func(), err error) {
+	cleanup = func() {}
+
+	// if src is a local folder already containing the test binaries, use it
+	if extract.GetSourceType(src) == extract.LocalRepositorySource {
+		src = strings.TrimPrefix(src, "file://")
+		if hasBinaries(src, binaries...) {
+			dir, _ := filepath.Abs(src)
+			log.Infof("using test binaries in %s folder", dir)
+			return dir, cleanup, nil
+		}
+	}
+
+	key, err := testBinariesCacheKey(src)
+	if err != nil {
+		return "", cleanup, err
+	}
+
+	// if test binaries from src can't be cached, extract them into a temporary folder
+	if key == "" {
+		tmpDir, err := ioutil.TempDir("", "kinder-test-binaries-")
+		if err != nil {
+			return "", cleanup, errors.Wrap(err, "failed to create a temporary dir for test binaries")
+		}
+		cleanup = func() { os.RemoveAll(tmpDir) }
+
+		dir = filepath.Join(tmpDir, "bin")
+		if err := extractTestBinaries(src, tmpDir, dir, binaries...); err != nil {
+			cleanup()
+			return "", func() {}, err
+		}
+		return dir, cleanup, nil
+	}
+
+	cacheRoot, err := testBinariesCacheRoot()
+	if err != nil {
+		return "", cleanup, err
+	}
+
+	// if test binaries for src are already in cache, use them
+	dir = filepath.Join(cacheRoot, key)
+	if hasBinaries(dir, binaries...) {
+		log.Infof("using test binaries cached in %s folder", dir)
+		return dir, cleanup, nil
+	}
+
+	if err := os.MkdirAll(cacheRoot, 0755); err != nil {
+		return "", cleanup, errors.Wrapf(err, "failed to create %s dir", cacheRoot)
+	}
+
+	// download the kubernetes-test tarball into a temporary folder
+	tmpDir, err := ioutil.TempDir(cacheRoot, "tmp-")
+	if err != nil {
+		return "", cleanup, errors.Wrap(err, "failed to create a temporary dir for test binaries")
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// extract the test binaries from the tarball, and then move them into the cache
+	binDir := filepath.Join(tmpDir, "bin")
+	if err := extractTestBinaries(src, tmpDir, binDir, binaries...); err != nil {
+		return "", cleanup, err
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		return "", cleanup, errors.Wrapf(err, "failed to cleanup %s dir", dir)
+	}
+	if err := os.Rename(binDir, dir); err != nil {
+		return "", cleanup, errors.Wrapf(err, "failed to move test binaries into %s", dir)
+	}
+
+	log.Infof("Test binaries saved into %s", dir)
+	return dir, cleanup, nil
+}
+
+// extractTestBinaries gets the kubernetes-test tarball from src into tmpDir, and then extracts
+// the test binaries into binDir
+func extractTestBinaries(src, tmpDir, binDir string, binaries ...string) error {
+	e := extract.NewExtractor(src, tmpDir, extract.OnlyKubernetesTestTarball(true))
+	paths, err := e.Extract()
+	if err != nil {
+		return errors.Wrapf(err, "failed to get the %s from %s", extract.KubernetesTestTarball, src)
+	}
+
+	for _, p := range paths {
+		log.Infof("Extracting test binaries from %s", p)
+		if err := untarTestBinaries(p, binDir); err != nil {
+			return err
+		}
+	}
+
+	if !hasBinaries(binDir, binaries...) {
+		return errors.Errorf("%s from %s does not contain %s", extract.KubernetesTestTarball, src, strings.Join(binaries, ", "))
+	}
+	return nil
+}
+
+// testBinariesCacheRoot returns the folder where test binaries are cached
+func testBinariesCacheRoot() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get the user cache dir")
+	}
+	return filepath.Join(cacheDir, "kinder", "test-binaries"), nil
+}
+
+// testBinariesCacheKey returns the name of the cache folder for test binaries from the given source.
+// Kubernetes versions and labels are cached by version, while http/https repositories by a hash of
+// the tarball URL and of the validators returned by the server (ETag, Last-Modified, Content-Length).
+// An empty key is returned for sources that should not be cached, like local sources or
+// http/https repositories not returning validators.
+func testBinariesCacheKey(src string) (string, error) {
+	switch extract.GetSourceType(src) {
+	case extract.ReleaseLabelOrVersionSource, extract.CILabelOrVersionSource:
+		s := strings.TrimPrefix(strings.TrimPrefix(src, "release/"), "ci/")
+		if v, err := K8sVersion.ParseSemantic(s); err == nil {
+			return fmt.Sprintf("v%s", v), nil
+		}
+		version, err := extract.ResolveLabel(src)
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to resolve %s", src)
+		}
+		return version, nil
+	case extract.RemoteRepositorySource:
+		url := fmt.Sprintf("%s/%s", strings.TrimSuffix(src, "/"), extract.KubernetesTestTarball)
+		client := http.Client{Timeout: 30 * time.Second}
+		resp, err := client.Head(url)
+		if err != nil {
+			log.Warnf("HTTP HEAD %s failed, test binaries won't be cached: %v", url, err)
+			return "", nil
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			log.Warnf("HTTP HEAD %s failed, test binaries won't be cached: %s", url, resp.Status)
+			return "", nil
+		}
+		return remoteTestBinariesCacheKey(url, resp.Header), nil
+	}
+	return "", nil
+}
+
+// remoteTestBinariesCacheKey returns the cache key for a kubernetes-test tarball served at the given url
+// with the given response headers, or an empty key if the server didn't return an ETag or Last-Modified header
+func remoteTestBinariesCacheKey(url string, header http.Header) string {
+	etag, lastModified := header.Get("ETag"), header.Get("Last-Modified")
+	if etag == "" && lastModified == "" {
+		return ""
+	}
+	s := strings.Join([]string{url, etag, lastModified, header.Get("Content-Length")}, "\n")
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(s)))[:12]
+}
+
+// hasBinaries returns true if all the given binaries exist in dir
+func hasBinaries(dir string, binaries ...string) bool {
+	for _, b := range binaries {
+		if info, err := os.Stat(filepath.Join(dir, b)); err != nil || info.IsDir() {
+			return false
+		}
+	}
+	return true
+}
+
+// untarTestBinaries extracts all the files in the test binaries folder of a kubernetes-test tarball into dst
+func untarTestBinaries(tarball, dst string) error {
+	f, err := os.Open(tarball)
+	if err != nil {
+		return errors.Wrapf(err, "failed to open %s", tarball)
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read %s", tarball)
+	}
+	defer gz.Close()
+
+	if err := os.MkdirAll(dst, 0755); err != nil {
+		return errors.Wrapf(err, "failed to create %s dir", dst)
+	}
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrapf(err, "failed to read %s", tarball)
+		}
+
+		// skip everything that is not a regular file in the test binaries folder
+		if hdr.Typeflag != tar.TypeReg || path.Dir(path.Clean(hdr.Name)) != testBinariesTarballFolder {
+			continue
+		}
+
+		name := filepath.Join(dst, path.Base(hdr.Name))
+		if err := writeExecutable(name, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// writeExecutable writes an executable file with the content read from r
+func writeExecutable(name string, r io.Reader) error {
+	w, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0755)
+	if err != nil {
+		return errors.Wrapf(err, "error creating %s", name)
+	}
+	defer w.Close()
+
+	if _, err := io.Copy(w, r); err != nil {
+		return errors.Wrapf(err, "error writing %s", name)
+	}
+	return nil
+}
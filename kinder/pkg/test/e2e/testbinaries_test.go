@@ -0,0 +1,161 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package e2e
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func TestTestBinariesCacheKey(t *testing.T) {
+	cases := []struct {
+		name     string
+		src      string
+		expected string
+	}{
+		{name: "release version", src: "v1.19.0", expected: "v1.19.0"},
+		{name: "release version with prefix", src: "release/v1.19.0", expected: "v1.19.0"},
+		{name: "ci version", src: "ci/v1.20.0-alpha.0.1+1234567890abcd", expected: "v1.20.0-alpha.0.1+1234567890abcd"},
+		{name: "local tarball", src: "/tmp/kubernetes-test-linux-amd64.tar.gz", expected: ""},
+		{name: "local folder", src: "file:///tmp/test-binaries", expected: ""},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			key, err := testBinariesCacheKey(c.src)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if c.expected != key {
+				t.Errorf("expected %q, got %q", c.expected, key)
+			}
+		})
+	}
+}
+
+func TestRemoteTestBinariesCacheKey(t *testing.T) {
+	url := "https://example.com/v1.19.0/kubernetes-test-linux-amd64.tar.gz"
+	header := func(kv ...string) http.Header {
+		h := http.Header{}
+		for i := 0; i < len(kv); i += 2 {
+			h.Set(kv[i], kv[i+1])
+		}
+		return h
+	}
+
+	if key := remoteTestBinariesCacheKey(url, header("Content-Length", "100")); key != "" {
+		t.Errorf("expected no key without validators, got %q", key)
+	}
+
+	key := remoteTestBinariesCacheKey(url, header("ETag", "\"a\"", "Content-Length", "100"))
+	if key == "" {
+		t.Fatal("expected a key with ETag")
+	}
+	if other := remoteTestBinariesCacheKey(url, header("ETag", "\"b\"", "Content-Length", "100")); other == key {
+		t.Errorf("expected a different key for a different ETag, got %q", other)
+	}
+	if other := remoteTestBinariesCacheKey(url, header("ETag", "\"a\"", "Content-Length", "200")); other == key {
+		t.Errorf("expected a different key for a different size, got %q", other)
+	}
+}
+
+func TestUntarTestBinaries(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kinder-test-binaries")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tarball := filepath.Join(dir, "kubernetes-test-linux-amd64.tar.gz")
+	writeTarball(t, tarball, []string{
+		"kubernetes/",
+		"kubernetes/test/bin/ginkgo",
+		"kubernetes/test/bin/e2e.test",
+		"kubernetes/test/bin/e2e_kubeadm.test",
+		"kubernetes/test/e2e/testing-manifests/foo.yaml",
+		"kubernetes/version",
+	})
+
+	dst := filepath.Join(dir, "bin")
+	if err := untarTestBinaries(tarball, dst); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	infos, err := ioutil.ReadDir(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var files []string
+	for _, i := range infos {
+		if i.Mode().Perm()&0100 == 0 {
+			t.Errorf("expected %s to be executable", i.Name())
+		}
+		files = append(files, i.Name())
+	}
+	sort.Strings(files)
+
+	expected := []string{"e2e.test", "e2e_kubeadm.test", "ginkgo"}
+	if len(files) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, files)
+	}
+	for i := range expected {
+		if files[i] != expected[i] {
+			t.Errorf("expected %v, got %v", expected, files)
+		}
+	}
+
+	if !hasBinaries(dst, "ginkgo", "e2e_kubeadm.test") {
+		t.Errorf("expected binaries in %s", dst)
+	}
+	if hasBinaries(dst, "ginkgo", "e2e_node.test") {
+		t.Errorf("unexpected e2e_node.test binary in %s", dst)
+	}
+}
+
+func writeTarball(t *testing.T, name string, entries []string) {
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	gz := gzip.NewWriter(f)
+	defer gz.Close()
+	tw := tar.NewWriter(gz)
+	defer tw.Close()
+
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e, Mode: 0644, Typeflag: tar.TypeReg, Size: int64(len(e))}
+		if e[len(e)-1] == '/' {
+			hdr = &tar.Header{Name: e, Mode: 0755, Typeflag: tar.TypeDir}
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if hdr.Typeflag == tar.TypeReg {
+			if _, err := tw.Write([]byte(e)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
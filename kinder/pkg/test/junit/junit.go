@@ -0,0 +1,263 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package junit implements the junit test suite and test case standard objects used for
+reporting kinder test results, and the utilities for reading, merging and writing them to file.
+*/
+package junit
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// TestSuite implements junit TestSuite standard object
+type TestSuite struct {
+	XMLName  xml.Name `xml:"testsuite"`
+	Name     string   `xml:"name,attr,omitempty"`
+	Failures int      `xml:"failures,attr"`
+	Tests    int      `xml:"tests,attr"`
+	Time     float64  `xml:"time,attr"`
+	Cases    []TestCase
+}
+
+// TestCase implements junit TestCase standard object
+type TestCase struct {
+	XMLName   xml.Name `xml:"testcase"`
+	ClassName string   `xml:"classname,attr"`
+	Name      string   `xml:"name,attr"`
+	Time      float64  `xml:"time,attr"`
+	Failure   string   `xml:"failure,omitempty"`
+	Skipped   string   `xml:"skipped,omitempty"`
+}
+
+// TestCaseOption defines an option for a test case
+type TestCaseOption func(*TestCase)
+
+// WithDuration sets the duration of a test case
+func WithDuration(duration time.Duration) TestCaseOption {
+	return func(t *TestCase) {
+		t.Time = duration.Seconds()
+	}
+}
+
+// WithFailure sets a test case as failed with the given message
+func WithFailure(message string) TestCaseOption {
+	return func(t *TestCase) {
+		t.Failure = message
+	}
+}
+
+// WithSkipped sets a test case as skipped with the given message
+func WithSkipped(message string) TestCaseOption {
+	return func(t *TestCase) {
+		t.Skipped = message
+	}
+}
+
+// AddTestCase adds a test case to the test suite, updating test and failure counters accordingly
+func (s *TestSuite) AddTestCase(className, name string, options ...TestCaseOption) {
+	tc := TestCase{
+		ClassName: className,
+		Name:      name,
+	}
+
+	for _, option := range options {
+		option(&tc)
+	}
+
+	s.Cases = append(s.Cases, tc)
+	s.Tests++
+	if tc.Failure != "" {
+		s.Failures++
+	}
+}
+
+// WriteFile writes the test suite into a junit file
+func (s *TestSuite) WriteFile(file string) error {
+	out, err := xml.MarshalIndent(s, "", "    ")
+	if err != nil {
+		return errors.Wrapf(err, "error marshaling test suite results")
+	}
+	f, err := os.Create(file)
+	if err != nil {
+		return errors.Wrapf(err, "error creating %s", file)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(xml.Header); err != nil {
+		return errors.Wrapf(err, "error writing XML header to %s", file)
+	}
+	if _, err := f.Write(out); err != nil {
+		return errors.Wrapf(err, "error writing XML data to %s", file)
+	}
+	return nil
+}
+
+// Merge adds all the test cases from another test suite, updating test, failure counters
+// and duration accordingly
+func (s *TestSuite) Merge(other *TestSuite) {
+	s.Cases = append(s.Cases, other.Cases...)
+	s.Tests += other.Tests
+	s.Failures += other.Failures
+	s.Time += other.Time
+}
+
+// Dedup removes duplicated test cases, e.g. specs re-run for detecting flakes, keeping the last result
+// for each class name and name; test and failure counters are updated accordingly
+func (s *TestSuite) Dedup() {
+	key := func(tc TestCase) string { return tc.ClassName + "/" + tc.Name }
+
+	last := map[string]int{}
+	for i, tc := range s.Cases {
+		last[key(tc)] = i
+	}
+
+	var cases []TestCase
+	failures := 0
+	for i, tc := range s.Cases {
+		if last[key(tc)] != i {
+			continue
+		}
+		cases = append(cases, tc)
+		if tc.Failure != "" {
+			failures++
+		}
+	}
+	s.Cases = cases
+	s.Tests = len(cases)
+	s.Failures = failures
+}
+
+// FailedTestCases returns the list of failed test cases in the test suite
+func (s *TestSuite) FailedTestCases() []TestCase {
+	var failed []TestCase
+	for _, tc := range s.Cases {
+		if tc.Failure != "" {
+			failed = append(failed, tc)
+		}
+	}
+	return failed
+}
+
+// SkippedTestCases returns the number of skipped test cases in the test suite
+func (s *TestSuite) SkippedTestCases() int {
+	skipped := 0
+	for _, tc := range s.Cases {
+		if tc.Skipped != "" {
+			skipped++
+		}
+	}
+	return skipped
+}
+
+// xmlTestSuites, xmlTestSuite, xmlTestCase and xmlMessage are used for reading junit files generated
+// by other tools, e.g. ginkgo, where failure and skipped are elements with an optional message attribute
+type xmlTestSuites struct {
+	Suites []xmlTestSuite `xml:"testsuite"`
+}
+
+type xmlTestSuite struct {
+	Name  string        `xml:"name,attr"`
+	Time  float64       `xml:"time,attr"`
+	Cases []xmlTestCase `xml:"testcase"`
+}
+
+type xmlTestCase struct {
+	ClassName string      `xml:"classname,attr"`
+	Name      string      `xml:"name,attr"`
+	Time      float64     `xml:"time,attr"`
+	Failure   *xmlMessage `xml:"failure"`
+	Error     *xmlMessage `xml:"error"`
+	Skipped   *xmlMessage `xml:"skipped"`
+}
+
+type xmlMessage struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// String returns the text of a junit message, or the message attribute if the text is empty
+func (m *xmlMessage) String(defaultMessage string) string {
+	if text := strings.TrimSpace(m.Text); text != "" {
+		return text
+	}
+	if m.Message != "" {
+		return m.Message
+	}
+	return defaultMessage
+}
+
+// Parse reads test suites from junit data; both a single testsuite and a list of testsuites are supported
+func Parse(data []byte) ([]TestSuite, error) {
+	var suites []xmlTestSuite
+	if bytes.Contains(data, []byte("<testsuites")) {
+		var s xmlTestSuites
+		if err := xml.Unmarshal(data, &s); err != nil {
+			return nil, errors.Wrap(err, "error unmarshaling junit testsuites")
+		}
+		suites = s.Suites
+	} else {
+		var s xmlTestSuite
+		if err := xml.Unmarshal(data, &s); err != nil {
+			return nil, errors.Wrap(err, "error unmarshaling junit testsuite")
+		}
+		suites = []xmlTestSuite{s}
+	}
+
+	var ret []TestSuite
+	for _, xs := range suites {
+		s := TestSuite{
+			Name: xs.Name,
+			Time: xs.Time,
+		}
+		for _, xc := range xs.Cases {
+			options := []TestCaseOption{
+				WithDuration(time.Duration(xc.Time * float64(time.Second))),
+			}
+			switch {
+			case xc.Failure != nil:
+				options = append(options, WithFailure(xc.Failure.String("failed")))
+			case xc.Error != nil:
+				options = append(options, WithFailure(xc.Error.String("error")))
+			case xc.Skipped != nil:
+				options = append(options, WithSkipped(xc.Skipped.String("skipped")))
+			}
+			s.AddTestCase(xc.ClassName, xc.Name, options...)
+		}
+		ret = append(ret, s)
+	}
+	return ret, nil
+}
+
+// ParseFile reads test suites from a junit file
+func ParseFile(file string) ([]TestSuite, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading %s", file)
+	}
+	suites, err := Parse(data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error parsing %s", file)
+	}
+	return suites, nil
+}
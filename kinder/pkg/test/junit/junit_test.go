@@ -0,0 +1,154 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package junit
+
+import (
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		name             string
+		data             string
+		expectedSuites   int
+		expectedTests    int
+		expectedFailures int
+		expectedSkipped  int
+		expectedFailed   []string
+		expectError      bool
+	}{
+		{
+			name: "ginkgo testsuite",
+			data: `<?xml version="1.0" encoding="UTF-8"?>
+<testsuite name="Kubernetes e2e suite" tests="4" failures="1" errors="0" time="12.5">
+  <testcase name="[sig-cluster-lifecycle] [area:kubeadm] should pass" classname="Kubernetes e2e suite" time="1.2"></testcase>
+  <testcase name="[sig-cluster-lifecycle] [area:kubeadm] should fail" classname="Kubernetes e2e suite" time="3.4">
+    <failure type="Failure">/go/src/test.go:42&#xA;expected true, got false</failure>
+  </testcase>
+  <testcase name="[sig-cluster-lifecycle] [area:kubeadm] [copy-certs] should be skipped" classname="Kubernetes e2e suite" time="0">
+    <skipped></skipped>
+  </testcase>
+  <testcase name="[k8s.io] [sig-node] should also pass" classname="Kubernetes e2e suite" time="0.1"></testcase>
+</testsuite>`,
+			expectedSuites:   1,
+			expectedTests:    4,
+			expectedFailures: 1,
+			expectedSkipped:  1,
+			expectedFailed:   []string{"[sig-cluster-lifecycle] [area:kubeadm] should fail"},
+		},
+		{
+			name: "testsuites",
+			data: `<testsuites>
+  <testsuite name="a" tests="1" failures="1">
+    <testcase name="error" classname="a"><error message="boom"/></testcase>
+  </testsuite>
+  <testsuite name="b" tests="1" failures="0">
+    <testcase name="pass" classname="b"/>
+  </testsuite>
+</testsuites>`,
+			expectedSuites:   2,
+			expectedTests:    2,
+			expectedFailures: 1,
+			expectedFailed:   []string{"error"},
+		},
+		{
+			name: "kinder testsuite",
+			data: `<testsuite failures="1" tests="2" time="10">
+    <testcase classname="kinder.test.workflow" name="task-00-create" time="5"></testcase>
+    <testcase classname="kinder.test.workflow" name="task-01-init" time="5">
+        <failure>exit status 1</failure>
+    </testcase>
+</testsuite>`,
+			expectedSuites:   1,
+			expectedTests:    2,
+			expectedFailures: 1,
+			expectedFailed:   []string{"task-01-init"},
+		},
+		{
+			name:        "invalid",
+			data:        `this is not xml`,
+			expectError: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			suites, err := Parse([]byte(c.data))
+			if err != nil {
+				if !c.expectError {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if c.expectError {
+				t.Fatalf("expected error, got nil")
+			}
+			if len(suites) != c.expectedSuites {
+				t.Fatalf("expected %d suites, got %d", c.expectedSuites, len(suites))
+			}
+
+			merged := &TestSuite{}
+			for i := range suites {
+				merged.Merge(&suites[i])
+			}
+			if merged.Tests != c.expectedTests {
+				t.Errorf("expected %d tests, got %d", c.expectedTests, merged.Tests)
+			}
+			if merged.Failures != c.expectedFailures {
+				t.Errorf("expected %d failures, got %d", c.expectedFailures, merged.Failures)
+			}
+			if merged.SkippedTestCases() != c.expectedSkipped {
+				t.Errorf("expected %d skipped, got %d", c.expectedSkipped, merged.SkippedTestCases())
+			}
+			failed := merged.FailedTestCases()
+			if len(failed) != len(c.expectedFailed) {
+				t.Fatalf("expected %d failed test cases, got %d", len(c.expectedFailed), len(failed))
+			}
+			for i := range failed {
+				if failed[i].Name != c.expectedFailed[i] {
+					t.Errorf("expected failed test case %q, got %q", c.expectedFailed[i], failed[i].Name)
+				}
+				if failed[i].Failure == "" {
+					t.Errorf("expected a failure message for %q", failed[i].Name)
+				}
+			}
+		})
+	}
+}
+
+func TestDedup(t *testing.T) {
+	s := &TestSuite{}
+	s.AddTestCase("e2e", "flake", WithFailure("timeout"))
+	s.AddTestCase("e2e", "pass")
+	s.AddTestCase("e2e", "fail", WithFailure("boom"))
+	s.AddTestCase("other", "flake")
+	s.AddTestCase("e2e", "flake")
+	s.AddTestCase("e2e", "fail", WithFailure("boom again"))
+
+	s.Dedup()
+
+	if s.Tests != 4 {
+		t.Errorf("expected 4 tests, got %d", s.Tests)
+	}
+	if s.Failures != 1 {
+		t.Errorf("expected 1 failure, got %d", s.Failures)
+	}
+	failed := s.FailedTestCases()
+	if len(failed) != 1 || failed[0].Name != "fail" || failed[0].Failure != "boom again" {
+		t.Errorf("expected the last result of fail, got %v", failed)
+	}
+}
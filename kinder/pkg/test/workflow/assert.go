@@ -0,0 +1,499 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/util/jsonpath"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/constants"
+)
+
+// validate checks that the assert defines a cluster and a list of valid expectations
+func (a *Assert) validate() error {
+	if a.Cluster == "" {
+		return errors.New("assert does not define a cluster")
+	}
+	if len(a.Expect) == 0 {
+		return errors.New("assert does not define any expectation")
+	}
+	for i, e := range a.Expect {
+		if err := e.validate(); err != nil {
+			return errors.Wrapf(err, "invalid expectation #%d", i+1)
+		}
+	}
+	return nil
+}
+
+// validate checks that one and only one type of expectation is defined, with all the required fields
+func (e *Expectation) validate() error {
+	set := 0
+	for _, isSet := range []bool{e.Nodes != nil, e.Pods != nil, e.File != nil, e.JSONPath != nil, e.Command != nil} {
+		if isSet {
+			set++
+		}
+	}
+	if set != 1 {
+		return errors.New("an expectation should define one of nodes, pods, file, jsonPath or command")
+	}
+
+	switch {
+	case e.File != nil:
+		if e.File.Node == "" || e.File.Path == "" {
+			return errors.New("file expectations require node and path")
+		}
+		if len(e.File.Contains) == 0 && len(e.File.NotContains) == 0 {
+			return errors.New("file expectations require contains or notContains")
+		}
+	case e.JSONPath != nil:
+		if e.JSONPath.Resource == "" || e.JSONPath.Path == "" {
+			return errors.New("jsonPath expectations require resource and path")
+		}
+		if e.JSONPath.Equals == "" && e.JSONPath.Contains == "" {
+			return errors.New("jsonPath expectations require equals or contains")
+		}
+	case e.Command != nil:
+		if e.Command.Node == "" || len(e.Command.Args) == 0 {
+			return errors.New("command expectations require node and args")
+		}
+	}
+	return nil
+}
+
+// fields returns the pointers to all the string fields of the expectation, e.g. for template expansion
+func (e *Expectation) fields() []*string {
+	switch {
+	case e.Nodes != nil:
+		return []*string{&e.Nodes.Selector, &e.Nodes.Version}
+	case e.Pods != nil:
+		return []*string{&e.Pods.Namespace, &e.Pods.Selector, &e.Pods.Image}
+	case e.File != nil:
+		fields := []*string{&e.File.Node, &e.File.Path}
+		for i := range e.File.Contains {
+			fields = append(fields, &e.File.Contains[i])
+		}
+		for i := range e.File.NotContains {
+			fields = append(fields, &e.File.NotContains[i])
+		}
+		return fields
+	case e.JSONPath != nil:
+		return []*string{&e.JSONPath.Resource, &e.JSONPath.Name, &e.JSONPath.Namespace, &e.JSONPath.Path, &e.JSONPath.Equals, &e.JSONPath.Contains}
+	case e.Command != nil:
+		fields := []*string{&e.Command.Node, &e.Command.OutputContains}
+		for i := range e.Command.Args {
+			fields = append(fields, &e.Command.Args[i])
+		}
+		return fields
+	}
+	return nil
+}
+
+// String returns a short description of the expectation, used e.g. as a test case name
+func (e *Expectation) String() string {
+	parts := []string{}
+	add := func(format string, args ...interface{}) {
+		parts = append(parts, fmt.Sprintf(format, args...))
+	}
+
+	switch {
+	case e.Nodes != nil:
+		add("nodes")
+		if e.Nodes.Selector != "" {
+			add("selector=%s", e.Nodes.Selector)
+		}
+		if e.Nodes.Count != nil {
+			add("count=%d", *e.Nodes.Count)
+		}
+		if e.Nodes.Ready {
+			add("ready")
+		}
+		if e.Nodes.Version != "" {
+			add("version=%s", e.Nodes.Version)
+		}
+	case e.Pods != nil:
+		add("pods namespace=%s", namespaceOrDefault(e.Pods.Namespace))
+		if e.Pods.Selector != "" {
+			add("selector=%s", e.Pods.Selector)
+		}
+		if e.Pods.Count != nil {
+			add("count=%d", *e.Pods.Count)
+		}
+		if e.Pods.Ready {
+			add("ready")
+		}
+		if e.Pods.Image != "" {
+			add("image=%s", e.Pods.Image)
+		}
+	case e.File != nil:
+		add("file %s:%s", e.File.Node, e.File.Path)
+		for _, c := range e.File.Contains {
+			add("contains=%q", c)
+		}
+		for _, c := range e.File.NotContains {
+			add("notContains=%q", c)
+		}
+	case e.JSONPath != nil:
+		add("jsonPath %s", strings.TrimSpace(strings.Join([]string{e.JSONPath.Resource, e.JSONPath.Name}, " ")))
+		add("%s", e.JSONPath.Path)
+		if e.JSONPath.Equals != "" {
+			add("equals=%q", e.JSONPath.Equals)
+		}
+		if e.JSONPath.Contains != "" {
+			add("contains=%q", e.JSONPath.Contains)
+		}
+	case e.Command != nil:
+		add("command %s:%s", e.Command.Node, strings.Join(e.Command.Args, " "))
+		if e.Command.ExpectFailure {
+			add("expectFailure")
+		}
+		if e.Command.OutputContains != "" {
+			add("outputContains=%q", e.Command.OutputContains)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// assertClientTimeout defines the timeout for requests to the API server while verifying expectations
+const assertClientTimeout = 30 * time.Second
+
+// assertContext provides support for verifying expectations against a cluster; expectations on
+// Kubernetes objects are verified using a client built from the admin kubeconfig file on the
+// bootstrap control-plane node
+type assertContext struct {
+	c      *status.Cluster
+	cp1    *status.Node
+	config *rest.Config
+	client kubernetes.Interface
+}
+
+// newAssertContext returns an assertContext for the given cluster
+func newAssertContext(cluster string) (*assertContext, error) {
+	c, err := status.FromDocker(cluster)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read cluster status for %s", cluster)
+	}
+	cp1 := c.BootstrapControlPlane()
+	if cp1 == nil {
+		return nil, errors.Errorf("cluster %s does not have a bootstrap control-plane node", cluster)
+	}
+	config, err := kubeConfig(cp1)
+	if err != nil {
+		return nil, err
+	}
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create a client for cluster %s", cluster)
+	}
+	return &assertContext{c: c, cp1: cp1, config: config, client: client}, nil
+}
+
+// kubeConfig returns the client config for the admin kubeconfig file on the bootstrap control-plane node;
+// the server address is replaced with the host port where the node exposes the API server
+func kubeConfig(cp1 *status.Node) (*rest.Config, error) {
+	lines, err := cp1.Command("cat", "/etc/kubernetes/admin.conf").Silent().RunAndCapture()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read the admin kubeconfig file from %s", cp1.Name())
+	}
+	config, err := clientcmd.RESTConfigFromKubeConfig([]byte(strings.Join(lines, "\n")))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load the admin kubeconfig file from %s", cp1.Name())
+	}
+	hostPort, err := cp1.Ports(constants.APIServerPort)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get the API server port for %s", cp1.Name())
+	}
+	config.Host = fmt.Sprintf("https://%s", net.JoinHostPort("localhost", fmt.Sprintf("%d", hostPort)))
+	config.Timeout = assertClientTimeout
+	return config, nil
+}
+
+// verify verifies an expectation and returns the list of problems found, if any
+func (a *assertContext) verify(e *Expectation) []string {
+	switch {
+	case e.Nodes != nil:
+		return a.verifyNodes(e.Nodes)
+	case e.Pods != nil:
+		return a.verifyPods(e.Pods)
+	case e.File != nil:
+		return a.verifyFile(e.File)
+	case e.JSONPath != nil:
+		return a.verifyJSONPath(e.JSONPath)
+	case e.Command != nil:
+		return a.verifyCommand(e.Command)
+	}
+	return nil
+}
+
+// selectNodes returns the nodes matching a node selector
+func (a *assertContext) selectNodes(selector string) (status.NodeList, error) {
+	nodes, err := a.c.SelectNodes(selector)
+	if err != nil {
+		return nil, err
+	}
+	if len(nodes) == 0 {
+		return nil, errors.Errorf("no node matches %s", selector)
+	}
+	return nodes, nil
+}
+
+func (a *assertContext) verifyNodes(e *NodesExpectation) []string {
+	nodes, err := a.client.CoreV1().Nodes().List(metav1.ListOptions{LabelSelector: e.Selector})
+	if err != nil {
+		return []string{fmt.Sprintf("failed to list nodes: %v", err)}
+	}
+	return checkNodes(nodes.Items, e)
+}
+
+func (a *assertContext) verifyPods(e *PodsExpectation) []string {
+	pods, err := a.client.CoreV1().Pods(namespaceOrDefault(e.Namespace)).List(metav1.ListOptions{LabelSelector: e.Selector})
+	if err != nil {
+		return []string{fmt.Sprintf("failed to list pods: %v", err)}
+	}
+	return checkPods(pods.Items, e)
+}
+
+func (a *assertContext) verifyFile(e *FileExpectation) []string {
+	nodes, err := a.selectNodes(e.Node)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	problems := []string{}
+	for _, n := range nodes {
+		lines, err := n.Command("cat", e.Path).Silent().RunAndCapture()
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("failed to read %s on %s: %s", e.Path, n.Name(), strings.Join(lines, "\n")))
+			continue
+		}
+		problems = append(problems, checkContent(fmt.Sprintf("%s on %s", e.Path, n.Name()), strings.Join(lines, "\n"), e.Contains, e.NotContains)...)
+	}
+	return problems
+}
+
+func (a *assertContext) verifyJSONPath(e *JSONPathExpectation) []string {
+	obj, err := a.getObject(e.Resource, e.Name, namespaceOrDefault(e.Namespace))
+	if err != nil {
+		return []string{err.Error()}
+	}
+	out, err := evalJSONPath(e.Path, obj)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	if e.Equals != "" && out != e.Equals {
+		return []string{fmt.Sprintf("expected %q, got %q", e.Equals, out)}
+	}
+	if e.Contains != "" && !strings.Contains(out, e.Contains) {
+		return []string{fmt.Sprintf("expected %q to contain %q", out, e.Contains)}
+	}
+	return nil
+}
+
+// getObject returns the content of the object with the given resource type and name, or the content of
+// the list of all the objects with the given resource type if name is empty; resource types can be
+// defined like in kubectl, e.g. configmap, configmaps, cm or deployments.apps
+func (a *assertContext) getObject(resource, name, namespace string) (map[string]interface{}, error) {
+	groups, err := restmapper.GetAPIGroupResources(a.client.Discovery())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to discover the API resources")
+	}
+	mapper := restmapper.NewShortcutExpander(restmapper.NewDiscoveryRESTMapper(groups), a.client.Discovery())
+
+	gvr, gr := schema.ParseResourceArg(strings.ToLower(resource))
+	if gvr == nil {
+		gvr = &schema.GroupVersionResource{Group: gr.Group, Resource: gr.Resource}
+	}
+	gvk, err := mapper.KindFor(*gvr)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to resolve resource %s", resource)
+	}
+	mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to resolve resource %s", resource)
+	}
+
+	client, err := dynamic.NewForConfig(a.config)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create a dynamic client")
+	}
+	resourceClient := client.Resource(mapping.Resource)
+	var ri dynamic.ResourceInterface = resourceClient
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		ri = resourceClient.Namespace(namespace)
+	}
+
+	if name == "" {
+		list, err := ri.List(metav1.ListOptions{})
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to list %s", resource)
+		}
+		return list.UnstructuredContent(), nil
+	}
+	obj, err := ri.Get(name, metav1.GetOptions{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get %s %s", resource, name)
+	}
+	return obj.UnstructuredContent(), nil
+}
+
+// evalJSONPath evaluates a JSONPath expression over the content of an object; like in kubectl,
+// missing keys are evaluated as empty and the enclosing braces can be omitted
+func evalJSONPath(path string, obj interface{}) (string, error) {
+	if !strings.HasPrefix(path, "{") {
+		path = fmt.Sprintf("{%s}", path)
+	}
+	j := jsonpath.New("expectation")
+	j.AllowMissingKeys(true)
+	if err := j.Parse(path); err != nil {
+		return "", errors.Wrapf(err, "invalid JSONPath expression %s", path)
+	}
+	var buf bytes.Buffer
+	if err := j.Execute(&buf, obj); err != nil {
+		return "", errors.Wrapf(err, "failed to evaluate JSONPath expression %s", path)
+	}
+	return buf.String(), nil
+}
+
+func (a *assertContext) verifyCommand(e *CommandExpectation) []string {
+	nodes, err := a.selectNodes(e.Node)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	problems := []string{}
+	for _, n := range nodes {
+		lines, err := n.Command(e.Args[0], e.Args[1:]...).Silent().RunAndCapture()
+		out := strings.Join(lines, "\n")
+		if e.ExpectFailure && err == nil {
+			problems = append(problems, fmt.Sprintf("command succeeded on %s while it was expected to fail", n.Name()))
+		}
+		if !e.ExpectFailure && err != nil {
+			problems = append(problems, fmt.Sprintf("command failed on %s: %v", n.Name(), err))
+		}
+		if e.OutputContains != "" && !strings.Contains(out, e.OutputContains) {
+			problems = append(problems, fmt.Sprintf("command output on %s does not contain %q", n.Name(), e.OutputContains))
+		}
+	}
+	return problems
+}
+
+// checkNodes checks a list of nodes against a NodesExpectation
+func checkNodes(nodes []corev1.Node, e *NodesExpectation) []string {
+	problems := []string{}
+	if e.Count != nil && len(nodes) != *e.Count {
+		problems = append(problems, fmt.Sprintf("expected %d nodes, got %d", *e.Count, len(nodes)))
+	}
+	for _, n := range nodes {
+		if e.Ready && !hasCondition(n.Status.Conditions, corev1.NodeReady) {
+			problems = append(problems, fmt.Sprintf("node %s is not Ready", n.Name))
+		}
+		if e.Version != "" && !versionMatches(n.Status.NodeInfo.KubeletVersion, e.Version) {
+			problems = append(problems, fmt.Sprintf("node %s has version %s, expected %s", n.Name, n.Status.NodeInfo.KubeletVersion, e.Version))
+		}
+	}
+	return problems
+}
+
+// checkPods checks a list of pods against a PodsExpectation
+func checkPods(pods []corev1.Pod, e *PodsExpectation) []string {
+	problems := []string{}
+	if e.Count != nil && len(pods) != *e.Count {
+		problems = append(problems, fmt.Sprintf("expected %d pods, got %d", *e.Count, len(pods)))
+	}
+	for _, p := range pods {
+		if e.Ready && !isPodReady(p) {
+			problems = append(problems, fmt.Sprintf("pod %s is not Ready", p.Name))
+		}
+		if e.Image == "" {
+			continue
+		}
+		for _, c := range p.Spec.Containers {
+			if !strings.Contains(c.Image, e.Image) {
+				problems = append(problems, fmt.Sprintf("container %s in pod %s has image %s, expected %s", c.Name, p.Name, c.Image, e.Image))
+			}
+		}
+	}
+	return problems
+}
+
+// checkContent checks that a text contains and does not contain the given strings
+func checkContent(name, text string, contains, notContains []string) []string {
+	problems := []string{}
+	for _, c := range contains {
+		if !strings.Contains(text, c) {
+			problems = append(problems, fmt.Sprintf("%s does not contain %q", name, c))
+		}
+	}
+	for _, c := range notContains {
+		if strings.Contains(text, c) {
+			problems = append(problems, fmt.Sprintf("%s contains %q", name, c))
+		}
+	}
+	return problems
+}
+
+// versionMatches returns true if a version matches the expected version; a partial expected version
+// like v1.19 matches any v1.19.x version, including pre-releases and builds
+func versionMatches(version, expected string) bool {
+	if !strings.HasPrefix(version, expected) {
+		return false
+	}
+	rest := strings.TrimPrefix(version, expected)
+	return rest == "" || !strings.ContainsAny(rest[:1], "0123456789")
+}
+
+// hasCondition returns true if a node condition of the given type has status True
+func hasCondition(conditions []corev1.NodeCondition, t corev1.NodeConditionType) bool {
+	for _, c := range conditions {
+		if c.Type == t {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// isPodReady returns true if the pod has the Ready condition with status True
+func isPodReady(p corev1.Pod) bool {
+	for _, c := range p.Status.Conditions {
+		if c.Type == corev1.PodReady {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// namespaceOrDefault returns the given namespace, or kube-system if empty
+func namespaceOrDefault(namespace string) string {
+	if namespace == "" {
+		return metav1.NamespaceSystem
+	}
+	return namespace
+}
@@ -0,0 +1,222 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNewWorkflowWithAssert(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kinder-workflow")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "workflow.yaml")
+	data := `version: 1
+tasks:
+- name: verify
+  assert:
+    cluster: kinder-test
+    expect:
+    - nodes:
+        count: 2
+        ready: true
+    - file:
+        node: "@cp*"
+        path: /etc/kubernetes/manifests/kube-apiserver.yaml
+        notContains: ["--insecure-port=8080"]
+    - jsonPath:
+        resource: configmap
+        name: kubeadm-config
+        path: "{.data.ClusterConfiguration}"
+        contains: "kubernetesVersion: v1.19"
+    - command:
+        node: "@cp1"
+        args: ["kubeadm", "upgrade", "apply", "v0.0.0"]
+        expectFailure: true
+`
+	if err := ioutil.WriteFile(file, []byte(data), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	w, err := NewWorkflow(file)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{
+		"nodes count=2 ready",
+		`file @cp*:/etc/kubernetes/manifests/kube-apiserver.yaml notContains="--insecure-port=8080"`,
+		`jsonPath configmap kubeadm-config {.data.ClusterConfiguration} contains="kubernetesVersion: v1.19"`,
+		"command @cp1:kubeadm upgrade apply v0.0.0 expectFailure",
+	}
+	got := []string{}
+	for _, e := range w.Tasks[0].Assert.Expect {
+		got = append(got, e.String())
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
+
+func TestExpectationValidate(t *testing.T) {
+	tests := []struct {
+		name        string
+		expectation Expectation
+		expectError bool
+	}{
+		{
+			name:        "valid nodes expectation",
+			expectation: Expectation{Nodes: &NodesExpectation{Ready: true}},
+		},
+		{
+			name:        "no expectation",
+			expectation: Expectation{},
+			expectError: true,
+		},
+		{
+			name:        "more than one expectation",
+			expectation: Expectation{Nodes: &NodesExpectation{}, Pods: &PodsExpectation{}},
+			expectError: true,
+		},
+		{
+			name:        "file expectation without checks",
+			expectation: Expectation{File: &FileExpectation{Node: "@all", Path: "/kind/kubeadm.conf"}},
+			expectError: true,
+		},
+		{
+			name:        "command expectation without args",
+			expectation: Expectation{Command: &CommandExpectation{Node: "@all"}},
+			expectError: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.expectation.validate()
+			if (err != nil) != test.expectError {
+				t.Errorf("expected error %v, got %v", test.expectError, err)
+			}
+		})
+	}
+}
+
+func TestCheckNodes(t *testing.T) {
+	node := func(name, version string, ready corev1.ConditionStatus) corev1.Node {
+		return corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status: corev1.NodeStatus{
+				Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: ready}},
+				NodeInfo:   corev1.NodeSystemInfo{KubeletVersion: version},
+			},
+		}
+	}
+	two := 2
+	nodes := []corev1.Node{
+		node("cp1", "v1.19.3", corev1.ConditionTrue),
+		node("w1", "v1.18.10", corev1.ConditionFalse),
+	}
+
+	tests := []struct {
+		name        string
+		expectation NodesExpectation
+		expected    []string
+	}{
+		{
+			name:        "count matches",
+			expectation: NodesExpectation{Count: &two},
+			expected:    []string{},
+		},
+		{
+			name:        "ready and version",
+			expectation: NodesExpectation{Ready: true, Version: "v1.19"},
+			expected:    []string{"node w1 is not Ready", "node w1 has version v1.18.10, expected v1.19"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := checkNodes(nodes, &test.expectation)
+			if !reflect.DeepEqual(got, test.expected) {
+				t.Errorf("expected %q, got %q", test.expected, got)
+			}
+		})
+	}
+}
+
+func TestVersionMatches(t *testing.T) {
+	tests := []struct {
+		version  string
+		expected string
+		match    bool
+	}{
+		{version: "v1.19.3", expected: "v1.19", match: true},
+		{version: "v1.19.0-beta.1", expected: "v1.19", match: true},
+		{version: "v1.19.3", expected: "v1.19.3", match: true},
+		{version: "v1.190.0", expected: "v1.19", match: false},
+		{version: "v1.18.10", expected: "v1.19", match: false},
+		{version: "v1.19.30", expected: "v1.19.3", match: false},
+	}
+	for _, test := range tests {
+		t.Run(test.version+"/"+test.expected, func(t *testing.T) {
+			if got := versionMatches(test.version, test.expected); got != test.match {
+				t.Errorf("expected %v, got %v", test.match, got)
+			}
+		})
+	}
+}
+
+func TestEvalJSONPath(t *testing.T) {
+	obj := map[string]interface{}{
+		"data": map[string]interface{}{
+			"ClusterConfiguration": "kubernetesVersion: v1.19.3",
+		},
+		"items": []interface{}{
+			map[string]interface{}{"metadata": map[string]interface{}{"name": "cp1"}},
+			map[string]interface{}{"metadata": map[string]interface{}{"name": "w1"}},
+		},
+	}
+
+	tests := []struct {
+		path        string
+		expected    string
+		expectError bool
+	}{
+		{path: "{.data.ClusterConfiguration}", expected: "kubernetesVersion: v1.19.3"},
+		{path: ".data.ClusterConfiguration", expected: "kubernetesVersion: v1.19.3"},
+		{path: "{.items[*].metadata.name}", expected: "cp1 w1"},
+		{path: "{.data.missing}", expected: ""},
+		{path: "{.data[}", expectError: true},
+	}
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			got, err := evalJSONPath(test.path, obj)
+			if (err != nil) != test.expectError {
+				t.Fatalf("expected error %v, got %v", test.expectError, err)
+			}
+			if got != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, got)
+			}
+		})
+	}
+}
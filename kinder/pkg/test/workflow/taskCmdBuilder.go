@@ -21,9 +21,11 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"syscall"
 	"text/template"
+	"time"
 
 	"github.com/pkg/errors"
 
@@ -109,6 +111,10 @@ func (c *taskCmdBuilder) expand(text string) (string, error) {
 
 // build creates a taskCmd
 func (c *taskCmdBuilder) build(t *Task, verbose bool) (tcmd *taskCmd, err error) {
+	if t.Assert != nil {
+		return c.buildAssert(t)
+	}
+
 	// expand golang templates that might exists in the cmd and/or into the args
 	t.Cmd, err = c.expand(t.Cmd)
 	if err != nil {
@@ -147,3 +153,56 @@ func (c *taskCmdBuilder) build(t *Task, verbose bool) (tcmd *taskCmd, err error)
 		CmdText: cmdText,
 	}, nil
 }
+
+// buildAssert creates a taskCmd for an assert task, expanding golang templates that might exists
+// in the cluster name and in the expectations
+func (c *taskCmdBuilder) buildAssert(t *Task) (tcmd *taskCmd, err error) {
+	t.Assert.Cluster, err = c.expand(t.Assert.Cluster)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error expanding cluster for task %q", t.Name)
+	}
+
+	// store a textual representation of the expectations to be used in logs/output
+	lines := []string{fmt.Sprintf("assert on cluster %s", t.Assert.Cluster)}
+	for i, e := range t.Assert.Expect {
+		for _, f := range e.fields() {
+			*f, err = c.expand(*f)
+			if err != nil {
+				return nil, errors.Wrapf(err, "error expanding expect[%d] for task %q", i, t.Name)
+			}
+		}
+		lines = append(lines, fmt.Sprintf("- %s", e))
+	}
+
+	return &taskCmd{
+		Task:    t,
+		CmdText: strings.Join(lines, "\n"),
+	}, nil
+}
+
+// buildDiagnostics expands golang templates that might exists in diagnostics settings, sets the default
+// diagnostics folder if not provided, and creates a taskCmd running kinder export diagnostics; the taskCmd
+// is forced, because it is executed after a failure, and its errors are ignored
+func (c *taskCmdBuilder) buildDiagnostics(d *Diagnostics, artifacts string, verbose bool) (tcmd *taskCmd, err error) {
+	cluster, err := c.expand(d.Cluster)
+	if err != nil {
+		return nil, errors.Wrap(err, "error expanding cluster for diagnostics")
+	}
+	dir, err := c.expand(d.Dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "error expanding dir for diagnostics")
+	}
+	if dir == "" {
+		dir = filepath.Join(artifacts, "diagnostics")
+	}
+
+	return c.build(&Task{
+		Name:        "export-diagnostics",
+		Description: fmt.Sprintf("exports diagnostics for cluster %s", cluster),
+		Cmd:         "kinder",
+		Args:        []string{"export", "diagnostics", fmt.Sprintf("--name=%s", cluster), dir},
+		Force:       true,
+		Timeout:     Duration{5 * time.Minute},
+		IgnoreError: true,
+	}, verbose)
+}
@@ -17,7 +17,6 @@ limitations under the License.
 package workflow
 
 import (
-	"encoding/xml"
 	"fmt"
 	"io"
 	"os"
@@ -29,6 +28,21 @@ import (
 	"time"
 
 	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"k8s.io/kubeadm/kinder/pkg/test/junit"
+)
+
+const (
+	// taskClassName defines the junit class name for workflow tasks
+	taskClassName = "kinder.test.workflow"
+
+	// junitRunnerFile defines the name of the junit file with the result of workflow tasks
+	junitRunnerFile = "junit_runner.xml"
+
+	// testReportFile defines the name of the file merging all the junit results of the workflow.
+	// nb. the name doesn't start with junit, so it is not considered by CI tools collecting junit files
+	testReportFile = "kinder-test-report.xml"
 )
 
 // taskCmdRunner defines all the info of a runner responsible for executing as
@@ -36,36 +50,17 @@ import (
 // and/or collecting all the workflow artifacts (junit_runner.xml, task logs, etc)
 type taskCmdRunner struct {
 	start    time.Time
-	suite    junitTestSuite
+	suite    junit.TestSuite
 	failed   bool
 	canceled bool
 	timedOut bool
 }
 
-// junitTestSuite implements junit TestSuite standard object
-type junitTestSuite struct {
-	XMLName  xml.Name `xml:"testsuite"`
-	Failures int      `xml:"failures,attr"`
-	Tests    int      `xml:"tests,attr"`
-	Time     float64  `xml:"time,attr"`
-	Cases    []junitTestCase
-}
-
-// junitTestCase implements junit TestCase standard object
-type junitTestCase struct {
-	XMLName   xml.Name `xml:"testcase"`
-	ClassName string   `xml:"classname,attr"`
-	Name      string   `xml:"name,attr"`
-	Time      float64  `xml:"time,attr"`
-	Failure   string   `xml:"failure,omitempty"`
-	Skipped   string   `xml:"skipped,omitempty"`
-}
-
 // newTaskCmdRunner returns a new taskCmdRunner
 func newTaskCmdRunner() *taskCmdRunner {
 	return &taskCmdRunner{
 		start: time.Now(),
-		suite: junitTestSuite{},
+		suite: junit.TestSuite{},
 	}
 }
 
@@ -76,16 +71,8 @@ func (c *taskCmdRunner) Run(t *taskCmd, artifacts string, verbose bool) error {
 	// unless the cmd execution is forced, check if the taskCmd should be skipped because one of
 	// the previous taskCmd failed, timedOut or was canceled.
 	// if this is the case record test case as skipped and exits with error
-	if !t.Force {
-		if c.failed {
-			return c.registerTestCase(t.Name, withSkipped("skipping because a predecessor task failed"))
-		}
-		if c.timedOut {
-			return c.registerTestCase(t.Name, withSkipped("skipping because a predecessor task timed-out"))
-		}
-		if c.canceled {
-			return c.registerTestCase(t.Name, withSkipped("skipping because task workflow was canceled by the user"))
-		}
+	if skipped, err := c.skip(t); skipped {
+		return err
 	}
 
 	// creates a channel for handling command cancellation
@@ -126,7 +113,7 @@ func (c *taskCmdRunner) Run(t *taskCmd, artifacts string, verbose bool) error {
 		c.failed = true
 
 		// record test case timeout and exits with error
-		return c.registerTestCase(t.Name, withFailure(err.Error()), withDuration(time.Since(start)))
+		return c.registerTestCase(t.Name, junit.WithFailure(err.Error()), junit.WithDuration(time.Since(start)))
 	}
 
 	// starts a go routine responsible for waiting the command completes
@@ -141,11 +128,20 @@ func (c *taskCmdRunner) Run(t *taskCmd, artifacts string, verbose bool) error {
 	// - the timeout is reached
 	select {
 	case err := <-result:
+		// if the command is expected to fail, a failure is recorded as success and vice versa
+		if t.ExpectFailure {
+			if err == nil {
+				err = errors.New("the task succeeded, but it was expected to fail")
+			} else {
+				err = nil
+			}
+		}
+
 		// if the command completed without an error or if we are ignoring errors, record the test case success and exit
 		if err == nil || t.IgnoreError {
 			// record test case timeout as success
 			return c.registerTestCase(t.Name,
-				withDuration(time.Since(start)),
+				junit.WithDuration(time.Since(start)),
 			)
 		}
 		// keeps track of this failure type to block execution of following TestCmd
@@ -156,8 +152,8 @@ func (c *taskCmdRunner) Run(t *taskCmd, artifacts string, verbose bool) error {
 
 		// otherwise record test case failure and exits with error
 		return c.registerTestCase(t.Name,
-			withFailure(err.Error()),
-			withDuration(time.Since(start)),
+			junit.WithFailure(err.Error()),
+			junit.WithDuration(time.Since(start)),
 		)
 
 	case <-cancel:
@@ -169,8 +165,8 @@ func (c *taskCmdRunner) Run(t *taskCmd, artifacts string, verbose bool) error {
 
 		// record test case cancellation and exits with error
 		return c.registerTestCase(t.Name,
-			withFailure("task was canceled by the user"),
-			withDuration(time.Since(start)),
+			junit.WithFailure("task was canceled by the user"),
+			junit.WithDuration(time.Since(start)),
 		)
 
 	case <-time.After(t.Timeout.Duration):
@@ -182,21 +178,170 @@ func (c *taskCmdRunner) Run(t *taskCmd, artifacts string, verbose bool) error {
 
 		// record test case timeout and exits with error
 		return c.registerTestCase(t.Name,
-			withFailure(fmt.Sprintf("timeout. The task did not complete in less than %s as expected", t.Timeout.Duration)),
-			withDuration(time.Since(start)),
+			junit.WithFailure(fmt.Sprintf("timeout. The task did not complete in less than %s as expected", t.Timeout.Duration)),
+			junit.WithDuration(time.Since(start)),
 		)
 	}
 }
 
+// skip checks if a taskCmd should be skipped because one of the previous taskCmd failed, timedOut or was canceled,
+// unless the cmd execution is forced; skipped taskCmd are recorded as skipped test cases
+func (c *taskCmdRunner) skip(t *taskCmd) (bool, error) {
+	if t.Force {
+		return false, nil
+	}
+	if c.failed {
+		return true, c.registerTestCase(t.Name, junit.WithSkipped("skipping because a predecessor task failed"))
+	}
+	if c.timedOut {
+		return true, c.registerTestCase(t.Name, junit.WithSkipped("skipping because a predecessor task timed-out"))
+	}
+	if c.canceled {
+		return true, c.registerTestCase(t.Name, junit.WithSkipped("skipping because task workflow was canceled by the user"))
+	}
+	return false, nil
+}
+
+// assertResult defines the result of the verification of an expectation, or the error
+// that prevents verifying expectations
+type assertResult struct {
+	expectation *Expectation
+	problems    []string
+	duration    time.Duration
+	err         error
+}
+
+// RunAssert verifies the expectations defined in an assert taskCmd; each expectation is recorded
+// as a separated test case, with the list of problems found as a failure message.
+// Like for other taskCmd, the verification is interrupted if the task timeout is reached or if the
+// user cancels the execution
+func (c *taskCmdRunner) RunAssert(t *taskCmd, artifacts string, verbose bool) error {
+	start := time.Now()
+
+	if skipped, err := c.skip(t); skipped {
+		return err
+	}
+
+	// creates a channel for handling cancellation
+	cancel := make(chan os.Signal, 1)
+	signal.Notify(cancel, syscall.SIGINT, syscall.SIGTERM)
+
+	// the result of each expectation goes on the task log file, and it is echoed
+	// on video only if specifically requested
+	taskLog := filepath.Join(artifacts, fmt.Sprintf("%s-log.txt", t.Name))
+	file, err := os.Create(taskLog)
+	if err != nil {
+		return errors.Wrapf(err, "error creating %q log file", taskLog)
+	}
+	defer file.Close()
+
+	var writer io.Writer = file
+	if verbose {
+		writer = io.MultiWriter(file, os.Stdout)
+	}
+
+	fmt.Fprintf(writer, "%s\n", strings.Repeat("-", 80))
+	fmt.Fprintf(writer, "%s\n", t.Name)
+	if t.Description != "" {
+		fmt.Fprintf(writer, "%s\n", t.Description)
+	}
+	fmt.Fprintf(writer, "%s\n", t.CmdText)
+	fmt.Fprintf(writer, "timeout : %s\n", t.Timeout.Duration)
+	fmt.Fprintf(writer, "force   : %v\n", t.Force)
+	fmt.Fprintf(writer, "%s\n\n", strings.Repeat("-", 80))
+
+	// starts a go routine responsible for verifying expectations, one at a time
+	results := make(chan assertResult, len(t.Assert.Expect))
+	go func() {
+		defer close(results)
+		a, err := newAssertContext(t.Assert.Cluster)
+		if err != nil {
+			results <- assertResult{err: err}
+			return
+		}
+		for _, e := range t.Assert.Expect {
+			expectationStart := time.Now()
+			problems := a.verify(e)
+			results <- assertResult{expectation: e, problems: problems, duration: time.Since(expectationStart)}
+		}
+	}()
+
+	// Wait for one of:
+	// - all the expectations are verified
+	// - the verification is canceled
+	// - the timeout is reached
+	failed := 0
+	timeout := time.After(t.Timeout.Duration)
+	for {
+		select {
+		case r, ok := <-results:
+			if !ok {
+				if failed > 0 {
+					// keeps track of this failure type to block execution of following TestCmd
+					c.failed = true
+					return errors.Errorf("%d of %d expectations failed", failed, len(t.Assert.Expect))
+				}
+				return nil
+			}
+
+			if r.err != nil {
+				fmt.Fprintf(writer, "%v\n", r.err)
+				if t.IgnoreError {
+					return c.registerTestCase(t.Name, junit.WithDuration(time.Since(start)))
+				}
+				// keeps track of this failure type to block execution of following TestCmd
+				c.failed = true
+				return c.registerTestCase(t.Name, junit.WithFailure(r.err.Error()), junit.WithDuration(time.Since(start)))
+			}
+
+			name := fmt.Sprintf("%s: %s", t.Name, r.expectation)
+			if len(r.problems) == 0 || t.IgnoreError {
+				fmt.Fprintf(writer, "PASS: %s\n", r.expectation)
+				for _, p := range r.problems {
+					fmt.Fprintf(writer, "  - %s (ignored)\n", p)
+				}
+				if err := c.registerTestCase(name, junit.WithDuration(r.duration)); err != nil {
+					return err
+				}
+				continue
+			}
+
+			fmt.Fprintf(writer, "FAIL: %s\n", r.expectation)
+			for _, p := range r.problems {
+				fmt.Fprintf(writer, "  - %s\n", p)
+			}
+			// the failure of the expectation is counted and reported when all the expectations are verified
+			if err := c.registerTestCase(name, junit.WithFailure(strings.Join(r.problems, "\n")), junit.WithDuration(r.duration)); err != nil {
+				failed++
+			}
+
+		case <-cancel:
+			// keeps track of this failure type to block execution of following TestCmd
+			c.canceled = true
+
+			// record test case cancellation and exits with error
+			return c.registerTestCase(t.Name,
+				junit.WithFailure("task was canceled by the user"),
+				junit.WithDuration(time.Since(start)),
+			)
+
+		case <-timeout:
+			// keeps track of this failure type to block execution of following TestCmd
+			c.timedOut = true
+
+			// record test case timeout and exits with error
+			return c.registerTestCase(t.Name,
+				junit.WithFailure(fmt.Sprintf("timeout. The task did not complete in less than %s as expected", t.Timeout.Duration)),
+				junit.WithDuration(time.Since(start)),
+			)
+		}
+	}
+}
+
 // ReportSummary prints a summary of executed task
 func (c *taskCmdRunner) ReportSummary() {
 	total := c.suite.Tests
-	skipped := 0
-	for _, t := range c.suite.Cases {
-		if t.Skipped != "" {
-			skipped++
-		}
-	}
+	skipped := c.suite.SkippedTestCases()
 	run := total - skipped
 	failures := c.suite.Failures
 	passed := run - failures
@@ -214,61 +359,64 @@ func (c *taskCmdRunner) DumpJUnitRunner(artifacts string) error {
 	// sets test suite duration
 	c.suite.Time = time.Since(c.start).Seconds()
 
-	// marshal test suite into the junit_runner.xml file
-	out, err := xml.MarshalIndent(&c.suite, "", "    ")
-	if err != nil {
-		return errors.Wrapf(err, "error marshaling test suite results")
+	// writes the test suite into the junit_runner.xml file
+	return c.suite.WriteFile(filepath.Join(artifacts, junitRunnerFile))
+}
+
+// DumpTestReport writes a single report describing the whole workflow run, merging the executed tasks
+// with all the junit files generated by the tasks into the artifacts folder (e.g. e2e test results)
+func (c *taskCmdRunner) DumpTestReport(artifacts string) (*junit.TestSuite, error) {
+	report := &junit.TestSuite{
+		Name: "kinder",
 	}
-	file := filepath.Join(artifacts, "junit_runner.xml")
-	f, err := os.Create(file)
+	report.Merge(&c.suite)
+
+	// nb. filepath.Walk visits files in lexical order, so results of specs re-run for detecting flakes
+	// (stored in rerun-N subfolders) are merged after the original results
+	err := filepath.Walk(artifacts, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			log.Warnf("skipping %s: %v", path, err)
+			return nil
+		}
+		if info.IsDir() || !isJUnitFile(info.Name()) || info.Name() == junitRunnerFile {
+			return nil
+		}
+
+		suites, err := junit.ParseFile(path)
+		if err != nil {
+			// junit files not generated by kinder are not under our control, so we are not failing in case of errors
+			log.Warnf("skipping junit file %s: %v", path, err)
+			return nil
+		}
+		for i := range suites {
+			report.Merge(&suites[i])
+		}
+		return nil
+	})
 	if err != nil {
-		return errors.Wrapf(err, "error creating %s", file)
-	}
-	defer f.Close()
-	if _, err := f.WriteString(xml.Header); err != nil {
-		return errors.Wrapf(err, "error writing XML header to %s", file)
-	}
-	if _, err := f.Write(out); err != nil {
-		return errors.Wrapf(err, "error writing XML data to %s", file)
+		log.Warnf("error walking %s tree: %v", artifacts, err)
 	}
 
-	return nil
-}
-
-type testCaseOption func(*junitTestCase)
+	// keep only the last result for test cases executed more than once, e.g. flakes
+	report.Dedup()
 
-func withDuration(duration time.Duration) testCaseOption {
-	return func(t *junitTestCase) {
-		t.Time = duration.Seconds()
-	}
-}
-func withFailure(message string) testCaseOption {
-	return func(t *junitTestCase) {
-		t.Failure = message
+	if err := report.WriteFile(filepath.Join(artifacts, testReportFile)); err != nil {
+		return nil, err
 	}
+	return report, nil
 }
 
-func withSkipped(message string) testCaseOption {
-	return func(t *junitTestCase) {
-		t.Skipped = message
-	}
+// isJUnitFile returns true if the file name is a junit file name
+func isJUnitFile(name string) bool {
+	return strings.HasPrefix(name, "junit") && filepath.Ext(name) == ".xml"
 }
 
 // registerTestCase register task output as a test case result
-func (c *taskCmdRunner) registerTestCase(name string, options ...testCaseOption) error {
-	tc := &junitTestCase{
-		ClassName: "kinder.test.workflow",
-		Name:      name,
-	}
-
-	for _, option := range options {
-		option(tc)
-	}
+func (c *taskCmdRunner) registerTestCase(name string, options ...junit.TestCaseOption) error {
+	c.suite.AddTestCase(taskClassName, name, options...)
 
-	c.suite.Cases = append(c.suite.Cases, *tc)
-	c.suite.Tests++
+	tc := c.suite.Cases[len(c.suite.Cases)-1]
 	if tc.Failure != "" {
-		c.suite.Failures++
 		return errors.New(tc.Failure)
 	}
 
@@ -66,10 +66,24 @@ type Workflow struct {
 	// Env variables can be used for golang template expansion using {{ .env.KEY }}
 	Env map[string]string
 
+	// Diagnostics, if set, instructs the workflow runner to export kubeadm diagnostics from a cluster
+	// as soon as a task fails, before executing any following task (e.g. cleanup tasks)
+	Diagnostics *Diagnostics
+
 	// Tasks defines the list of tasks to be executed during test workflow
 	Tasks Tasks
 }
 
+// Diagnostics defines how to export kubeadm diagnostics when a task fails
+type Diagnostics struct {
+	// Cluster defines the name of the cluster diagnostics are exported from; it can be a literal or a template
+	Cluster string
+
+	// Dir defines the folder where diagnostics are exported, $ARTIFACTS/diagnostics by default;
+	// it can be a literal or a template
+	Dir string
+}
+
 // Tasks represents a list of tasks to be executed during test workflow.
 // Task are executed in order; if a task fails, timeouts or it is canceled by the user,
 // following task are skipped (unless execution is explicitly forced on a specific task)
@@ -89,6 +103,9 @@ type Task struct {
 	// Cmd to execute; it can be a literal or a template
 	Cmd string
 
+	// Assert defines a set of expectations to be verified against a cluster instead of executing a Cmd
+	Assert *Assert
+
 	// Import defines a path of a workflow file to import into the current workflow
 	Import string
 
@@ -104,6 +121,123 @@ type Task struct {
 
 	// IgnoreError sets a task to be recorded as successful even if it is actually failed
 	IgnoreError bool `yaml:"ignoreError"`
+
+	// ExpectFailure sets a task to be recorded as successful only if it fails, and as failed if it succeeds
+	ExpectFailure bool `yaml:"expectFailure"`
+}
+
+// Assert defines a set of expectations to be verified against a cluster; each expectation is
+// reported as a separated test case in the junit output
+type Assert struct {
+	// Cluster defines the name of the cluster to be verified; it can be a literal or a template
+	Cluster string
+
+	// Expect defines the list of expectations to be verified
+	Expect []*Expectation
+}
+
+// Expectation defines an expectation about the cluster; only one of the fields should be set.
+// All the string values in an expectation can be a literal or a template
+type Expectation struct {
+	// Nodes defines expectations about the Kubernetes nodes
+	Nodes *NodesExpectation
+
+	// Pods defines expectations about pods
+	Pods *PodsExpectation
+
+	// File defines expectations about the content of a file on nodes
+	File *FileExpectation
+
+	// JSONPath defines expectations about the result of a JSONPath expression over Kubernetes objects
+	JSONPath *JSONPathExpectation `json:"jsonPath"`
+
+	// Command defines expectations about the result of a command executed on nodes
+	Command *CommandExpectation
+}
+
+// NodesExpectation defines expectations about the Kubernetes nodes
+type NodesExpectation struct {
+	// Selector defines a label selector for filtering nodes, all the nodes by default
+	Selector string
+
+	// Count defines the expected number of nodes, if set
+	Count *int
+
+	// Ready requires all the nodes to be Ready
+	Ready bool
+
+	// Version defines the expected kubelet version for all the nodes; a partial version like v1.19 matches any v1.19.x version
+	Version string
+}
+
+// PodsExpectation defines expectations about pods
+type PodsExpectation struct {
+	// Namespace of the pods, kube-system by default
+	Namespace string
+
+	// Selector defines a label selector for filtering pods, all the pods in the namespace by default
+	Selector string
+
+	// Count defines the expected number of pods, if set
+	Count *int
+
+	// Ready requires all the pods to be Ready
+	Ready bool
+
+	// Image defines a string, e.g. a version, that all the container images in the pods should contain
+	Image string
+}
+
+// FileExpectation defines expectations about the content of a file on nodes
+type FileExpectation struct {
+	// Node defines the target nodes using a node selector like for kinder exec, e.g. @cp*
+	Node string
+
+	// Path of the file
+	Path string
+
+	// Contains defines strings that the file should contain
+	Contains []string
+
+	// NotContains defines strings that the file should not contain
+	NotContains []string `json:"notContains"`
+}
+
+// JSONPathExpectation defines expectations about the result of a JSONPath expression over Kubernetes objects,
+// as returned by kubectl get -o jsonpath
+type JSONPathExpectation struct {
+	// Resource defines the Kubernetes resource, e.g. nodes or configmap
+	Resource string
+
+	// Name defines the name of the object, all the objects by default
+	Name string
+
+	// Namespace of the objects, kube-system by default
+	Namespace string
+
+	// Path defines the JSONPath expression, e.g. {.items[*].status.nodeInfo.kubeletVersion}
+	Path string
+
+	// Equals defines the expected result
+	Equals string
+
+	// Contains defines a string that the result should contain
+	Contains string
+}
+
+// CommandExpectation defines expectations about the result of a command executed on nodes
+type CommandExpectation struct {
+	// Node defines the target nodes using a node selector like for kinder exec, e.g. @cp*
+	Node string
+
+	// Args defines the command to be executed and its arguments
+	Args []string
+
+	// ExpectFailure requires the command to fail
+	ExpectFailure bool `json:"expectFailure"`
+
+	// OutputContains defines a string that the command output should contain
+	OutputContains string `json:"outputContains"`
 }
 
 // Duration is a wrapper around time.Duration to satisfy the encoding/json Marshaller
@@ -171,6 +305,10 @@ func NewWorkflow(file string) (*Workflow, error) {
 		return nil, errors.Errorf("invalid taskfile %s: at least one task should be defined", file)
 	}
 
+	if w.Diagnostics != nil && w.Diagnostics.Cluster == "" {
+		return nil, errors.Errorf("invalid taskfile %s: diagnostics does not define a cluster", file)
+	}
+
 	// Detect and resolve imports by expanding imported workflows into the top level workflow
 	if err := w.expandImports(file); err != nil {
 		return nil, err
@@ -192,6 +330,20 @@ func NewWorkflow(file string) (*Workflow, error) {
 			t.Timeout.Duration = time.Duration(5 * time.Minute)
 		}
 
+		// check if the task defines an assert, that cannot be combined with cmd and args
+		if t.Assert != nil {
+			if t.Cmd != "" || len(t.Args) != 0 {
+				return nil, errors.Errorf("invalid taskfile %s: task %q - cmd and args settings can't be combined with assert", file, t.Name)
+			}
+			if t.ExpectFailure {
+				return nil, errors.Errorf("invalid taskfile %s: task %q - expectFailure setting can't be combined with assert", file, t.Name)
+			}
+			if err := t.Assert.validate(); err != nil {
+				return nil, errors.Wrapf(err, "invalid taskfile %s: task %q", file, t.Name)
+			}
+			continue
+		}
+
 		// check if the task defines a cmd
 		if t.Cmd == "" {
 			return nil, errors.Errorf("invalid taskfile %s: task %q does not define a cmd", file, t.Name)
@@ -232,6 +384,12 @@ func (w *Workflow) expandImports(file string) error {
 		if t.IgnoreError {
 			return errors.Errorf("invalid workflow file %s: task #%d - ignoreError setting can't be combined with import directive", file, i+1)
 		}
+		if t.Assert != nil {
+			return errors.Errorf("invalid workflow file %s: task #%d - assert setting can't be combined with import directive", file, i+1)
+		}
+		if t.ExpectFailure {
+			return errors.Errorf("invalid workflow file %s: task #%d - expectFailure setting can't be combined with import directive", file, i+1)
+		}
 
 		// reads the Import file
 		// if path are relative, consider as a base path the folder where the importing file is located.
@@ -266,6 +424,11 @@ func (w *Workflow) expandImports(file string) error {
 			log.Debugf("env var %s in workflow file %s is shadowed by env var %[1]s in parent workflow file %[3]s", k, path, file)
 		}
 
+		// use the diagnostics settings from the import file if not defined in the parent file
+		if w.Diagnostics == nil {
+			w.Diagnostics = wx.Diagnostics
+		}
+
 		// import all tasks from the import file into the parent file, removing task name prefix
 		re := regexp.MustCompile(`^task\-\d{2}\-?`)
 		for _, tx := range wx.Tasks {
@@ -341,6 +504,15 @@ func (w *Workflow) Run(out io.Writer, dryRun, verbose, exitOnError bool, artifac
 		tcmds = append(tcmds, tcmd)
 	}
 
+	// Expands golang templates for diagnostics settings, if any, and creates the corresponding taskCmd
+	var diagnostics *taskCmd
+	if w.Diagnostics != nil {
+		diagnostics, err = taskCmdBuilder.buildDiagnostics(w.Diagnostics, artifacts, verbose)
+		if err != nil {
+			return err
+		}
+	}
+
 	foundError := false
 	// Executes taskCmds
 	for _, tcmd := range tcmds {
@@ -348,11 +520,26 @@ func (w *Workflow) Run(out io.Writer, dryRun, verbose, exitOnError bool, artifac
 		fmt.Fprintf(out, "%s\n\n", tcmd.CmdText)
 
 		if !dryRun {
-			err := taskCmdRunner.Run(tcmd, artifacts, verbose)
+			var err error
+			if tcmd.Assert != nil {
+				err = taskCmdRunner.RunAssert(tcmd, artifacts, verbose)
+			} else {
+				err = taskCmdRunner.Run(tcmd, artifacts, verbose)
+			}
 			if err != nil {
-				foundError = true
 				fmt.Fprintf(out, " %v\n\n", err)
 
+				// exports diagnostics only for the first failure, because following failures
+				// are usually a consequence of it
+				if diagnostics != nil && !foundError {
+					fmt.Fprintf(out, "# %s\n", diagnostics.Name)
+					fmt.Fprintf(out, "%s\n\n", diagnostics.CmdText)
+					if err := taskCmdRunner.Run(diagnostics, artifacts, verbose); err != nil {
+						fmt.Fprintf(out, " %v\n\n", err)
+					}
+				}
+				foundError = true
+
 				if exitOnError {
 					return err
 				}
@@ -372,7 +559,21 @@ func (w *Workflow) Run(out io.Writer, dryRun, verbose, exitOnError bool, artifac
 			fmt.Fprintf(out, "%v\n", err)
 			return err
 		}
-		fmt.Fprintf(out, "see junit-runner.xml and task logs files for more details\n\n")
+
+		// merges all the junit files into a single report, and prints a summary of failed tests
+		report, err := taskCmdRunner.DumpTestReport(artifacts)
+		if err != nil {
+			fmt.Fprintf(out, "%v\n", err)
+			return err
+		}
+		if failed := report.FailedTestCases(); len(failed) > 0 {
+			fmt.Fprintf(out, "Failed tests:\n")
+			for _, tc := range failed {
+				fmt.Fprintf(out, "- %s\n", tc.Name)
+			}
+			fmt.Fprintf(out, "\n")
+		}
+		fmt.Fprintf(out, "see %s, %s and task logs files for more details\n\n", junitRunnerFile, testReportFile)
 	}
 
 	if foundError {